@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+)
+
+// ErrUnexpectedOutput is when action output from device does not match
+// action's output in schema.  See Client.ValidateActionOutput.
+var ErrUnexpectedOutput = errors.New("action output does not match schema")
+
+// checkOutput is that JSON output has nothing that is not in rpc's output
+// and has all mandatory leaves.  Only checked if client is configured to.
+func (self *client) checkOutput(rpc *meta.Rpc, body []byte) error {
+	if !self.validateOutput || !isJSONCodec(self.codec) {
+		return nil
+	}
+	return checkOutput(rpc, body)
+}
+
+func checkOutput(rpc *meta.Rpc, body []byte) error {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("%w. %s %s", ErrUnexpectedOutput, rpc.Ident(), err)
+	}
+	if rpc.Output() == nil {
+		if len(data) > 0 {
+			return fmt.Errorf("%w. %s has no output", ErrUnexpectedOutput, rpc.Ident())
+		}
+		return nil
+	}
+	if err := checkData(rpc.Output(), "", data); err != nil {
+		return fmt.Errorf("%w. %s %s", ErrUnexpectedOutput, rpc.Ident(), err)
+	}
+	return nil
+}
+
+func checkData(parent meta.HasDataDefinitions, prefix string, data map[string]interface{}) error {
+	for _, ident := range sortedKeys(data) {
+		// members can be module qualified
+		local := ident
+		if colon := strings.IndexRune(ident, ':'); colon >= 0 {
+			local = ident[colon+1:]
+		}
+		def := meta.Find(parent, local)
+		if def == nil {
+			return fmt.Errorf("unexpected %s%s", prefix, local)
+		}
+		if err := checkValue(def, prefix+local, data[ident]); err != nil {
+			return err
+		}
+	}
+	for _, def := range parent.DataDefinitions() {
+		if m, valid := def.(meta.HasMandatory); valid && m.Mandatory() {
+			if _, found := data[def.Ident()]; !found {
+				return fmt.Errorf("missing %s%s", prefix, def.Ident())
+			}
+		}
+	}
+	return nil
+}
+
+func checkValue(def meta.Definition, path string, v interface{}) error {
+	switch x := def.(type) {
+	case *meta.List:
+		entries, valid := v.([]interface{})
+		if !valid {
+			return fmt.Errorf("expected %s to be a list", path)
+		}
+		for _, entry := range entries {
+			child, valid := entry.(map[string]interface{})
+			if !valid {
+				return fmt.Errorf("expected %s entries to be objects", path)
+			}
+			if err := checkData(x, path+"/", child); err != nil {
+				return err
+			}
+		}
+	case meta.HasDataDefinitions:
+		child, valid := v.(map[string]interface{})
+		if !valid {
+			return fmt.Errorf("expected %s to be an object", path)
+		}
+		return checkData(x, path+"/", child)
+	case *meta.LeafList:
+		if _, valid := v.([]interface{}); !valid {
+			return fmt.Errorf("expected %s to be an array", path)
+		}
+	default:
+		if _, valid := v.(map[string]interface{}); valid {
+			return fmt.Errorf("expected %s to be a value", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+const mimeEventStream = "text/event-stream"
+
+// ActionStream is for actions that send their output progressively, such as
+// a long diagnostic, instead of all at once.  Each chunk of output is sent
+// on channel as soon as it is decoded.  Device can send output as server
+// sent events w/each event's data being a chunk or as a sequence of JSON
+// objects.  Channel is closed when device is done or ctx is done.  Chunks
+// that could not be decoded are error nodes, see RawFrame.
+func (self *client) ActionStream(ctx context.Context, path string, input node.Node) (<-chan node.Node, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	rpc, isRpc := p.Meta().(*meta.Rpc)
+	if !isRpc {
+		return nil, fmt.Errorf("%w. %s is not an action", fc.BadRequestError, path)
+	}
+	var payload bytes.Buffer
+	if input != nil && rpc.Input() != nil {
+		in := node.Selection{
+			Node:        input,
+			Path:        node.NewContainerPath(p, rpc.Input()),
+			Constraints: &node.Constraints{},
+			Context:     ctx,
+		}
+		if err := codecOrDefault(self.codec).Write(&payload, in); err != nil {
+			return nil, err
+		}
+	}
+	accept := mimeEventStream + ", " + mimeYangDataJson + ", application/json"
+	resp, err := self.send(withAccept(ctx, accept), "POST", "", p, &payload)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make(chan node.Node, self.streamBuffer)
+	go func() {
+		defer resp.Body.Close()
+		defer close(chunks)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-ctx.Done():
+				// unblocks decoder if it's waiting on device
+				resp.Body.Close()
+			case <-done:
+			}
+		}()
+		send := func(n node.Node) bool {
+			select {
+			case chunks <- n:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if isEventStream(resp) {
+			for frame := range decodeSse(resp.Body, done) {
+				if !send(decodeEvent(rpc.Output(), frame, self.eventTimeLayouts)) {
+					return
+				}
+			}
+			return
+		}
+		decodeJSONChunks(resp.Body, ctx, send)
+	}()
+	return chunks, nil
+}
+
+func isEventStream(resp *http.Response) bool {
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mimeType == mimeEventStream
+}
+
+// decodeJSONChunks reads a sequence of JSON objects, each one a chunk,
+// until end of input, ctx is done or something that is not JSON is read
+func decodeJSONChunks(in io.Reader, ctx context.Context, send func(n node.Node) bool) {
+	dec := json.NewDecoder(in)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				send(node.ErrorNode{Err: &EventDecodeError{Frame: raw, Err: err}})
+			}
+			return
+		}
+		var data map[string]interface{}
+		var n node.Node
+		if err := json.Unmarshal(raw, &data); err != nil {
+			n = node.ErrorNode{Err: &EventDecodeError{Frame: raw, Err: err}}
+		} else {
+			n = jsonContainerReader(data)
+		}
+		if !send(n) {
+			return
+		}
+	}
+}
+
+type acceptKey struct{}
+
+// withAccept replaces codec's mime type in Accept header
+func withAccept(ctx context.Context, accept string) context.Context {
+	return context.WithValue(ctx, acceptKey{}, accept)
+}
+
+func acceptFrom(ctx context.Context) (string, bool) {
+	accept, found := ctx.Value(acceptKey{}).(string)
+	return accept, found
+}
@@ -0,0 +1,86 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientActionStream(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		rpc diag {
+			input {
+				leaf count { type int32; }
+			}
+			output {
+				leaf line { type string; }
+			}
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var accept string
+	var sse bool
+	stopped := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		if sse {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/yang-data+json")
+		}
+		for i := 0; ; i++ {
+			if sse {
+				fmt.Fprintf(w, "data: {\"line\":\"line %d\"}\n\n", i)
+			} else {
+				fmt.Fprintf(w, "{\"line\":\"line %d\"}\n", i)
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				stopped <- struct{}{}
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	rpc := meta.Find(m, "diag").(*meta.Rpc)
+	line := func(n node.Node) string {
+		out := node.Selection{
+			Node:        n,
+			Path:        node.NewContainerPath(node.NewRootPath(m), rpc.Output()),
+			Constraints: &node.Constraints{},
+		}
+		actual, err := nodeutil.WriteJSON(out)
+		fc.AssertEqual(t, nil, err)
+		return actual
+	}
+	for _, sse = range []bool{true, false} {
+		ctx, cancel := context.WithCancel(context.Background())
+		chunks, err := c.ActionStream(ctx, "m:diag", nodeutil.ReadJSON(`{"count":3}`))
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, "text/event-stream, application/yang-data+json, application/json", accept)
+		for i := 0; i < 3; i++ {
+			fc.AssertEqual(t, fmt.Sprintf(`{"line":"line %d"}`, i), line(<-chunks))
+		}
+		cancel()
+		// device keeps sending, but nothing more should be decoded
+		for range chunks {
+		}
+		select {
+		case <-stopped:
+		case <-time.After(2 * time.Second):
+			t.Error("device never saw request cancelled")
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package restconf
+
+import (
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// Where actions are sent.  See Client.ActionUrl
+const (
+	// {+restconf}/data/module:path for all actions which servers that treat
+	// rpcs like any other resource expect, including this package's server
+	ActionUrlData = "data"
+
+	// {+restconf}/operations/module:path for all actions
+	ActionUrlOperations = "operations"
+
+	// operations for top-level rpcs and data for actions inside data as RFC
+	// 8040 section 3.6 describes
+	ActionUrlAuto = "auto"
+)
+
+// operationsUrl is whether action at p is sent to operations resource
+// instead of data
+func (self *client) operationsUrl(p *node.Path) bool {
+	if _, isRpc := p.Meta().(*meta.Rpc); !isRpc {
+		return false
+	}
+	switch self.actionUrl {
+	case ActionUrlOperations:
+		return true
+	case ActionUrlAuto:
+		_, topLevel := p.Parent().Meta().(*meta.Module)
+		return topLevel
+	}
+	return false
+}
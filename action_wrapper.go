@@ -0,0 +1,77 @@
+package restconf
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+)
+
+type actionWrapperKey struct{}
+
+// WithActionWrapper overrides Client.ActionWrapper for actions invoked with
+// this context for devices in a fleet that disagree on wrapping.
+//
+//	ctx := restconf.WithActionWrapper(ctx, true)
+//	b.RootWithContext(ctx).Find("reset").Action(nil)
+func WithActionWrapper(ctx context.Context, wrap bool) context.Context {
+	return context.WithValue(ctx, actionWrapperKey{}, wrap)
+}
+
+// actionWrapped is whether action input and output are wrapped in
+// "module:input" and "module:output".  Only JSON is ever wrapped.
+func (self *client) actionWrapped(ctx context.Context) bool {
+	if !isJSONCodec(self.codec) {
+		return false
+	}
+	if wrap, found := ctx.Value(actionWrapperKey{}).(bool); found {
+		return wrap
+	}
+	return self.actionWrapper
+}
+
+func isJSONCodec(c Codec) bool {
+	return strings.Contains(codecOrDefault(c).MimeType(), "json")
+}
+
+// wrapInput is {"module:input":payload} unless there is no input
+func wrapInput(rpc *meta.Rpc, payload io.Reader) io.Reader {
+	if payload == nil {
+		return nil
+	}
+	prefix := `{"` + meta.RootModule(rpc).Ident() + `:input":`
+	// buffered input stays buffered so action can be retried
+	if buf, isBuf := payload.(*bytes.Buffer); isBuf {
+		if buf.Len() == 0 {
+			return buf
+		}
+		wrapped := bytes.NewBufferString(prefix)
+		wrapped.Write(buf.Bytes())
+		wrapped.WriteString("}")
+		return wrapped
+	}
+	body := bufio.NewReader(payload)
+	if _, err := body.Peek(1); err == io.EOF {
+		return body
+	}
+	return io.MultiReader(strings.NewReader(prefix), body, strings.NewReader("}"))
+}
+
+// unwrapOutput is the object inside "module:output". Output that is not
+// wrapped is returned as is.
+func unwrapOutput(body []byte) []byte {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(body, &wrapper); err != nil || len(wrapper) != 1 {
+		return body
+	}
+	for ident, output := range wrapper {
+		if ident == "output" || strings.HasSuffix(ident, ":output") {
+			return bytes.TrimSpace(output)
+		}
+	}
+	return body
+}
@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+func TestClientActionWrapper(t *testing.T) {
+	var reqBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		reqBody = string(data)
+		if strings.HasPrefix(reqBody, `{"m:input"`) {
+			fmt.Fprint(w, `{"m:output":{"out":"wrapped"}}`)
+		} else {
+			fmt.Fprint(w, `{"out":"unwrapped"}`)
+		}
+	}))
+	defer srv.Close()
+	m := requestBuilder{}.m(`rpc x { input { leaf in { type string; } } output { leaf out { type string; } } }`)
+	newClient := func(wrap bool) *client {
+		c := newTestClient(srv, m)
+		c.actionWrapper = wrap
+		return c
+	}
+	action := func(c *client, ctx context.Context) string {
+		b := node.NewBrowser(m, c.newClientNode().node())
+		out := b.RootWithContext(ctx).Find("x").Action(nodeutil.ReadJSON(`{"in":"hi"}`))
+		fc.AssertEqual(t, nil, out.LastErr)
+		actual, err := nodeutil.WriteJSON(out)
+		fc.AssertEqual(t, nil, err)
+		return actual
+	}
+	ctx := context.Background()
+	wrapped := newClient(true)
+	unwrapped := newClient(false)
+
+	fc.AssertEqual(t, `{"out":"wrapped"}`, action(wrapped, ctx))
+	fc.AssertEqual(t, `{"m:input":{"in":"hi"}}`, reqBody)
+	fc.AssertEqual(t, `{"out":"unwrapped"}`, action(unwrapped, ctx))
+	fc.AssertEqual(t, `{"in":"hi"}`, reqBody)
+
+	// per request
+	fc.AssertEqual(t, `{"out":"unwrapped"}`, action(wrapped, WithActionWrapper(ctx, false)))
+	fc.AssertEqual(t, `{"out":"wrapped"}`, action(unwrapped, WithActionWrapper(ctx, true)))
+
+	resp, output, err := wrapped.ActionRaw(ctx, "m:x", nodeutil.ReadJSON(`{"in":"hi"}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"m:input":{"in":"hi"}}`, reqBody)
+	raw, _ := ioutil.ReadAll(resp.Body)
+	fc.AssertEqual(t, `{"m:output":{"out":"wrapped"}}`, string(raw))
+	out, err := nodeutil.WriteJSON(node.Selection{
+		Node:        output,
+		Path:        node.NewRootPath(m.Actions()["x"].Output()),
+		Constraints: &node.Constraints{},
+		Context:     ctx,
+	})
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"out":"wrapped"}`, out)
+}
@@ -1,9 +1,14 @@
 package restconf
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/source"
 )
 
 func Test_findDeviceIdInUrl(t *testing.T) {
@@ -12,3 +17,68 @@ func Test_findDeviceIdInUrl(t *testing.T) {
 	dev = findDeviceIdInUrl("http://server:port/restconf/")
 	fc.AssertEqual(t, "", dev)
 }
+
+func TestConnectTimeout(t *testing.T) {
+	factory := Client{
+		YangPath:       source.Dir("./yang"),
+		ConnectTimeout: 100 * time.Millisecond,
+	}
+	t0 := time.Now()
+	// non-routable address, connect should never succeed
+	_, err := factory.NewDevice("http://10.255.255.1/restconf")
+	if err == nil {
+		t.Fatal("expected connection failure")
+	}
+	if elapsed := time.Since(t0); elapsed > 5*time.Second {
+		t.Errorf("connect timeout not honored, took %s", elapsed)
+	}
+}
+
+func TestDiscoverAddress(t *testing.T) {
+	hostMeta := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/host-meta" || hostMeta == "" {
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.Write([]byte(hostMeta))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	tests := []struct {
+		hostMeta string
+		expected string
+	}{
+		{
+			hostMeta: `<XRD xmlns='http://docs.oasis-open.org/ns/xri/xrd-1.0'>
+				<Link rel='author' href='/people'/>
+				<Link rel='restconf' href='/top/api/restconf'/>
+			</XRD>`,
+			expected: srv.URL + "/top/api/restconf/",
+		},
+		{
+			hostMeta: `{ "xrd" : { "link" : { "@rel" : "restconf", "@href" : "/gw/restconf" } } }`,
+			expected: srv.URL + "/gw/restconf/",
+		},
+		{
+			hostMeta: `{ "xrd" : { "link" : [{ "@rel" : "restconf", "@href" : "https://other/restconf" }] } }`,
+			expected: "https://other/restconf/",
+		},
+		{
+			// not available, assume given url is root
+			hostMeta: "",
+			expected: srv.URL + "/restconf/",
+		},
+		{
+			hostMeta: `<XRD><Link rel='author' href='/people'/></XRD>`,
+			expected: srv.URL + "/restconf/",
+		},
+	}
+	for _, test := range tests {
+		hostMeta = test.hostMeta
+		address, err := DiscoverAddress(ctx, srv.Client(), srv.URL+"/restconf")
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, address.Base)
+		fc.AssertEqual(t, test.expected+"data/", address.Data)
+	}
+}
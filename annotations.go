@@ -0,0 +1,73 @@
+package restconf
+
+import (
+	"context"
+	"sync"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+type annotationsKey struct{}
+
+// Annotations are YANG metadata annotations (RFC 7952) on leaves, such as
+// ietf-origin:origin from NMDA, that server sent along w/data read using
+// context from WithAnnotations.  JSON has them as "@leaf" sibling of leaf.
+type Annotations struct {
+	lock   sync.Mutex
+	leaves map[string]map[string]interface{}
+}
+
+// WithAnnotations collects annotations on leaves read w/returned context.
+//
+//	ctx, anns := restconf.WithAnnotations(ctx)
+//	nodeutil.WriteJSON(b.RootWithContext(ctx).Find("system"))
+//	origin, _ := anns.Get("ietf-system:system/hostname", "ietf-origin:origin")
+func WithAnnotations(ctx context.Context) (context.Context, *Annotations) {
+	anns := &Annotations{leaves: make(map[string]map[string]interface{})}
+	return context.WithValue(ctx, annotationsKey{}, anns), anns
+}
+
+func annotationsFrom(ctx context.Context) (*Annotations, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	anns, found := ctx.Value(annotationsKey{}).(*Annotations)
+	return anns, found
+}
+
+// Leaf is all annotations on leaf in module:path form keyed by module
+// qualified annotation name or nil if leaf had none or was not read
+func (self *Annotations) Leaf(path string) map[string]interface{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.leaves[path]
+}
+
+// Get is a single annotation on leaf in module:path form
+func (self *Annotations) Get(path string, name string) (interface{}, bool) {
+	v, found := self.Leaf(path)[name]
+	return v, found
+}
+
+func (self *Annotations) add(p *node.Path, leaf string, md map[string]interface{}) {
+	path := meta.RootModule(p.Meta()).Ident() + ":" + urlPath(p)
+	if path[len(path)-1] != ':' {
+		path += "/"
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.leaves[path+leaf] = md
+}
+
+// readAnnotations records "@leaf" from container data if caller asked for
+// annotations
+func readAnnotations(r node.FieldRequest, data map[string]interface{}) {
+	anns, found := annotationsFrom(r.Selection.Context)
+	if !found {
+		return
+	}
+	if md, valid := data["@"+r.Meta.Ident()].(map[string]interface{}); valid {
+		anns.add(r.Selection.Path, r.Meta.Ident(), md)
+	}
+}
@@ -0,0 +1,51 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestAnnotations(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			leaf a { type int32; }
+			leaf b { type int32; }
+			container z { leaf c { type string; } }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "OPTIONS":
+		case "GET":
+			fmt.Fprint(w, `{"a":1,"@a":{"ietf-origin:origin":"ietf-origin:intended","v:note":"x"},"b":2,
+				"z":{"c":"k","@c":{"ietf-origin:origin":"ietf-origin:system"}}}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	b, err := c.Browser("m")
+	fc.AssertEqual(t, nil, err)
+
+	ctx, anns := WithAnnotations(context.Background())
+	sel := b.RootWithContext(ctx).Find("x")
+	fc.AssertEqual(t, nil, sel.LastErr)
+	actual, err := nodeutil.WriteJSON(sel)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"a":1,"b":2,"z":{"c":"k"}}`, actual)
+
+	origin, found := anns.Get("m:x/a", "ietf-origin:origin")
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, "ietf-origin:intended", origin)
+	fc.AssertEqual(t, 2, len(anns.Leaf("m:x/a")))
+	fc.AssertEqual(t, true, anns.Leaf("m:x/b") == nil)
+	origin, _ = anns.Get("m:x/z/c", "ietf-origin:origin")
+	fc.AssertEqual(t, "ietf-origin:system", origin)
+}
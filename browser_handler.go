@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"mime"
 	"net/http"
+	"sync"
 
 	"context"
 
@@ -68,6 +69,24 @@ func (self *browserHandler) ServeHTTP(ctx context.Context, w http.ResponseWriter
 					subscribeCount--
 				}()
 
+				// callback and this goroutine both write to w
+				var wLock sync.Mutex
+				var wDone bool
+				send := func(data []byte) {
+					wLock.Lock()
+					defer wLock.Unlock()
+					if wDone {
+						return
+					}
+					w.Write(data)
+					flusher.Flush()
+				}
+				defer func() {
+					wLock.Lock()
+					wDone = true
+					wLock.Unlock()
+				}()
+
 				errOnSend := make(chan error, 20)
 				sub, err = sel.Notifications(func(msg node.Selection) {
 					defer func() {
@@ -93,14 +112,15 @@ func (self *browserHandler) ServeHTTP(ctx context.Context, w http.ResponseWriter
 					}
 
 					fmt.Fprint(&buf, "\n\n")
-					w.Write(buf.Bytes())
-					flusher.Flush()
+					send(buf.Bytes())
 				})
 				if err != nil {
 					fc.Err.Print(err)
 					return
 				}
 				defer sub()
+				// send headers now so client knows it's subscribed
+				send(nil)
 				select {
 				case <-r.Context().Done():
 					// normal client closing subscription
@@ -0,0 +1,39 @@
+package restconf
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCertNotPinned is when device's certificate is not one of
+// Client.PinnedCertSHA256
+var ErrCertNotPinned = errors.New("device certificate does not match any pinned fingerprint")
+
+// verifyPinned accepts connection only if leaf certificate's SHA-256
+// fingerprint is one of pins.  Pins are hex and may be separated by colons
+// as openssl prints them.
+func verifyPinned(pins []string) func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	normalized := make(map[string]struct{}, len(pins))
+	for _, pin := range pins {
+		normalized[normalizePin(pin)] = struct{}{}
+	}
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("%w. device sent no certificate", ErrCertNotPinned)
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		fingerprint := hex.EncodeToString(sum[:])
+		if _, found := normalized[fingerprint]; !found {
+			return fmt.Errorf("%w. got %s", ErrCertNotPinned, fingerprint)
+		}
+		return nil
+	}
+}
+
+func normalizePin(pin string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(pin), ":", "", -1))
+}
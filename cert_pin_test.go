@@ -0,0 +1,43 @@
+package restconf
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestPinnedCert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	}))
+	defer srv.Close()
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	colons := make([]string, len(sum))
+	for i, b := range sum {
+		colons[i] = fmt.Sprintf("%02X", b)
+	}
+	tests := []struct {
+		pins  []string
+		valid bool
+	}{
+		{[]string{fmt.Sprintf("%x", sum)}, true},
+		{[]string{"00", strings.Join(colons, ":")}, true},
+		{[]string{strings.Repeat("ab", 32)}, false},
+	}
+	for _, test := range tests {
+		factory := &Client{PinnedCertSHA256: test.pins}
+		c := &http.Client{Transport: factory.newTransport()}
+		resp, err := c.Get(srv.URL)
+		if test.valid {
+			fc.AssertEqual(t, nil, err)
+			resp.Body.Close()
+		} else {
+			fc.AssertEqual(t, true, errors.Is(err, ErrCertNotPinned))
+		}
+	}
+}
@@ -1,24 +1,31 @@
 package restconf
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/freeconf/restconf/device"
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
-	"github.com/freeconf/yang/nodeutil"
 	"github.com/freeconf/yang/parser"
 	"github.com/freeconf/yang/source"
+	"github.com/freeconf/yang/val"
 )
 
 // NewClient interfaces with a remote RESTCONF server.  This also implements device.Device
@@ -27,6 +34,287 @@ import (
 // with one minor exceptions. Peek() wouldn't work.
 type Client struct {
 	YangPath source.Opener
+
+	// Optional: Limits time to establish TCP connection and TLS handshake to
+	// device.  Useful to fail fast on unreachable devices w/o limiting how long
+	// a slow but live device has to respond.  Zero means no limit.
+	ConnectTimeout time.Duration
+
+	// Optional: Only trust device if SHA-256 fingerprint of it's certificate
+	// is one of these, in hex w/ or w/o colons.  For devices w/self-signed
+	// certificates.  Default is to not verify certificates at all.
+	PinnedCertSHA256 []string
+
+	// Optional: Wire format for data.  Default is JSONCodec.  See also
+	// CanonicalJSONCodec and XMLCodec
+	Codec Codec
+
+	// Optional: Serialize edits directly into request body as it is sent instead
+	// of buffering entire payload first.  Server must accept chunked requests.
+	StreamEdits bool
+
+	// Optional: Limits how long downloading all of device's schema can take
+	// when creating device so a device that hangs serving schema doesn't block
+	// forever.  Zero means no limit.
+	BootstrapTimeout time.Duration
+
+	// Optional: Record every request and response to device for debugging.  See
+	// Playback
+	Record io.Writer
+
+	// Optional: Serve requests from a recording made with Record instead of
+	// talking to device. Schema is not recorded so all modules must be
+	// available in YangPath.
+	Playback io.Reader
+
+	// Optional: Called before each edit is sent with what is changing. Useful
+	// to log or confirm edits.  Returning an error aborts the edit.
+	OnBeforeSend BeforeSend
+
+	// Optional: NMDA datastore to read and edit by default such as
+	// ietf-datastores:operational.  Empty means the conventional /data
+	// resource.  See WithDatastore to pick a datastore per request.
+	Datastore string
+
+	// Optional: Gzip request payloads at least this many bytes once server
+	// advertises it accepts gzip with Accept-Encoding response header.  Zero
+	// means never compress.
+	CompressThreshold int
+
+	// Optional: Limits on idle connections kept in the transport shared by
+	// every device this factory creates.  Zero uses http.Transport defaults.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+
+	// Optional: Customize transport for a single device such as a different
+	// TLS config or adding auth.  Wrap or return shared to keep reusing
+	// pooled connections.
+	DeviceTransport func(url string, shared *http.Transport) http.RoundTripper
+
+	// Optional: Change url of every request just before it is sent such as to
+	// add path prefix of an API gateway device is behind.  Applies to data,
+	// operations, schema and notification streams alike.
+	URLRewriter func(url string) string
+
+	// Optional: Choose configuration of each device by it's url, such as
+	// credentials and compliance for the group device belongs to, instead of
+	// a factory per group.  Called by NewDevice before contacting device.
+	// Returning an error fails NewDevice.
+	DeviceConfig func(url string) (DeviceConfig, error)
+
+	// Optional: Notifications buffered per subscription between reading them
+	// from device and handing them to subscriber so a slow subscriber doesn't
+	// stall reading from device.  Zero means unbuffered.
+	StreamBuffer int
+
+	// Optional: When StreamBuffer is full, drop newest notifications instead of
+	// waiting for subscriber to catch up.  Waiting is default because dropping
+	// loses data but waiting can back up device's stream.
+	StreamDropWhenFull bool
+
+	// Optional: Receive notifications by repeatedly GETting stream, each
+	// request waiting on device until next notification, instead of SSE for
+	// networks w/proxies that buffer SSE.  See longPollEvents.
+	LongPoll bool
+
+	// Optional: How notifications are encoded, StreamEncodingJSON, the
+	// default, StreamEncodingXML or StreamEncodingAuto to pick from what
+	// device's ietf-restconf-monitoring lists.  See WithStreamEncoding.
+	StreamEncoding string
+
+	// Optional: with-defaults mode used to read what is on device before an
+	// edit.  Default is trim.  Use explicit for servers that track which
+	// values were explicitly set so values equal to their default are not
+	// mistaken for unset.
+	EditWithDefaults string
+
+	// Optional: Maps a module name and file extension to what is appended to
+	// device's schema url to download it for servers that do not serve schema
+	// as <name><ext>.  Example: name@revision.yang
+	SchemaName func(name string, ext string) (string, error)
+
+	// Optional: Make edits and deletes conditional on resource not changing
+	// since it was last read.  Sends If-Match when read returned an ETag
+	// otherwise If-Unmodified-Since when read returned Last-Modified.  If
+	// resource changed edit fails w/ErrConflict.
+	ConditionalEdits bool
+
+	// Optional: Identifies your application in device's logs. Default is
+	// freeconf-restconf
+	UserAgent string
+
+	// Optional: Sent as Prefer: return=<EditReturn> on edits.  ReturnMinimal
+	// asks server not to echo resource back and ReturnRepresentation asks for
+	// server's version of resource.  Empty sends no preference.  See
+	// WithEditResult to receive the representation.
+	EditReturn string
+
+	// Optional: Attach anti-CSRF tokens to unsafe requests for devices behind
+	// web gateways that require them. See HeaderTokens.
+	CsrfTokens CsrfTokens
+
+	// Optional: Send legacy config=true or config=false instead of
+	// content=config or content=nonconfig for older servers.  See WithContent.
+	LegacyConfigParam bool
+
+	// Optional: Edit existing resources w/PATCH instead of PUT.  Device is
+	// asked w/OPTIONS which patch formats it accepts thru Accept-Patch header
+	// and plain patch is preferred over YANG Patch.  If device accepts
+	// neither, edits are sent w/PUT.
+	PatchEdits bool
+
+	// Optional: Generates a key sent in Idempotency-Key header of each action
+	// so device can recognize a retried action and not execute it twice.  See
+	// RandomIdempotencyKey.  Only useful if device supports idempotency keys.
+	IdempotencyKey func() string
+
+	// Optional: Retry actions this many times after transport errors or
+	// 502, 503 or 504 responses.  Only applies when IdempotencyKey is set,
+	// otherwise actions are never retried because they are not safe to repeat.
+	ActionRetries int
+
+	// Optional: Called when an action is abandoned because it's context was
+	// cancelled before device responded.  Device may still be running action
+	// so this is where to ask device to stop it if device has a way to.  Key is
+	// Idempotency-Key sent w/action or empty if there is no IdempotencyKey.
+	// Credentials in url are redacted.
+	ActionCanceled func(url string, key string)
+
+	// Optional: Limits concurrent requests made by batch operations such as
+	// Exists.  Default is 4
+	MaxInFlight int
+
+	// Optional: Translate unsuccessful responses into your own errors.  Return
+	// nil to use default mapping which is an error that errors.Is recognizes
+	// as fc.NotFoundError, fc.ConflictError and so on.
+	ErrorMapper func(resp *http.Response, body []byte) error
+
+	// Optional: Method used to check a resource exists when navigating to it,
+	// either ProbeOptions, the default, or ProbeHead.  Some servers answer HEAD
+	// more cheaply and HEAD also returns ETag or Last-Modified which is
+	// remembered for ConditionalEdits w/o having to read resource.
+	NavigationProbe string
+
+	// Optional: Layouts, in order, to parse eventTime of notifications that
+	// come in an RFC 8040 envelope.  Default is DefaultEventTimeLayouts.
+	// Notifications w/an eventTime that cannot be parsed are error nodes.  See
+	// EventTime.
+	EventTimeLayouts []string
+
+	// Optional: Find RESTCONF root from device's /.well-known/host-meta
+	// instead of assuming url given to NewDevice is root.  See
+	// DiscoverAddress.
+	DiscoverRoot bool
+
+	// Optional: Where actions are sent for servers that only accept one form,
+	// ActionUrlData, the default, ActionUrlOperations or ActionUrlAuto.
+	ActionUrl string
+
+	// Optional: Keep connections on HTTP/1.1 for devices that advertise
+	// HTTP/2 but mishandle it.  Default negotiates HTTP/2 w/devices that
+	// support it over TLS.  See Protocol to learn what was negotiated.
+	ForceHTTP1 bool
+
+	// Optional: Fail edits, deletes and actions w/ErrReadOnly w/o contacting
+	// device for tools that must never change device state.  Reads and
+	// notifications still work.
+	ReadOnly bool
+
+	// Optional: End resource urls w/a slash for devices that require one.
+	// Default is to never send a trailing slash, even if path given has one.
+	TrailingSlash bool
+
+	// Optional: Keep up to this many responses to reads in memory for as
+	// long as device says they are fresh w/Cache-Control or Expires so
+	// repeated reads do not go to device.  Stale responses are revalidated
+	// w/a conditional GET.  Zero, the default, caches nothing.  See
+	// WithoutCache.
+	ResponseCacheSize int
+
+	// Optional: Share one request to device between identical reads that are
+	// made at same time such as many goroutines reading same hot path.  Off
+	// by default because reads are then no longer independent, if first
+	// reader gives up waiting so do the rest.
+	CoalesceReads bool
+
+	// Optional: How to read lists device sent w/more than one entry w/same
+	// key, DuplicateKeysStrict, DuplicateKeysKeepFirst or
+	// DuplicateKeysKeepLast.  Default reads every entry as device sent it.
+	// Only JSON is checked.
+	DuplicateKeys string
+
+	// Optional: Check action output from device has only fields in action's
+	// output definition and all mandatory leaves, returning
+	// ErrUnexpectedOutput otherwise.  Only JSON output is checked.
+	ValidateActionOutput bool
+
+	// Optional: Check staged edits against mandatory and when statements of
+	// schema before sending them, returning ErrInvalidEdit w/path of first
+	// violation.  When is evaluated against staged data only.  Mandatory
+	// nodes are not checked in data that is merged, see
+	// Compliance.PatchEdits.
+	ValidateEdits bool
+
+	// Optional: Wrap action input in "module:input" and expect output in
+	// "module:output" as RFC 8040 section 3.6 has it.  Default sends and
+	// reads them unwrapped as this package's server does.  Only JSON is
+	// wrapped.  See WithActionWrapper.
+	ActionWrapper bool
+
+	// Optional: Render list keys in urls as device expects them when it
+	// differs from DefaultKeyFormat, for lists keyed by booleans, enums or
+	// identityrefs for example.
+	KeyFormat KeyFormat
+
+	// Optional: Download schema of modules device says it deviates from
+	// device even when they are in YangPath because local copy would not
+	// match what device implements.  Other modules still come from YangPath
+	// first.
+	RemoteDeviatedSchema bool
+
+	// Optional: Only prefix JSON member names w/module name where namespace
+	// changes as RFC 7951 has it, for strict servers that reject members
+	// prefixed w/same module as their parent.  Payloads are buffered to
+	// rewrite them.
+	JSONNamespaceChangesOnly bool
+
+	// Optional: Ask device for a module again w/a conditional GET once it has
+	// been cached this long so a long-lived process picks up schema of an
+	// upgraded device.  Browsers made after a module changes use new module,
+	// browsers made before keep old one.  Zero, the default, never asks again.
+	ModuleMaxAge time.Duration
+
+	// Optional: When device rejects an edit because an element or namespace
+	// is unknown to it, such as after an upgrade removed it, load module
+	// from device again and send edit once more using new module.  Data new
+	// module does not have is dropped.  Only JSON errors w/error-tag
+	// unknown-element or unknown-namespace count, edits under mount points
+	// are not retried.
+	RetryStaleSchema bool
+
+	// Optional: Most levels of data to read below resource being read.  Sent
+	// to device as depth parameter and JSON responses nested deeper fail w/
+	// ErrDepthExceeded so a misbehaving device cannot send data w/o end.
+	// Zero, the default, reads all levels.
+	MaxDepth int
+
+	// Optional: After loading device's schema, open a keep-alive connection
+	// and load WarmUpModules so first real request does not pay for them.
+	// NewDevice waits at most WarmUpBudget, the rest of warm up continues in
+	// background.
+	WarmUp bool
+
+	// Optional: Modules to load during warm up that are not in device's
+	// yang-library such as modules of mount points.
+	WarmUpModules []string
+
+	// Optional: How long NewDevice waits for warm up.  Zero does not wait.
+	WarmUpBudget time.Duration
+
+	// Optional: Structured logging of requests and notification streams w/
+	// method, url, status, duration and device.  See SlogLogger.  Default
+	// logs thru fc.
+	Logger Logger
 }
 
 func ProtocolHandler(ypath source.Opener) device.ProtocolHandler {
@@ -35,14 +323,15 @@ func ProtocolHandler(ypath source.Opener) device.ProtocolHandler {
 }
 
 type Address struct {
-	Base     string
-	Data     string
-	Stream   string
-	Ui       string
-	Schema   string
-	DeviceId string
-	Host     string
-	Origin   string
+	Base       string
+	Data       string
+	Operations string
+	Stream     string
+	Ui         string
+	Schema     string
+	DeviceId   string
+	Host       string
+	Origin     string
 }
 
 func NewAddress(urlAddr string) (Address, error) {
@@ -57,15 +346,22 @@ func NewAddress(urlAddr string) (Address, error) {
 	}
 
 	return Address{
-		Base:     urlAddr,
-		Data:     urlAddr + "data/",
-		Schema:   urlAddr + "schema/",
-		Ui:       urlAddr + "ui/",
-		Origin:   "http://" + urlParts.Host,
-		DeviceId: findDeviceIdInUrl(urlAddr),
+		Base:       urlAddr,
+		Data:       urlAddr + "data/",
+		Operations: urlAddr + "operations/",
+		Schema:     urlAddr + "schema/",
+		Ui:         urlAddr + "ui/",
+		Origin:     "http://" + urlParts.Host,
+		DeviceId:   findDeviceIdInUrl(urlAddr),
 	}, nil
 }
 
+// Datastore is root of data resources for a NMDA datastore (RFC 8527) such
+// as ietf-datastores:running
+func (self Address) Datastore(datastore string) string {
+	return self.Base + "ds/" + datastore + "/"
+}
+
 func findDeviceIdInUrl(addr string) string {
 	segs := strings.SplitAfter(addr, "/restconf=")
 	if len(segs) == 2 {
@@ -75,50 +371,320 @@ func findDeviceIdInUrl(addr string) string {
 	return ""
 }
 
+// NewDevice connects to device and loads it's schema.  Device is safe to use
+// from multiple goroutines: Browser, Modules and other requests can be called
+// concurrently.  Browsers are not, each goroutine should get it's own.
+// Device returned is also a Device of this package for requests only
+// RESTCONF can make.
 func (self Client) NewDevice(url string) (device.Device, error) {
 	address, err := NewAddress(url)
 	if err != nil {
 		return nil, err
 	}
+	config, err := self.deviceConfig(url)
+	if err != nil {
+		return nil, err
+	}
+	compliance := config.Compliance
+	transport := config.Transport
+	if self.URLRewriter != nil {
+		transport = rewriteTransport{rewrite: self.URLRewriter, next: transport}
+	}
 	httpClient := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
+		Transport: transport,
+	}
+	if self.DiscoverRoot {
+		if address, err = DiscoverAddress(context.Background(), httpClient, url); err != nil {
+			return nil, err
+		}
 	}
+	userAgent := config.UserAgent
 	remoteSchemaPath := httpStream{
-		ypath:  self.YangPath,
-		client: httpClient,
-		url:    address.Schema,
+		ypath:     self.YangPath,
+		client:    httpClient,
+		url:       address.Schema,
+		userAgent: userAgent,
+		name:      self.SchemaName,
+		deviceId:  address.DeviceId,
+
+		remoteDeviated: self.RemoteDeviatedSchema,
 	}
+	codec := config.Codec
 	c := &client{
-		address:    address,
-		yangPath:   self.YangPath,
-		schemaPath: source.Any(self.YangPath, remoteSchemaPath.OpenStream),
-		client:     httpClient,
+		address: address,
+		clientOptions: clientOptions{
+			yangPath:     self.YangPath,
+			schemaPath:   source.Any(self.YangPath, remoteSchemaPath.OpenStream),
+			client:       httpClient,
+			codec:        codec,
+			streamEdits:  self.StreamEdits,
+			onBeforeSend: self.OnBeforeSend,
+
+			compressThreshold: self.CompressThreshold,
+			datastore:         self.Datastore,
+			streamBuffer:      self.StreamBuffer,
+			streamDrop:        self.StreamDropWhenFull,
+			longPoll:          self.LongPoll,
+			streamEnc:         self.StreamEncoding,
+			userAgent:         userAgent,
+			editWithDefaults:  self.EditWithDefaults,
+			schemaName:        self.SchemaName,
+			conditionalEdits:  self.ConditionalEdits,
+			editReturn:        self.EditReturn,
+			csrf:              self.CsrfTokens,
+			legacyConfig:      compliance.LegacyConfigParam,
+			patchEdits:        compliance.PatchEdits,
+			logger:            deviceLogger(self.Logger, address),
+			idempotencyKey:    self.IdempotencyKey,
+			actionRetries:     self.ActionRetries,
+			actionCanceled:    self.ActionCanceled,
+			maxInFlight:       self.MaxInFlight,
+			errorMapper:       self.ErrorMapper,
+			probe:             self.NavigationProbe,
+			eventTimeLayouts:  self.EventTimeLayouts,
+			actionUrl:         compliance.ActionUrl,
+			readOnly:          self.ReadOnly,
+			trailingSlash:     compliance.TrailingSlash,
+			cache:             newResponseCache(self.ResponseCacheSize),
+			reads:             newReadGroup(self.CoalesceReads),
+			duplicateKeys:     self.DuplicateKeys,
+			validateOutput:    self.ValidateActionOutput,
+			validateEdits:     self.ValidateEdits,
+			actionWrapper:     compliance.ActionWrapper,
+			keyFormat:         compliance.KeyFormat,
+			remoteDeviated:    self.RemoteDeviatedSchema,
+			moduleMaxAge:      self.ModuleMaxAge,
+			retryStale:        self.RetryStaleSchema,
+			maxDepth:          self.MaxDepth,
+			namespaceChanges:  compliance.JSONNamespaceChangesOnly,
+		},
 	}
-	d := &clientNode{support: c, device: address.DeviceId}
+	c.support = c
+	if self.Playback != nil {
+		if c.support, err = newPlayer(self.Playback); err != nil {
+			return nil, err
+		}
+	} else if self.Record != nil {
+		c.support = newRecorder(c, self.Record)
+	}
+	d := c.newClientNode()
 	m := parser.RequireModule(self.YangPath, "ietf-yang-library")
 	b := node.NewBrowser(m, d.node())
-	modules, err := device.LoadModules(b, remoteSchemaPath)
+	ctx := context.Background()
+	if self.BootstrapTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, self.BootstrapTimeout)
+		defer cancel()
+	}
+	loader := &bootstrapLoader{schema: remoteSchemaPath, ctx: ctx}
+	loader.schema.ctx = ctx
+	modules, hnds, err := device.LoadModuleHnds(b, loader)
+	if err != nil && loader.failed == "" && ctx.Err() == nil {
+		// device may only have newer revision of yang-library w/o modules-state.
+		// if it doesn't have that either, original error is more useful
+		newModules, newHnds, newErr := c.loadYangLibrary2019(ctx, loader)
+		if newErr == nil || loader.failed != "" {
+			modules, hnds, err = newModules, newHnds, newErr
+		}
+	}
 	fc.Debug.Printf("loaded modules %v", modules)
 	if err != nil {
-		return nil, fmt.Errorf("could not load modules. %s", err)
+		return nil, loader.bootstrapErr(err)
 	}
 	c.modules = modules
+	c.moduleHnds = hnds
+	c.schemaLoaded = time.Now()
+	if self.WarmUp {
+		c.warmUp(self.WarmUpModules, self.WarmUpBudget)
+	}
 	return c, nil
 }
 
+// ModuleInfo is summary of a module as the device reported it in it's
+// yang-library which may differ from what was parsed locally.
+type ModuleInfo struct {
+	Name       string
+	Revision   string
+	Namespace  string
+	Features   []string
+	Deviations []ModuleInfo
+}
+
+// sharedTransports pool connections across all devices from factories w/
+// same transport settings.  Factory is a value so they cannot live there.
+var sharedTransports = struct {
+	sync.Mutex
+	byKey map[transportKey]*http.Transport
+}{byKey: make(map[transportKey]*http.Transport)}
+
+// transportKey are factory's settings that newTransport uses
+type transportKey struct {
+	connectTimeout      time.Duration
+	pinnedCerts         string
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	forceHTTP1          bool
+}
+
+// sharedTransport is created on first device so connections are pooled
+// across all devices
+func (self Client) sharedTransport() *http.Transport {
+	key := transportKey{
+		connectTimeout:      self.ConnectTimeout,
+		pinnedCerts:         strings.Join(self.PinnedCertSHA256, ","),
+		maxIdleConns:        self.MaxIdleConns,
+		maxIdleConnsPerHost: self.MaxIdleConnsPerHost,
+		forceHTTP1:          self.ForceHTTP1,
+	}
+	sharedTransports.Lock()
+	defer sharedTransports.Unlock()
+	t, found := sharedTransports.byKey[key]
+	if !found {
+		t = self.newTransport()
+		sharedTransports.byKey[key] = t
+	}
+	return t
+}
+
+func (self Client) newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: self.ConnectTimeout,
+	}
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true,
+	}
+	if len(self.PinnedCertSHA256) > 0 {
+		// chain isn't verified, only the pin
+		tlsConfig.VerifyPeerCertificate = verifyPinned(self.PinnedCertSHA256)
+	}
+	t := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: self.ConnectTimeout,
+		MaxIdleConns:        self.MaxIdleConns,
+		MaxIdleConnsPerHost: self.MaxIdleConnsPerHost,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   !self.ForceHTTP1,
+	}
+	if self.ForceHTTP1 {
+		// non-nil but empty disables HTTP/2 regardless of GODEBUG settings
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return t
+}
+
+const defaultUserAgent = "freeconf-restconf"
+
 var badAddressErr = errors.New("Expected format: http://server/restconf[=device]/operation/module:path")
 
+// client is safe for concurrent use, state shared between requests is
+// guarded by a lock.  Each Browser has it's own clientNode which is not.
 type client struct {
-	address    Address
-	yangPath   source.Opener
-	schemaPath source.Opener
-	client     *http.Client
-	origin     string
-	modules    map[string]*meta.Module
+	clientOptions
+
+	address      Address
+	origin       string
+	modules      map[string]*meta.Module
+	modulesLock  sync.RWMutex
+	moduleChecks map[string]moduleCheck
+	schemaLoaded time.Time
+	moduleHnds   []*device.ModuleHnd
+	// concurrent revalidations of a module share one request to device
+	moduleReads readGroup
+	// time.Now unless a test replaces it
+	clock func() time.Time
+
+	// modules of each datastore from 2019 yang-library, nil if device
+	// did not say
+	datastoreModules map[string]map[string]*meta.Module
+
+	// normally self but can be replaced to intercept all requests
+	support clientSupport
+
+	// methods server allows on each resource from OPTIONS requests
+	allowed     map[string][]string
+	acceptPatch map[string][]string
+	allowedLock sync.RWMutex
+
+	acceptsGzip int32
+
+	// HTTP version of last response
+	protocol atomic.Value
+
+	// see Client.StreamEncoding, learned once for StreamEncodingAuto
+	advertisedEnc  string
+	advertisedLock sync.Mutex
+
+	// modules under schema mount points, loaded on first use
+	mounts    map[string]map[string]*meta.Module
+	mountRefs map[string]string
+	mountLock sync.Mutex
+
+	// ETag or Last-Modified of each resource when it was last read
+	validators     map[string]validator
+	validatorsLock sync.Mutex
+
+	// active notification subscriptions
+	streams     map[*streamCounters]int
+	streamsLock sync.Mutex
+}
+
+// clientOptions are from Client and do not change after NewDevice so
+// clients of mount points get them all by copying
+type clientOptions struct {
+	yangPath     source.Opener
+	schemaPath   source.Opener
+	client       *http.Client
+	moduleMaxAge time.Duration
+	retryStale   bool
+	maxDepth     int
+
+	codec        Codec
+	streamEdits  bool
+	onBeforeSend BeforeSend
+	patchEdits   bool
+
+	// nil logs thru fc
+	logger Logger
+
+	idempotencyKey func() string
+	actionRetries  int
+	actionCanceled func(url string, key string)
+	actionUrl      string
+	readOnly       bool
+	trailingSlash  bool
+	duplicateKeys  string
+	validateOutput bool
+	validateEdits  bool
+	actionWrapper  bool
+	keyFormat      KeyFormat
+	remoteDeviated bool
+	maxInFlight    int
+	errorMapper    func(resp *http.Response, body []byte) error
+
+	// shared w/mount points
+	cache *responseCache
+	reads *readGroup
+
+	compressThreshold int
+	namespaceChanges  bool
+
+	// default NMDA datastore, empty for /data
+	datastore string
+
+	streamBuffer     int
+	streamDrop       bool
+	longPoll         bool
+	eventTimeLayouts []string
+	streamEnc        string
+
+	userAgent        string
+	probe            string
+	editWithDefaults string
+	editReturn       string
+	csrf             CsrfTokens
+	legacyConfig     bool
+	schemaName       func(name string, ext string) (string, error)
+	conditionalEdits bool
 }
 
 func (self *client) SchemaSource() source.Opener {
@@ -127,14 +693,16 @@ func (self *client) SchemaSource() source.Opener {
 
 func (self *client) UiSource() source.Opener {
 	s := httpStream{
-		client: self.client,
-		url:    self.address.Ui,
+		client:    self.client,
+		url:       self.address.Ui,
+		userAgent: self.userAgent,
+		deviceId:  self.address.DeviceId,
 	}
 	return s.OpenStream
 }
 
 func (self *client) Browser(module string) (*node.Browser, error) {
-	d := &clientNode{support: self, device: self.address.DeviceId}
+	d := self.newClientNode()
 	m, err := self.module(module)
 	if err != nil {
 		return nil, err
@@ -142,48 +710,222 @@ func (self *client) Browser(module string) (*node.Browser, error) {
 	return node.NewBrowser(m, d.node()), nil
 }
 
+func (self *client) newClientNode() *clientNode {
+	d := &clientNode{
+		support:      self.support,
+		device:       self.address.DeviceId,
+		codec:        self.codec,
+		streamEdits:  self.streamEdits,
+		onBeforeSend: self.onBeforeSend,
+
+		editWithDefaults: self.editWithDefaults,
+		editReturn:       self.editReturn,
+		legacyConfig:     self.legacyConfig,
+		probe:            self.probe,
+		maxDepth:         self.maxDepth,
+		validateEdits:    self.validateEdits,
+		patchEdits:       self.patchEdits,
+	}
+	if self.retryStale {
+		d.refreshSchema = self.refreshSchema
+	}
+	return d
+}
+
+// Protocol is HTTP version of last response from device such as HTTP/1.1 or
+// HTTP/2.0.  Empty until device has responded to a request.
+func (self *client) Protocol() string {
+	proto, _ := self.protocol.Load().(string)
+	return proto
+}
+
 func (self *client) Close() {
 }
 
+// Modules is a copy so caller can iterate it while other goroutines load
+// more modules
 func (self *client) Modules() map[string]*meta.Module {
-	return self.modules
+	self.modulesLock.RLock()
+	defer self.modulesLock.RUnlock()
+	mods := make(map[string]*meta.Module, len(self.modules))
+	for name, m := range self.modules {
+		mods[name] = m
+	}
+	return mods
+}
+
+// ModuleInfo lists modules, features and deviations from the device's
+// yang-library
+func (self *client) ModuleInfo() []ModuleInfo {
+	return moduleInfos(self.moduleHnds)
+}
+
+func moduleInfos(hnds []*device.ModuleHnd) []ModuleInfo {
+	infos := make([]ModuleInfo, len(hnds))
+	for i, hnd := range hnds {
+		infos[i] = ModuleInfo{
+			Name:      hnd.Name,
+			Revision:  hnd.Revision,
+			Namespace: hnd.Namespace,
+			Features:  hnd.Feature,
+		}
+		if len(hnd.Deviation) > 0 {
+			infos[i].Deviations = moduleInfos(hnd.Deviation)
+		}
+	}
+	return infos
 }
 
 func (self *client) module(module string) (*meta.Module, error) {
-	// caching module, but should replace w/cache that can refresh on stale
+	// caching module, refreshed from device after ModuleMaxAge
+	self.modulesLock.RLock()
 	m := self.modules[module]
-	if m == nil {
+	stale := m != nil && self.moduleStale(module)
+	self.modulesLock.RUnlock()
+	if stale {
+		// not under lock so a slow device doesn't hold up other modules
+		return self.revalidateModule(module, m), nil
+	}
+	if m != nil {
+		return m, nil
+	}
+	// loading under lock so concurrent callers share a single instance
+	self.modulesLock.Lock()
+	defer self.modulesLock.Unlock()
+	if m = self.modules[module]; m == nil {
 		var err error
 		if m, err = parser.LoadModule(self.schemaPath, module); err != nil {
 			return nil, err
 		}
 		self.modules[module] = m
+		self.moduleFresh(module, nil)
 	}
 	return m, nil
 }
 
 func (self *client) clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error) {
 	mod := meta.RootModule(p.Meta())
-	fullUrl := fmt.Sprint(self.address.Data, mod.Ident(), ":", p.StringNoModule())
-	req, err := http.NewRequest("GET", fullUrl, nil)
-	if err != nil {
-		return nil, err
+	fullUrl := self.resourceUrl(self.address.Data, mod.Ident()+":"+self.targetPath(p))
+	if params != "" {
+		fullUrl = fmt.Sprint(fullUrl, "?", params)
 	}
-	req.Header.Set("Accept", "text/event-stream")
-	fc.Info.Printf("<=> SSE %s", fullUrl)
-	resp, err := self.client.Do(req)
-	if err != nil {
-		return nil, err
+	start := time.Now()
+	logUrl := redactUrl(fullUrl)
+	done := make(chan struct{})
+	var events <-chan sseFrame
+	var closeEvents func()
+	// why device ended stream if it said
+	endErr := func() error { return nil }
+	if self.longPoll {
+		events, closeEvents = self.longPollEvents(ctx, fullUrl, done)
+		if !self.log(ctx, LogInfo, "stream opened", LogField{"url", logUrl}, LogField{"transport", "long-poll"}) {
+			fc.Info.Printf("<=> long poll %s", logUrl)
+		}
+	} else {
+		req, err := http.NewRequest("GET", fullUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", mimeEventStream)
+		if cursor, found := cursorFrom(ctx); found {
+			req.Header.Set("Last-Event-ID", cursor)
+		}
+		self.setUserAgent(req)
+		resp, err := self.client.Do(req)
+		if err != nil {
+			self.log(ctx, LogWarn, "stream failed", LogField{"url", logUrl}, LogField{"error", err.Error()})
+			return nil, self.requestErr("GET", fullUrl, err)
+		}
+		self.protocol.Store(resp.Proto)
+		if err := self.responseErr(resp); err != nil {
+			self.log(ctx, LogWarn, "stream failed", LogField{"url", logUrl}, LogField{"error", err.Error()})
+			return nil, self.requestErr("GET", fullUrl, err)
+		}
+		// not standard, see Subscription.UpdateFilter
+		if id := resp.Header.Get("Subscription-Id"); id != "" {
+			streamCountersFrom(ctx).subscriptionId.Store(id)
+		}
+		if !self.log(ctx, LogInfo, "stream opened", LogField{"url", logUrl}, LogField{"status", resp.StatusCode}, LogField{"duration", time.Since(start)}) {
+			fc.Info.Printf("<=> SSE %s", logUrl)
+		}
+		events = decodeSse(resp.Body, done)
+		closeEvents = func() {
+			resp.Body.Close()
+		}
+		endErr = func() error {
+			return streamTrailerErr(resp.Trailer)
+		}
 	}
-	stream := make(chan node.Node)
+	decode := decodeEvent
+	if self.streamEncoding(ctx) == StreamEncodingXML {
+		decode = decodeXmlEvent
+	}
+	stream := make(chan node.Node, self.streamBuffer)
+	counters := streamCountersFrom(ctx)
+	counters.opened(mod.Ident()+":"+self.targetPath(p), func() int {
+		return len(stream)
+	})
+	self.addStream(counters)
 	go func() {
-		events := decodeSse(resp.Body)
-		defer resp.Body.Close()
+		defer closeEvents()
+		defer close(stream)
+		defer self.removeStream(counters)
+		defer close(done)
+		defer func() {
+			self.log(ctx, LogInfo, "stream closed", LogField{"url", logUrl},
+				LogField{"duration", time.Since(start)},
+				LogField{"received", atomic.LoadUint64(&counters.received)},
+				LogField{"dropped", atomic.LoadUint64(&counters.dropped)})
+		}()
 		for {
 			select {
-			case event := <-events:
-				stream <- nodeutil.ReadJSONIO(bytes.NewReader(event))
+			case event, open := <-events:
+				if !open {
+					if ctx.Err() == nil {
+						if err := endErr(); err != nil {
+							counters.setErr(err)
+						} else if _, ended := counters.lastErr().(*StreamError); !ended {
+							counters.setErr(ErrStreamEnded)
+						}
+					}
+					return
+				}
+				if streamErr, isErr := streamFrameErr(event); isErr {
+					if !self.log(ctx, LogWarn, "stream error", LogField{"url", logUrl}, LogField{"error", streamErr.Error()}) {
+						fc.Err.Printf("%s %s", logUrl, streamErr)
+					}
+					counters.setErr(streamErr)
+					continue
+				}
+				atomic.AddUint64(&counters.received, 1)
+				n := decode(p.Meta(), event, self.eventTimeLayouts)
+				if errNode, isErr := n.(node.ErrorNode); isErr {
+					counters.setErr(errNode.Err)
+				}
+				if self.streamDrop {
+					select {
+					case stream <- n:
+						atomic.AddUint64(&counters.accepted, 1)
+						counters.setCursor(event.id)
+					default:
+						atomic.AddUint64(&counters.dropped, 1)
+						if !self.log(ctx, LogDebug, "notification dropped", LogField{"url", logUrl}) {
+							fc.Debug.Printf("dropped notification from %s, subscriber too slow", logUrl)
+						}
+					}
+					continue
+				}
+				select {
+				case stream <- n:
+					atomic.AddUint64(&counters.accepted, 1)
+					counters.setCursor(event.id)
+				case <-ctx.Done():
+					closeEvents()
+					return
+				}
 			case <-ctx.Done():
+				// unblocks decoder if it's waiting on device
+				closeEvents()
 				return
 			}
 		}
@@ -192,15 +934,146 @@ func (self *client) clientStream(params string, p *node.Path, ctx context.Contex
 	return stream, nil
 }
 
+// bootstrapLoader bounds downloading all of a device's schema by a single
+// deadline and remembers progress so timeout errors can say how far it got
+type bootstrapLoader struct {
+	schema httpStream
+	ctx    context.Context
+	loaded []string
+
+	// module that could not be loaded, if any
+	failed string
+}
+
+func (self *bootstrapLoader) ResolveModuleHnd(hnd device.ModuleHnd) (*meta.Module, error) {
+	if err := self.ctx.Err(); err != nil {
+		return nil, self.timeoutErr(hnd, err)
+	}
+	m, err := self.schema.ResolveModuleHnd(hnd)
+	if err != nil {
+		self.failed = hnd.Name
+		if ctxErr := self.ctx.Err(); ctxErr != nil {
+			return nil, self.timeoutErr(hnd, ctxErr)
+		}
+		return nil, err
+	}
+	self.loaded = append(self.loaded, hnd.Name)
+	return m, nil
+}
+
+func (self *bootstrapLoader) timeoutErr(hnd device.ModuleHnd, err error) error {
+	return fmt.Errorf("%w. aborted loading schema %s after loading %d modules %v",
+		err, hnd.Name, len(self.loaded), self.loaded)
+}
+
+var (
+	// ErrDeviceUnreachable is when device could not be contacted or did not
+	// respond in time
+	ErrDeviceUnreachable = errors.New("device unreachable")
+
+	// ErrNoYangLibrary is when device did not serve ietf-yang-library so there
+	// is no way to know what modules it supports
+	ErrNoYangLibrary = errors.New("yang library not available")
+
+	// ErrSchemaParse is when a module device lists could not be downloaded
+	// or parsed
+	ErrSchemaParse = errors.New("could not parse schema")
+
+	// ErrDeviceUntrusted is when device was reached but it's certificate could
+	// not be verified
+	ErrDeviceUntrusted = errors.New("device certificate not trusted")
+)
+
+// BootstrapError is why NewDevice could not load a device's schema. Use
+// errors.Is w/ErrDeviceUnreachable, ErrDeviceUntrusted, ErrCertNotPinned,
+// ErrNoYangLibrary or ErrSchemaParse to categorize failure.  Underlying cause
+// is preserved.
+type BootstrapError struct {
+	Kind error
+
+	// Module being loaded when it failed, empty if failure was not specific
+	// to a module
+	Module string
+
+	Err error
+}
+
+func (self *BootstrapError) Error() string {
+	return fmt.Sprintf("could not load modules. %s. %s", self.Kind, self.Err)
+}
+
+func (self *BootstrapError) Unwrap() error {
+	return self.Err
+}
+
+func (self *BootstrapError) Is(target error) bool {
+	return target == self.Kind
+}
+
+func (self *bootstrapLoader) bootstrapErr(err error) error {
+	berr := &BootstrapError{Module: self.failed, Err: err}
+	var netErr net.Error
+	switch {
+	// tls and cancel errors are also net.Errors so check them first
+	case errors.Is(err, ErrCertNotPinned):
+		berr.Kind = ErrCertNotPinned
+	case isCertErr(err):
+		berr.Kind = ErrDeviceUntrusted
+	case errors.Is(err, context.Canceled):
+		berr.Kind = context.Canceled
+	case errors.As(err, &netErr), errors.Is(err, context.DeadlineExceeded):
+		berr.Kind = ErrDeviceUnreachable
+	case self.failed != "":
+		berr.Kind = ErrSchemaParse
+	default:
+		berr.Kind = ErrNoYangLibrary
+	}
+	return berr
+}
+
+// isCertErr is when device's certificate failed verification
+func isCertErr(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	return errors.As(err, &unknownAuthority) || errors.As(err, &hostname) || errors.As(err, &invalid)
+}
+
 // ClientSchema downloads schema and implements yang.StreamSource so it can transparently
 // be used in a YangPath.
 type httpStream struct {
 	ypath  source.Opener
 	client *http.Client
 	url    string
+
+	// optional, bounds schema download
+	ctx context.Context
+
+	userAgent string
+
+	// optional, maps module name to url suffix
+	name func(name string, ext string) (string, error)
+
+	// optional, for error messages
+	deviceId string
+
+	// optional, modules device deviates come from device first
+	remoteDeviated bool
 }
 
 func (self httpStream) ResolveModuleHnd(hnd device.ModuleHnd) (*meta.Module, error) {
+	if self.remoteDeviated && len(hnd.Deviation) > 0 {
+		// imports can still be local
+		var ypath source.Opener = self.OpenStream
+		if self.ypath != nil {
+			ypath = source.Any(self.OpenStream, self.ypath)
+		}
+		m, err := parser.LoadModule(ypath, hnd.Name)
+		if err == nil {
+			return m, nil
+		}
+		fc.Debug.Printf("could not load deviated module %s from device, using local copy. %s", hnd.Name, err)
+	}
 	m, _ := parser.LoadModule(self.ypath, hnd.Name)
 	if m != nil {
 		return m, nil
@@ -210,37 +1083,688 @@ func (self httpStream) ResolveModuleHnd(hnd device.ModuleHnd) (*meta.Module, err
 
 // OpenStream implements source.Opener
 func (self httpStream) OpenStream(name string, ext string) (io.Reader, error) {
-	fullUrl := self.url + name + ext
-	fc.Debug.Printf("httpStream url %s, name=%s, ext=%s", fullUrl, name, ext)
-	resp, err := self.client.Get(fullUrl)
+	suffix := name + ext
+	if self.name != nil {
+		var err error
+		if suffix, err = self.name(name, ext); err != nil {
+			return nil, err
+		}
+	}
+	fullUrl := self.url + suffix
+	fc.Debug.Printf("httpStream url %s, name=%s, ext=%s", redactUrl(fullUrl), name, ext)
+	ctx := self.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", fullUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if self.userAgent != "" {
+		req.Header.Set("User-Agent", self.userAgent)
+	}
+	resp, err := self.client.Do(req)
+	if err != nil {
+		err = &RequestError{Method: "GET", Url: redactUrl(fullUrl), Device: self.deviceId, Err: err}
+	}
 	if resp != nil {
 		return resp.Body, err
 	}
 	return nil, err
 }
 
-func (self *client) clientDo(method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
-	var req *http.Request
-	var err error
+func (self *client) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	rpc, isRpc := p.Meta().(*meta.Rpc)
+	wrapped := isRpc && method == "POST" && self.actionWrapped(ctx)
+	if wrapped {
+		payload = wrapInput(rpc, payload)
+	}
+	resp, err := self.send(ctx, method, params, p, payload)
+	if err != nil || resp == nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if counts, found := totalCountsFrom(ctx); found && method == "GET" {
+		counts.record(p, resp.Header)
+	}
+	if isRpc {
+		return self.actionOutput(rpc, resp.Body, wrapped)
+	}
+	// edits w/return=minimal or 204 No Content have no body
+	body := bufio.NewReader(resp.Body)
+	if _, err := body.Peek(1); err == io.EOF {
+		return nil, nil
+	}
+	return self.reader(body), nil
+}
+
+// reader decodes response w/device's codec
+func (self *client) reader(body io.Reader) node.Node {
+	if (self.duplicateKeys != "" || self.maxDepth > 0) && isJSONCodec(self.codec) {
+		return jsonReader{duplicateKeys: self.duplicateKeys, maxDepth: self.maxDepth}.read(body)
+	}
+	return codecOrDefault(self.codec).Reader(body)
+}
+
+// actionOutput reads all of output so it can be checked before it is
+// decoded.  No output, even when response is chunked or just whitespace, is
+// nil.
+func (self *client) actionOutput(rpc *meta.Rpc, r io.Reader, wrapped bool) (node.Node, error) {
+	body, err := ioutil.ReadAll(r)
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil, err
+	}
+	if wrapped {
+		body = unwrapOutput(body)
+	}
+	if err := self.checkOutput(rpc, body); err != nil {
+		return nil, err
+	}
+	return self.reader(bytes.NewReader(body)), nil
+}
+
+// send is the HTTP exchange for a single request to data. Unsuccessful
+// responses are returned as errors otherwise caller must close response
+// body.
+func (self *client) send(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (*http.Response, error) {
 	mod := meta.RootModule(p.Meta())
-	fullUrl := fmt.Sprint(self.address.Data, mod.Ident(), ":", p.StringNoModule())
+	target := fmt.Sprint(mod.Ident(), ":", self.targetPath(p))
+	fullUrl := self.resourceUrl(self.dataUrl(ctx), target)
+	if method == "POST" && self.operationsUrl(p) {
+		fullUrl = self.resourceUrl(self.address.Operations, target)
+	}
 	if params != "" {
 		fullUrl = fmt.Sprint(fullUrl, "?", params)
 	}
-	if req, err = http.NewRequest(method, fullUrl, payload); err != nil {
+	contentType := codecOrDefault(self.codec).MimeType()
+	if method == "PUT" && self.patchEdits && !self.readOnly {
+		method, contentType, payload = self.patchEdit(ctx, target, p, payload)
+	}
+	_, isAction := p.Meta().(*meta.Rpc)
+	return self.sendRequest(ctx, request{
+		method:      method,
+		url:         fullUrl,
+		target:      target,
+		contentType: contentType,
+		payload:     payload,
+		action:      isAction && method == "POST",
+	})
+}
+
+// request is what sendRequest needs to send a request to any url
+type request struct {
+	method string
+	url    string
+
+	// module:path of resource for what is learned about each resource such
+	// as allowed methods.  Empty for urls that are not a resource such as
+	// a datastore.
+	target string
+
+	// default is codec's
+	contentType string
+	accept      string
+
+	payload io.Reader
+	action  bool
+
+	// errors in body of an error response, if nil, client's are used
+	errMapper func(resp *http.Response, body []byte) error
+}
+
+// sendRequest is for every request to device except notification streams
+// so they are all logged, cached, limited and have errors mapped the same
+func (self *client) sendRequest(ctx context.Context, r request) (resp *http.Response, err error) {
+	var req *http.Request
+	method, fullUrl, target, payload := r.method, r.url, r.target, r.payload
+	defer func() {
+		err = self.requestErr(method, fullUrl, err)
+	}()
+	if self.readOnly && isUnsafeMethod(method) {
+		return nil, fmt.Errorf("%w. %s %s", ErrReadOnly, method, target)
+	}
+	if target != "" && !self.methodAllowed(target, method) {
+		return nil, fmt.Errorf("%w. %s %s", ErrMethodNotAllowed, method, target)
+	}
+	codec := codecOrDefault(self.codec)
+	jsonPayload := isJSONCodec(self.codec) || r.contentType == mimeYangPatchJson
+	if self.namespaceChanges && payload != nil && jsonPayload {
+		if payload, err = namespacePayload(payload); err != nil {
+			return nil, err
+		}
+	}
+	compress := self.shouldCompress(payload)
+	if compress {
+		if payload, err = gzipPayload(payload); err != nil {
+			return nil, err
+		}
+	}
+	trace, traced := traceFrom(ctx)
+	var sent func() []byte
+	if traced {
+		payload, sent = tracePayload(payload)
+	}
+	if req, err = http.NewRequestWithContext(ctx, method, fullUrl, payload); err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	fc.Info.Printf("=> %s %s", method, fullUrl)
-	resp, getErr := self.client.Do(req)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	contentType := r.contentType
+	if contentType == "" {
+		contentType = codec.MimeType()
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", codec.MimeType())
+	self.setUserAgent(req)
+	if prefer, found := preferFrom(ctx); found {
+		req.Header.Set("Prefer", "return="+prefer)
+	}
+	if r.accept != "" {
+		req.Header.Set("Accept", r.accept)
+	} else if accept, found := acceptFrom(ctx); found {
+		req.Header.Set("Accept", accept)
+	}
+	setByteRange(ctx, req)
+	token, snapshot := snapshotTokenFrom(ctx)
+	if snapshot && (method == "GET" || method == "HEAD") {
+		req.Header.Set("If-Match", token)
+	}
+	if self.conditionalEdits && target != "" {
+		self.setPrecondition(req, target)
+	}
+	// before lookup adds it's own precondition to revalidate
+	cacheable := !conditional(req)
+	var cached *http.Response
+	var stale *cacheEntry
+	if cacheable {
+		cached, stale = self.cache.lookup(req)
+	}
+	if cached != nil {
+		fc.Debug.Printf("cached %s %s", method, redactUrl(fullUrl))
+		return cached, nil
+	}
+	if self.logger == nil {
+		fc.Info.Printf("=> %s %s", method, redactUrl(fullUrl))
+	}
+	if traced {
+		trace.request(req)
+	}
+	start := time.Now()
+	var getErr error
+	if r.action {
+		resp, getErr = self.doAction(req)
+	} else if self.reads.coalesced(req) && !traced {
+		resp, getErr = self.reads.do(req, self.doCsrf)
+	} else {
+		resp, getErr = self.doCsrf(req)
+	}
+	self.logRequest(ctx, method, fullUrl, start, resp, getErr)
 	if getErr != nil || resp.Body == nil {
 		return nil, getErr
 	}
+	if traced {
+		trace.Request = sent()
+		trace.response(resp)
+	}
+	self.protocol.Store(resp.Proto)
+	if strings.Contains(resp.Header.Get("Accept-Encoding"), "gzip") {
+		atomic.StoreInt32(&self.acceptsGzip, 1)
+	}
+	if allow := resp.Header.Get("Allow"); method == "OPTIONS" && allow != "" && target != "" {
+		self.setAllowedMethods(target, parseAllow(allow))
+	}
+	if method == "OPTIONS" && target != "" {
+		self.setAcceptedPatch(target, parseAcceptPatch(resp.Header.Get("Accept-Patch")))
+	}
+	if snapshot && resp.StatusCode == http.StatusPreconditionFailed {
+		resp.Body.Close()
+		return nil, ErrSnapshotChanged
+	}
+	if resp.StatusCode == http.StatusNotModified && stale != nil {
+		resp = self.cache.revalidated(stale, resp)
+	}
+	if err := self.mappedResponseErr(resp, r.errMapper); err != nil {
+		return nil, err
+	}
+	if isUnsafeMethod(method) {
+		self.cache.clear()
+	} else if cacheable {
+		if resp, err = self.cache.store(resp); err != nil {
+			return nil, err
+		}
+	}
+	if self.conditionalEdits && target != "" {
+		self.updateValidator(method, target, resp)
+	}
+	return resp, nil
+}
+
+// responseErr is nil for successful responses otherwise response body is
+// read and closed and status is mapped to an error
+func (self *client) responseErr(resp *http.Response) error {
+	return self.mappedResponseErr(resp, nil)
+}
+
+// mappedResponseErr is responseErr that tries mapper before client's error
+// mapper
+func (self *client) mappedResponseErr(resp *http.Response, mapper func(*http.Response, []byte) error) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
 	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		msg, _ := ioutil.ReadAll(resp.Body)
-		return nil, fmt.Errorf("(%d) %s", resp.StatusCode, string(msg))
+	msg, _ := ioutil.ReadAll(resp.Body)
+	for _, m := range []func(*http.Response, []byte) error{mapper, self.errorMapper} {
+		if m == nil {
+			continue
+		}
+		if err := m(resp, msg); err != nil {
+			return throttledErr(resp, err)
+		}
+	}
+	err := statusErr(resp.StatusCode, string(msg))
+	if staleSchemaTag(msg) {
+		return staleSchemaErr{err}
+	}
+	return throttledErr(resp, err)
+}
+
+// urlPath is like p.StringNoModule() but each key value is percent-encoded
+// on it's own so reserved chars in keys cannot change structure of url.
+// Server decodes keys w/url.QueryUnescape so '+' is encoded too.
+func urlPath(p *node.Path) string {
+	return keyedUrlPath(p, DefaultKeyFormat)
+}
+
+// KeyFormat renders value of a list key as device expects it in urls such
+// as "1" for boolean true or an identityref w/it's module prefix.  Result is
+// percent-encoded after.  See Client.KeyFormat.
+type KeyFormat func(key meta.Leafable, v val.Value) string
+
+// DefaultKeyFormat is value as node layer renders it
+func DefaultKeyFormat(key meta.Leafable, v val.Value) string {
+	return v.String()
+}
+
+// targetPath is urlPath w/device's key format
+func (self *client) targetPath(p *node.Path) string {
+	if self.keyFormat == nil {
+		return urlPath(p)
+	}
+	return keyedUrlPath(p, self.keyFormat)
+}
+
+func keyedUrlPath(p *node.Path, format KeyFormat) string {
+	segs := p.Segments()
+	strs := make([]string, 0, len(segs))
+	for _, seg := range segs[1:] {
+		s := seg.Meta().Ident()
+		if key := seg.Key(); len(key) > 0 {
+			keyStrs := make([]string, len(key))
+			for i, k := range key {
+				// leaf-list values are their own key
+				keyMeta, _ := seg.Meta().(meta.Leafable)
+				if list, isList := seg.Meta().(*meta.List); isList {
+					keyMeta = list.KeyMeta()[i]
+				}
+				keyStrs[i] = strings.Replace(url.PathEscape(format(keyMeta, k)), "+", "%2B", -1)
+			}
+			s = s + "=" + strings.Join(keyStrs, ",")
+		}
+		strs = append(strs, s)
+	}
+	return strings.Join(strs, "/")
+}
+
+// parsePath resolves path in module:path form against device's schema
+// without contacting device
+func (self *client) parsePath(path string) (*node.Path, error) {
+	path = strings.TrimSuffix(path, "/")
+	colon := strings.IndexRune(path, ':')
+	if colon <= 0 {
+		return nil, fmt.Errorf("%w. expected module:path, got %s", fc.BadRequestError, path)
+	}
+	m, err := self.module(self.moduleIdent(path[:colon]))
+	if err != nil {
+		return nil, err
+	}
+	slice, err := node.ParsePath(path[colon+1:], m)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Tail, nil
+}
+
+// ActionRaw is for when you need to see the response headers from an
+// action such as rate limits or async operation ids. Path is in module:path
+// form. Response body has been read already to decode output.
+func (self *client) ActionRaw(ctx context.Context, path string, input node.Node) (*http.Response, node.Node, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	rpc, isRpc := p.Meta().(*meta.Rpc)
+	if !isRpc {
+		return nil, nil, fmt.Errorf("%w. %s is not an action", fc.BadRequestError, path)
+	}
+	codec := codecOrDefault(self.codec)
+	var payload bytes.Buffer
+	if input != nil && rpc.Input() != nil {
+		in := node.Selection{
+			Node:        input,
+			Path:        node.NewContainerPath(p, rpc.Input()),
+			Constraints: &node.Constraints{},
+			Context:     ctx,
+		}
+		if err := codec.Write(&payload, in); err != nil {
+			return nil, nil, err
+		}
+	}
+	var in io.Reader = &payload
+	wrapped := self.actionWrapped(ctx)
+	if wrapped {
+		in = wrapInput(rpc, in)
+	}
+	resp, err := self.send(ctx, "POST", "", p, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if wrapped {
+		body = unwrapOutput(body)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := self.checkOutput(rpc, body); err != nil {
+			return resp, nil, err
+		}
+	}
+	var output node.Node
+	if len(bytes.TrimSpace(body)) > 0 && rpc.Output() != nil {
+		output = self.reader(bytes.NewReader(body))
+	}
+	return resp, output, nil
+}
+
+// GetRaw is resource at path, in module:path form, exactly as device sent it
+// for proxying or caching responses unchanged.
+func (self *client) GetRaw(ctx context.Context, path string) ([]byte, error) {
+	body, err := self.GetRawStream(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+// GetRawStream is like GetRaw for large resources.  Caller must close
+// response body.
+func (self *client) GetRawStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := self.send(ctx, "GET", "", p, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+type datastoreKey struct{}
+
+// WithDatastore routes requests made with this context to a NMDA datastore
+// such as ietf-datastores:intended instead of device's default.
+//
+//	b.RootWithContext(restconf.WithDatastore(ctx, "ietf-datastores:operational"))
+func WithDatastore(ctx context.Context, datastore string) context.Context {
+	return context.WithValue(ctx, datastoreKey{}, datastore)
+}
+
+func datastoreFrom(ctx context.Context) (string, bool) {
+	ds, found := ctx.Value(datastoreKey{}).(string)
+	return ds, found
+}
+
+// resourceUrl is url to target in module:path form w/o a trailing slash unless
+// device wants one
+func (self *client) resourceUrl(base string, target string) string {
+	target = strings.TrimSuffix(target, "/")
+	if self.trailingSlash {
+		target += "/"
+	}
+	return base + target
+}
+
+// moduleIdent is name of loaded module that matches name ignoring case so
+// urls always have module's exact identifier.  Names that match no loaded
+// module are returned as is.
+func (self *client) moduleIdent(name string) string {
+	self.modulesLock.RLock()
+	defer self.modulesLock.RUnlock()
+	if _, found := self.modules[name]; found {
+		return name
+	}
+	for ident := range self.modules {
+		if strings.EqualFold(ident, name) {
+			return ident
+		}
+	}
+	return name
+}
+
+// dataUrl is root of data resources, either /data or /ds/<datastore> per
+// RFC 8527
+func (self *client) dataUrl(ctx context.Context) string {
+	ds := self.datastore
+	if override, found := datastoreFrom(ctx); found {
+		ds = override
+	}
+	if ds == "" {
+		return self.address.Data
+	}
+	return self.address.Datastore(ds)
+}
+
+const (
+	// ReturnMinimal asks server to not send resource back after an edit
+	ReturnMinimal = "minimal"
+
+	// ReturnRepresentation asks server to send resource back after an edit
+	// as server has normalized it
+	ReturnRepresentation = "representation"
+)
+
+type preferKey struct{}
+
+type editResultKey struct{}
+
+// EditResult is given server's representation of what was edited
+type EditResult func(sel node.Selection) error
+
+// WithEditResult asks server to send back resource after edits made with
+// this context and hands it to onResult.  If server ignores request, onResult
+// is not called.
+//
+//	ctx := restconf.WithEditResult(ctx, func(sel node.Selection) error {...})
+//	b.RootWithContext(ctx).Find("x").UpsertFrom(n)
+func WithEditResult(ctx context.Context, onResult EditResult) context.Context {
+	return context.WithValue(ctx, editResultKey{}, onResult)
+}
+
+func editResultFrom(ctx context.Context) (EditResult, bool) {
+	onResult, found := ctx.Value(editResultKey{}).(EditResult)
+	return onResult, found
+}
+
+func withPrefer(ctx context.Context, prefer string) context.Context {
+	return context.WithValue(ctx, preferKey{}, prefer)
+}
+
+func preferFrom(ctx context.Context) (string, bool) {
+	prefer, found := ctx.Value(preferKey{}).(string)
+	return prefer, found
+}
+
+func (self *client) setUserAgent(req *http.Request) {
+	if self.userAgent != "" {
+		req.Header.Set("User-Agent", self.userAgent)
+	}
+}
+
+// shouldCompress only when server has advertised it accepts gzip requests,
+// thru Accept-Encoding response header, and payload is large enough to be
+// worth it.  Payloads of unknown size are not compressed.
+func (self *client) shouldCompress(payload io.Reader) bool {
+	if self.compressThreshold <= 0 || atomic.LoadInt32(&self.acceptsGzip) == 0 {
+		return false
+	}
+	sized, hasLen := payload.(interface{ Len() int })
+	return hasLen && sized.Len() >= self.compressThreshold
+}
+
+func gzipPayload(payload io.Reader) (io.Reader, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(zw, payload); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// ErrConflict is when a conditional edit was rejected because resource was
+// changed since it was read
+var ErrConflict = fmt.Errorf("%w. resource changed since it was read", fc.ConflictError)
+
+// validator is what server gave us to detect changes to a resource, ETag is
+// preferred when server sends both
+type validator struct {
+	etag         string
+	lastModified string
+}
+
+func (self *client) setPrecondition(req *http.Request, target string) {
+	switch req.Method {
+	case "PUT", "PATCH", "DELETE":
+	default:
+		return
+	}
+	self.validatorsLock.Lock()
+	v := self.validators[target]
+	self.validatorsLock.Unlock()
+	if v.etag != "" {
+		req.Header.Set("If-Match", v.etag)
+	} else if v.lastModified != "" {
+		req.Header.Set("If-Unmodified-Since", v.lastModified)
+	}
+}
+
+func (self *client) updateValidator(method string, target string, resp *http.Response) {
+	self.validatorsLock.Lock()
+	defer self.validatorsLock.Unlock()
+	switch method {
+	case "GET", "HEAD":
+		v := validator{
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}
+		if v.etag == "" && v.lastModified == "" {
+			delete(self.validators, target)
+			return
+		}
+		if self.validators == nil {
+			self.validators = make(map[string]validator)
+		}
+		self.validators[target] = v
+	case "PUT", "PATCH", "DELETE", "POST":
+		// resource changed, need to read it again to know new version
+		delete(self.validators, target)
+	}
+}
+
+// ErrMethodNotAllowed is when server has told us, thru Allow header, it does
+// not support a method on a resource
+var ErrMethodNotAllowed = errors.New("method not allowed on this resource")
+
+// ErrReadOnly is when client was made w/ReadOnly and asked to change device
+var ErrReadOnly = errors.New("client is read-only")
+
+// AllowedMethods is what server reported it would allow on resource in
+// module:path form the last time it was navigated to.  Nil means unknown.
+func (self *client) AllowedMethods(path string) []string {
+	self.allowedLock.RLock()
+	defer self.allowedLock.RUnlock()
+	return self.allowed[path]
+}
+
+func (self *client) setAllowedMethods(path string, methods []string) {
+	self.allowedLock.Lock()
+	defer self.allowedLock.Unlock()
+	if self.allowed == nil {
+		self.allowed = make(map[string][]string)
+	}
+	self.allowed[path] = methods
+}
+
+func (self *client) methodAllowed(path string, method string) bool {
+	allowed := self.AllowedMethods(path)
+	if allowed == nil {
+		// unknown, let server decide
+		return true
+	}
+	for _, candidate := range allowed {
+		if candidate == method {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAllow(header string) []string {
+	var methods []string
+	for _, m := range strings.Split(header, ",") {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// statusErr is inverse of fc.HttpStatusCode so callers can use errors.Is to
+// learn what happened on server
+func statusErr(status int, msg string) error {
+	if err := statusSentinel(status); err != nil {
+		return fmt.Errorf("%w. (%d) %s", err, status, msg)
+	}
+	return fmt.Errorf("(%d) %s", status, msg)
+}
+
+func statusSentinel(status int) error {
+	switch status {
+	case http.StatusNotFound:
+		return fc.NotFoundError
+	case http.StatusBadRequest:
+		return fc.BadRequestError
+	case http.StatusConflict:
+		return fc.ConflictError
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fc.UnauthorizedError
+	case http.StatusNotImplemented:
+		return fc.NotImplementedError
+	case http.StatusMethodNotAllowed:
+		return ErrMethodNotAllowed
+	case http.StatusPreconditionFailed:
+		return ErrConflict
 	}
-	return nodeutil.ReadJSONIO(resp.Body), nil
+	return nil
 }
@@ -0,0 +1,171 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// Diff reads config at path, in module:path form, and compares it to
+// desired without changing anything on device.  Path should be a container
+// or a list entry. See Apply to send difference to device.
+func (self *client) Diff(ctx context.Context, path string, desired node.Selection) (Diff, error) {
+	d := Diff{Path: path}
+	p, err := self.parsePath(path)
+	if err != nil {
+		return d, err
+	}
+	params := editReadParams(self.editWithDefaults, self.legacyConfig, true)
+	existing, err := self.support.clientDo(ctx, "GET", params, p, nil)
+	if err != nil && !errors.Is(err, fc.NotFoundError) {
+		return d, err
+	}
+	from := make(map[string]interface{})
+	if existing != nil {
+		if err := desired.Split(existing).InsertInto(nodeutil.ReflectChild(from)).LastErr; err != nil {
+			return d, err
+		}
+	}
+	to := make(map[string]interface{})
+	if err := desired.InsertInto(nodeutil.ReflectChild(to)).LastErr; err != nil {
+		return d, err
+	}
+	diffData(p.Meta().(meta.HasDataDefinitions), "", from, to, &d)
+	return d, nil
+}
+
+// Apply sends difference made by Diff to device.  Removed leaves are
+// deleted, or entire list entries when their keys were removed, then added
+// and changed leaves are sent in a single merge.
+func (self *client) Apply(ctx context.Context, d Diff) error {
+	if d.Path == "" {
+		return fmt.Errorf("%w. diff has no path, use Diff to make one", fc.BadRequestError)
+	}
+	target, err := self.parsePath(d.Path)
+	if err != nil {
+		return err
+	}
+	deletes, err := self.diffDeletes(d)
+	if err != nil {
+		return err
+	}
+	for _, p := range deletes {
+		if _, err := self.support.clientDo(ctx, "DELETE", "", p, nil); err != nil {
+			return err
+		}
+	}
+	if len(d.Added) == 0 && len(d.Changed) == 0 {
+		return nil
+	}
+	changes := make(map[string]interface{})
+	for _, leaves := range [][]LeafDiff{d.Added, d.Changed} {
+		for _, leaf := range leaves {
+			p, err := self.parsePath(d.Path + "/" + leaf.Path)
+			if err != nil {
+				return err
+			}
+			setLeaf(changes, p, leaf.New)
+		}
+	}
+	m := meta.RootModule(target.Meta())
+	b := node.NewBrowser(m, nodeutil.ReflectChild(changes))
+	sel := b.Root().Find(d.Path[len(m.Ident())+1:])
+	if sel.LastErr != nil {
+		return sel.LastErr
+	}
+	var payload bytes.Buffer
+	if err := codecOrDefault(self.codec).Write(&payload, sel); err != nil {
+		return err
+	}
+	_, err = self.support.clientDo(ctx, "PATCH", "", target, &payload)
+	return err
+}
+
+// diffDeletes are paths to delete.  Removing a key means entire entry was
+// removed so entry is deleted instead of each of it's leaves.
+func (self *client) diffDeletes(d Diff) ([]*node.Path, error) {
+	var leaves []*node.Path
+	var entries []*node.Path
+	for _, leaf := range d.Removed {
+		p, err := self.parsePath(d.Path + "/" + leaf.Path)
+		if err != nil {
+			return nil, err
+		}
+		if isKeyLeaf(p) && !containsPath(entries, p.Parent()) {
+			entries = append(entries, p.Parent())
+		} else {
+			leaves = append(leaves, p)
+		}
+	}
+	deletes := entries
+	for _, p := range leaves {
+		inEntry := false
+		for _, entry := range entries {
+			if strings.HasPrefix(p.String(), entry.String()+"/") {
+				inEntry = true
+				break
+			}
+		}
+		if !inEntry {
+			deletes = append(deletes, p)
+		}
+	}
+	return deletes, nil
+}
+
+func isKeyLeaf(p *node.Path) bool {
+	l, isList := p.Parent().Meta().(*meta.List)
+	if !isList {
+		return false
+	}
+	for _, k := range l.KeyMeta() {
+		if k.Ident() == p.Meta().Ident() {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPath(paths []*node.Path, p *node.Path) bool {
+	for _, candidate := range paths {
+		if candidate.Equal(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// setLeaf stores value in data as nodeutil.ReflectChild would, including
+// key leaves of each list entry along the way so entries can be identified.
+func setLeaf(data map[string]interface{}, p *node.Path, v interface{}) {
+	segs := p.Segments()
+	for _, seg := range segs[1 : len(segs)-1] {
+		ident := seg.Meta().Ident()
+		child, _ := data[ident].(map[string]interface{})
+		if child == nil {
+			child = make(map[string]interface{})
+			data[ident] = child
+		}
+		data = child
+		if l, isList := seg.Meta().(*meta.List); isList {
+			key := seg.Key()
+			entry, _ := data[key[0].String()].(map[string]interface{})
+			if entry == nil {
+				entry = make(map[string]interface{})
+				data[key[0].String()] = entry
+			}
+			for i, k := range l.KeyMeta() {
+				entry[k.Ident()] = key[i].Value()
+			}
+			data = entry
+		}
+	}
+	data[p.Meta().Ident()] = v
+}
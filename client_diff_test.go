@@ -0,0 +1,78 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientDiffApply(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container sys {
+			container dns {
+				leaf server { type string; }
+				leaf timeout { type int32; }
+			}
+			list iface {
+				key name;
+				leaf name { type string; }
+				leaf mtu { type int32; }
+				container ip {
+					leaf addr { type string; }
+				}
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{"dns":{"server":"1.1.1.1","timeout":5},"iface":[
+				{"name":"eth0","mtu":1500},
+				{"name":"eth1","mtu":1500,"ip":{"addr":"a"}}]}`)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		sent = append(sent, fmt.Sprint(r.Method, " ", r.URL.Path, " ", string(body)))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	desired := node.NewBrowser(m, nodeutil.ReadJSON(`{"sys":{"dns":{"server":"8.8.8.8"},"iface":[
+		{"name":"eth0","mtu":9000,"ip":{"addr":"b"}},
+		{"name":"eth2","mtu":1500}]}}`)).Root().Find("sys")
+	ctx := context.Background()
+	d, err := c.Diff(ctx, "m:sys", desired)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "m:sys", d.Path)
+	fc.AssertEqual(t, "iface=eth0/ip/addr,iface=eth2/name,iface=eth2/mtu", diffPaths(d.Added))
+	fc.AssertEqual(t, "dns/server,iface=eth0/mtu", diffPaths(d.Changed))
+	fc.AssertEqual(t, "dns/timeout,iface=eth1/name,iface=eth1/mtu,iface=eth1/ip/addr", diffPaths(d.Removed))
+	fc.AssertEqual(t, 0, len(sent))
+
+	fc.AssertEqual(t, nil, c.Apply(ctx, d))
+	fc.AssertEqual(t, 3, len(sent))
+	fc.AssertEqual(t, "DELETE /restconf/data/m:sys/iface=eth1 ", sent[0])
+	fc.AssertEqual(t, "DELETE /restconf/data/m:sys/dns/timeout ", sent[1])
+	fc.AssertEqual(t, `PATCH /restconf/data/m:sys {"dns":{"server":"8.8.8.8"},"iface":[{"name":"eth0","mtu":9000,"ip":{"addr":"b"}},{"name":"eth2","mtu":1500}]}`, sent[2])
+
+	// nothing different, nothing sent
+	sent = nil
+	same := node.NewBrowser(m, nodeutil.ReadJSON(`{"sys":{"dns":{"server":"1.1.1.1","timeout":5},"iface":[
+		{"name":"eth0","mtu":1500},
+		{"name":"eth1","mtu":1500,"ip":{"addr":"a"}}]}}`)).Root().Find("sys")
+	d, err = c.Diff(ctx, "m:sys", same)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, d.Empty())
+	fc.AssertEqual(t, nil, c.Apply(ctx, d))
+	fc.AssertEqual(t, 0, len(sent))
+}
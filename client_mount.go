@@ -0,0 +1,178 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/parser"
+)
+
+const (
+	mountInline = "inline"
+	mountShared = "shared-schema"
+)
+
+// MountBrowser navigates data under a YANG schema mount point (RFC 8528).  Path
+// is in module:path form to the container or list entry w/the mount-point
+// extension and module is one of the modules mounted there.
+//
+// Mounted modules are not in device's top-level yang-library so they are
+// loaded from the yang-library under the mount point the first time it is
+// traversed.  Shared-schema mount points are loaded once per label, inline
+// mount points once per instance.
+func (self *client) MountBrowser(path string, module string) (*node.Browser, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	label := mountPointLabel(p.Meta())
+	if label == "" {
+		return nil, fmt.Errorf("%w. %s is not a mount point", fc.BadRequestError, path)
+	}
+	ctx := context.Background()
+	mnt := self.mountClient(self.dataUrl(ctx) + meta.RootModule(p.Meta()).Ident() + ":" + self.targetPath(p) + "/")
+	mods, err := self.mountedModules(mnt, p, label)
+	if err != nil {
+		return nil, err
+	}
+	// copy because mounted modules are shared w/other mount clients
+	for name, m := range mods {
+		mnt.modules[name] = m
+	}
+	m := mods[module]
+	if m == nil {
+		return nil, fmt.Errorf("%w. %s not mounted at %s", fc.NotFoundError, module, path)
+	}
+	return node.NewBrowser(m, mnt.newClientNode().node()), nil
+}
+
+func mountPointLabel(def meta.Definition) string {
+	ext, valid := def.(interface{ Extensions() []*meta.Extension })
+	if !valid {
+		return ""
+	}
+	for _, x := range ext.Extensions() {
+		if x.Ident() == "mount-point" && len(x.Arguments()) > 0 {
+			return x.Arguments()[0]
+		}
+	}
+	return ""
+}
+
+// mountClient talks to data under a mount point as if it were the root of
+// a device
+func (self *client) mountClient(dataUrl string) *client {
+	address := self.address
+	address.Data = dataUrl
+	c := &client{
+		clientOptions: self.clientOptions,
+		address:       address,
+		modules:       make(map[string]*meta.Module),
+		schemaLoaded:  time.Now(),
+	}
+	// mount's data url is already in datastore
+	c.datastore = ""
+	c.support = c
+	return c
+}
+
+func (self *client) mountedModules(mnt *client, p *node.Path, label string) (map[string]*meta.Module, error) {
+	mountId := meta.RootModule(p.Meta()).Ident() + ":" + label
+	ref, err := self.mountSchemaRef(mountId)
+	if err != nil {
+		return nil, err
+	}
+	cacheKey := mountId
+	if ref != mountShared {
+		cacheKey = mnt.address.Data
+	}
+	self.mountLock.Lock()
+	defer self.mountLock.Unlock()
+	if mods, found := self.mounts[cacheKey]; found {
+		return mods, nil
+	}
+	ylib, err := parser.LoadModule(self.yangPath, "ietf-yang-library")
+	if err != nil {
+		return nil, err
+	}
+	b := node.NewBrowser(ylib, mnt.newClientNode().node())
+	schema := httpStream{
+		ypath:     self.yangPath,
+		client:    self.client,
+		url:       self.address.Schema,
+		userAgent: self.userAgent,
+		name:      self.schemaName,
+		deviceId:  self.address.DeviceId,
+
+		remoteDeviated: self.remoteDeviated,
+	}
+	mods, _, err := device.LoadModuleHnds(b, schema)
+	if err != nil {
+		return nil, fmt.Errorf("could not load modules mounted at %s. %w", mountId, err)
+	}
+	if self.mounts == nil {
+		self.mounts = make(map[string]map[string]*meta.Module)
+	}
+	self.mounts[cacheKey] = mods
+	return mods, nil
+}
+
+// mountSchemaRef is inline or shared-schema according to device's
+// ietf-yang-schema-mount data. Devices that do not report mount points are
+// treated as inline
+func (self *client) mountSchemaRef(mountId string) (string, error) {
+	self.mountLock.Lock()
+	defer self.mountLock.Unlock()
+	if self.mountRefs == nil {
+		refs, err := self.loadSchemaRefs()
+		if err != nil {
+			return "", err
+		}
+		self.mountRefs = refs
+	}
+	if ref, found := self.mountRefs[mountId]; found {
+		return ref, nil
+	}
+	return mountInline, nil
+}
+
+func (self *client) loadSchemaRefs() (map[string]string, error) {
+	refs := make(map[string]string)
+	b, err := self.Browser("ietf-yang-schema-mount")
+	if err != nil {
+		// cannot find schema locally or on device
+		fc.Debug.Printf("no schema mount information. %s", err)
+		return refs, nil
+	}
+	sel := b.Root().Find("schema-mounts/mount-point")
+	if errors.Is(sel.LastErr, fc.NotFoundError) || sel.IsNil() {
+		return refs, nil
+	} else if sel.LastErr != nil {
+		return nil, sel.LastErr
+	}
+	for item := sel.First(); !item.Selection.IsNil(); item = item.Next() {
+		if item.Selection.LastErr != nil {
+			return nil, item.Selection.LastErr
+		}
+		module, err := item.Selection.Get("module")
+		if err != nil {
+			return nil, err
+		}
+		label, err := item.Selection.Get("label")
+		if err != nil {
+			return nil, err
+		}
+		ref := mountInline
+		if shared := item.Selection.Find("shared-schema"); !shared.IsNil() {
+			ref = mountShared
+		}
+		refs[fmt.Sprint(module, ":", label)] = ref
+	}
+	return refs, nil
+}
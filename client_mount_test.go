@@ -0,0 +1,91 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestClientMount(t *testing.T) {
+	ypath := source.Dir("./yang")
+	m, err := parser.LoadModuleFromString(ypath, `module m { namespace "m"; prefix "m"; revision 0;
+		import ietf-yang-schema-mount { prefix yangmnt; }
+		list vrf {
+			key name;
+			leaf name { type string; }
+			container root {
+				yangmnt:mount-point "vrf-root";
+			}
+		}
+		container x {}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mountedYang := `module mnt { namespace "mnt"; prefix "mnt"; revision 0;
+		container car { leaf speed { type int32; } }
+	}`
+	for _, ref := range []string{"inline", "shared-schema"} {
+		var ylibReads []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "OPTIONS" {
+				return
+			}
+			switch {
+			case r.URL.Path == "/restconf/schema/mnt.yang":
+				fmt.Fprint(w, mountedYang)
+			case strings.HasSuffix(r.URL.Path, "ietf-yang-schema-mount:schema-mounts/mount-point"):
+				fmt.Fprintf(w, `{"mount-point":[{"module":"m","label":"vrf-root","%s":{}}]}`, ref)
+			case strings.HasSuffix(r.URL.Path, "ietf-yang-library:modules-state/module"):
+				ylibReads = append(ylibReads, r.URL.Path)
+				fmt.Fprint(w, `{"module":[{"name":"mnt","revision":"0","namespace":"mnt"}]}`)
+			case r.URL.Path == "/restconf/data/m:vrf=red/root/mnt:car":
+				fmt.Fprint(w, `{"speed":10}`)
+			default:
+				http.Error(w, r.URL.Path, 404)
+			}
+		}))
+		c := newTestClient(srv, m)
+		c.yangPath = ypath
+		c.schemaPath = ypath
+		b, err := c.MountBrowser("m:vrf=red/root", "mnt")
+		fc.AssertEqual(t, nil, err)
+		actual, err := nodeutil.WriteJSON(b.Root().Find("car"))
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, `{"speed":10}`, actual)
+
+		_, err = c.MountBrowser("m:vrf=blue/root", "mnt")
+		fc.AssertEqual(t, nil, err)
+		if ref == "inline" {
+			fc.AssertEqual(t, 2, len(ylibReads))
+		} else {
+			fc.AssertEqual(t, 1, len(ylibReads))
+		}
+		fc.AssertEqual(t, "/restconf/data/m:vrf=red/root/ietf-yang-library:modules-state/module", ylibReads[0])
+
+		_, err = c.MountBrowser("m:x", "mnt")
+		fc.AssertEqual(t, true, err != nil)
+		srv.Close()
+	}
+}
+
+func TestClientMountOptions(t *testing.T) {
+	c := &client{address: Address{Data: "http://x/restconf/data/"}}
+	c.streamBuffer = 5
+	c.streamDrop = true
+	c.readOnly = true
+	c.datastore = "ietf-datastores:running"
+	mnt := c.mountClient("http://x/restconf/ds/ietf-datastores:running/m:x/")
+	fc.AssertEqual(t, 5, mnt.streamBuffer)
+	fc.AssertEqual(t, true, mnt.streamDrop)
+	fc.AssertEqual(t, true, mnt.readOnly)
+	fc.AssertEqual(t, "", mnt.datastore)
+	fc.AssertEqual(t, "http://x/restconf/ds/ietf-datastores:running/m:x/", mnt.address.Data)
+}
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 
 	"io"
+	"net/url"
+	"strconv"
 
 	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
 	"github.com/freeconf/yang/val"
@@ -22,13 +26,61 @@ type clientNode struct {
 	method  string
 	changes node.Node
 	device  string
+	codec   Codec
+
+	// write payload into request as it's sent instead of buffering
+	streamEdits bool
+
+	// optional hook to inspect and possibly veto edits
+	onBeforeSend BeforeSend
+	existing     node.Node
+	changesData  map[string]interface{}
+
+	// with-defaults mode when reading existing config before edit, empty
+	// means trim
+	editWithDefaults string
+
+	// Prefer: return=<editReturn> on edits, empty sends nothing
+	editReturn string
+
+	// config=true|false instead of content=config|nonconfig
+	legacyConfig bool
+
+	// method to check resource exists when navigating, empty means OPTIONS
+	probe string
+
+	// NMDA datastore of every request unless context has one, see
+	// DatastoreBrowser
+	datastore string
+
+	// loads module of path from device again, nil unless
+	// Client.RetryStaleSchema
+	refreshSchema func(p *node.Path) (*node.Path, error)
+
+	// depth param of reads, zero sends none
+	maxDepth int
+
+	// check edits against schema before sending, PUT merges if patchEdits
+	validateEdits bool
+	patchEdits    bool
 }
 
+// Methods to check a resource exists when navigating to it.  See
+// Client.NavigationProbe
+const (
+	ProbeOptions = "OPTIONS"
+	ProbeHead    = "HEAD"
+)
+
+// BeforeSend is given the difference between what is on server and what is
+// about to be sent.  Returning an error aborts the edit.
+type BeforeSend func(method string, path *node.Path, diff Diff) error
+
 // clientSupport is interface between Device and driver.  Factored out as part of
 // testing but also because a lot of what driver does is potentially universal to proxying
 // for other protocols and might allow reusablity when other protocols are added
 type clientSupport interface {
-	clientDo(method string, params string, p *node.Path, payload io.Reader) (node.Node, error)
+	clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error)
 	clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error)
 }
 
@@ -45,11 +97,15 @@ func (self *clientNode) node() node.Node {
 		} else {
 			self.method = "PUT"
 		}
-		return self.startEditMode(r.Selection.Path)
+		if self.validateEdits {
+			// otherwise data w/a false when is dropped w/o saying
+			r.Selection.Constraints.AddConstraint("~when", 100, 0, editWhen{})
+		}
+		return self.startEditMode(r.Selection.Context, r.Selection.Path)
 	}
 	n.OnChild = func(r node.ChildRequest) (node.Node, error) {
 		if r.IsNavigation() {
-			if valid, err := self.validNavigation(r.Target); !valid || err != nil {
+			if valid, err := self.validNavigation(r.Selection.Context, r.Target); !valid || err != nil {
 				return nil, err
 			}
 			return n, nil
@@ -58,19 +114,19 @@ func (self *clientNode) node() node.Node {
 			return self.edit.Child(r)
 		}
 		if self.read == nil {
-			if err := self.startReadMode(r.Selection.Path); err != nil {
+			if err := self.startReadMode(r.Selection.Context, r.Selection.Path); err != nil {
 				return nil, err
 			}
 		}
 		return self.read.Child(r)
 	}
 	n.OnDelete = func(r node.NodeRequest) error {
-		_, err := self.request("DELETE", r.Selection.Path, noSelection)
+		_, err := self.request(r.Selection.Context, "DELETE", r.Selection.Path, noSelection)
 		return err
 	}
 	n.OnNext = func(r node.ListRequest) (node.Node, []val.Value, error) {
 		if r.IsNavigation() {
-			if valid, err := self.validNavigation(r.Target); !valid || err != nil {
+			if valid, err := self.validNavigation(r.Selection.Context, r.Target); !valid || err != nil {
 				return nil, nil, err
 			}
 			return n, r.Key, nil
@@ -79,7 +135,10 @@ func (self *clientNode) node() node.Node {
 			return self.edit.Next(r)
 		}
 		if self.read == nil {
-			if err := self.startReadMode(r.Selection.Path); err != nil {
+			if len(r.Key) > 0 {
+				return self.readListEntry(r)
+			}
+			if err := self.startListReadMode(r); err != nil {
 				return nil, nil, err
 			}
 		}
@@ -92,61 +151,173 @@ func (self *clientNode) node() node.Node {
 			return self.edit.Field(r, hnd)
 		}
 		if self.read == nil {
-			if err := self.startReadMode(r.Selection.Path); err != nil {
+			if err := self.startReadMode(r.Selection.Context, r.Selection.Path); err != nil {
 				return err
 			}
 		}
 		return self.read.Field(r, hnd)
 	}
 	n.OnNotify = func(r node.NotifyRequest) (node.NotifyCloser, error) {
-		var params string // TODO: support params
-		ctx, cancel := context.WithCancel(context.Background())
-		events, err := self.support.clientStream(params, r.Selection.Path, ctx)
+		sub, err := subscribe(context.Background(), self.support, r.Selection.Path)
 		if err != nil {
-			cancel()
 			return nil, err
 		}
 		go func() {
-			for n := range events {
+			for n := range sub.Events() {
 				r.Send(n)
 			}
 		}()
-		closer := func() error {
-			cancel()
-			return nil
-		}
-		return closer, nil
+		return sub.Close, nil
 	}
 	n.OnAction = func(r node.ActionRequest) (node.Node, error) {
-		return self.request("POST", r.Selection.Path, r.Input)
+		return self.request(r.Selection.Context, "POST", r.Selection.Path, r.Input)
 	}
 	n.OnEndEdit = func(r node.NodeRequest) error {
 		// send request
 		if !r.EditRoot {
 			return nil
 		}
-		_, err := self.request(self.method, r.Selection.Path, r.Selection.Split(self.changes))
-		return err
+		if err := self.requireKeys(r.Selection); err != nil {
+			return err
+		}
+		if self.validateEdits {
+			if err := self.validate(r.Selection); err != nil {
+				return err
+			}
+		}
+		if self.onBeforeSend != nil {
+			diff, err := self.diff(r.Selection)
+			if err != nil {
+				return err
+			}
+			if err := self.onBeforeSend(self.method, r.Selection.Path, diff); err != nil {
+				return err
+			}
+		}
+		ctx := r.Selection.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		prefer := self.editReturn
+		onResult, wantsResult := editResultFrom(ctx)
+		if wantsResult {
+			prefer = ReturnRepresentation
+		}
+		if prefer != "" {
+			ctx = withPrefer(ctx, prefer)
+		}
+		result, err := self.request(ctx, self.method, r.Selection.Path, r.Selection.Split(self.changes))
+		if errors.Is(err, ErrStaleSchema) && self.refreshSchema != nil {
+			result, err = self.retryStale(ctx, r.Selection.Path, err)
+		}
+		if err != nil || !wantsResult || result == nil {
+			return err
+		}
+		return onResult(r.Selection.Split(result))
 	}
 	return n
 }
 
-func (self *clientNode) startReadMode(path *node.Path) (err error) {
-	self.read, err = self.get(path, self.params)
+// retryStale sends edit once more after loading module from device
+func (self *clientNode) retryStale(ctx context.Context, p *node.Path, staleErr error) (node.Node, error) {
+	fresh, err := self.refreshSchema(p)
+	if err != nil {
+		fc.Debug.Printf("could not refresh schema of %s. %s", p, err)
+		return nil, staleErr
+	}
+	in := node.Selection{
+		Node:        self.changes,
+		Path:        fresh,
+		Constraints: &node.Constraints{},
+		Context:     ctx,
+	}
+	return self.request(ctx, self.method, fresh, in)
+}
+
+func (self *clientNode) startReadMode(ctx context.Context, path *node.Path) (err error) {
+	self.read, err = self.get(ctx, path, self.readParams(ctx))
 	return
 }
 
-func (self *clientNode) startEditMode(path *node.Path) error {
-	// add depth = 1 so we can pull first level containers and
-	// know what container would be conflicts.  we'll have to pull field
-	// values too because there's no url param to exclude those yet.
-	params := "depth=1&content=config&with-defaults=trim"
-	existing, err := self.get(path, params)
+// startListReadMode is like startReadMode but honors sort hint and asks for
+// total count if caller wants it
+func (self *clientNode) startListReadMode(r node.ListRequest) (err error) {
+	params := self.readParams(r.Selection.Context)
+	if hint, found := sortHintFrom(r.Selection.Constraints); found {
+		if params != "" {
+			params += "&"
+		}
+		params += hint.param()
+	}
+	if counts, found := totalCountsFrom(r.Selection.Context); found && counts.param != "" {
+		if params != "" {
+			params += "&"
+		}
+		params += counts.param
+	}
+	self.read, err = self.get(r.Selection.Context, r.Selection.Path, params)
+	return
+}
+
+// readListEntry addresses entry directly as list=key instead of reading
+// entire list to find it
+func (self *clientNode) readListEntry(r node.ListRequest) (node.Node, []val.Value, error) {
+	entry, err := self.get(r.Selection.Context, r.Selection.Path.SetKey(r.Key), self.readParams(r.Selection.Context))
+	if errors.Is(err, fc.NotFoundError) {
+		return nil, nil, nil
+	}
+	if err != nil || entry == nil {
+		return nil, nil, err
+	}
+	return entry, r.Key, nil
+}
+
+// readParams are params for reads w/content filter from context if any
+func (self *clientNode) readParams(ctx context.Context) string {
+	params := self.params
+	if self.maxDepth > 0 {
+		if params != "" {
+			params += "&"
+		}
+		params += "depth=" + strconv.Itoa(self.maxDepth)
+	}
+	if ctx == nil {
+		return params
+	}
+	if content, found := contentFrom(ctx); found {
+		if filter := contentParam(content, self.legacyConfig); filter != "" {
+			if params != "" {
+				params += "&"
+			}
+			params += filter
+		}
+	}
+	if mode, found := withDefaultsFrom(ctx); found {
+		if params != "" {
+			params += "&"
+		}
+		params += "with-defaults=" + url.QueryEscape(mode)
+	}
+	if expr, found := fieldsFrom(ctx); found {
+		if params != "" {
+			params += "&"
+		}
+		params += fieldsParam(expr)
+	}
+	return params
+}
+
+func (self *clientNode) startEditMode(ctx context.Context, path *node.Path) error {
+	// need everything to report a complete diff
+	params := editReadParams(self.editWithDefaults, self.legacyConfig, self.onBeforeSend != nil)
+	existing, err := self.get(ctx, path, params)
 	if err != nil {
 		return err
 	}
+	self.existing = existing
 	data := make(map[string]interface{})
-	self.changes = nodeutil.ReflectChild(data)
+	self.changesData = data
+	self.changes = binaryEdits(nodeutil.ReflectChild(data), make(map[string]val.Value))
 	self.edit = &nodeutil.Extend{
 		Base: self.changes,
 		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
@@ -159,9 +330,102 @@ func (self *clientNode) startEditMode(path *node.Path) error {
 	return nil
 }
 
-func (self *clientNode) validNavigation(target *node.Path) (bool, error) {
+// editReadParams are for reading what is on server before an edit.  Unless
+// full, add depth = 1 so we can pull first level containers and know what
+// container would be conflicts.  we'll have to pull field values too because
+// there's no url param to exclude those yet.
+func editReadParams(withDefaults string, legacyConfig bool, full bool) string {
+	if withDefaults == "" {
+		withDefaults = DefaultsTrim
+	}
+	params := contentParam(ContentConfig, legacyConfig) + "&with-defaults=" + withDefaults
+	if full {
+		return params
+	}
+	return "depth=1&" + params
+}
+
+func (self *clientNode) diff(sel node.Selection) (Diff, error) {
+	var d Diff
+	from := make(map[string]interface{})
+	if self.existing != nil {
+		if err := sel.Split(self.existing).InsertInto(nodeutil.ReflectChild(from)).LastErr; err != nil {
+			return d, err
+		}
+	}
+	diffData(sel.Meta().(meta.HasDataDefinitions), "", from, self.changesData, &d)
+	return d, nil
+}
+
+// validate staged changes.  POST creates everything staged under target and
+// PUT replaces target unless device merges w/PATCH.
+func (self *clientNode) validate(sel node.Selection) error {
+	staged := node.Selection{
+		Node:        self.changes,
+		Path:        sel.Path,
+		Constraints: &node.Constraints{},
+		Context:     sel.Context,
+	}
+	replace := self.method == "PUT" && !self.patchEdits
+	return validateEdit(staged, replace, replace || self.method == "POST")
+}
+
+// requireKeys catches list entries staged w/o all their keys before server
+// does because server errors rarely say which key is missing.
+func (self *clientNode) requireKeys(sel node.Selection) error {
+	if l, isList := sel.Meta().(*meta.List); isList && len(sel.Path.Key()) == 0 {
+		return requireListKeys(l, sel.Path.String(), self.changesData)
+	}
+	return requireDataKeys(sel.Meta().(meta.HasDataDefinitions), sel.Path.String(), self.changesData)
+}
+
+func requireDataKeys(m meta.HasDataDefinitions, prefix string, data map[string]interface{}) error {
+	for _, def := range m.DataDefinitions() {
+		p := prefix + "/" + def.Ident()
+		var err error
+		switch x := def.(type) {
+		case *meta.Choice:
+			for _, kase := range x.Cases() {
+				if err = requireDataKeys(kase, prefix, data); err != nil {
+					break
+				}
+			}
+		case *meta.List:
+			err = requireListKeys(x, p, asDataMap(data[x.Ident()]))
+		case meta.HasDataDefinitions:
+			if child := asDataMap(data[x.Ident()]); child != nil {
+				err = requireDataKeys(x, p, child)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func requireListKeys(l *meta.List, p string, entries map[string]interface{}) error {
+	for _, k := range sortedKeys(entries) {
+		entry := asDataMap(entries[k])
+		for _, key := range l.KeyMeta() {
+			if _, found := entry[key.Ident()]; !found {
+				return fmt.Errorf("%w. %s missing key %s", fc.BadRequestError, p, key.Ident())
+			}
+		}
+		if err := requireDataKeys(l, p+"="+k, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *clientNode) validNavigation(ctx context.Context, target *node.Path) (bool, error) {
 	if !self.found {
-		_, err := self.request("OPTIONS", target, noSelection)
+		probe := self.probe
+		if probe == "" {
+			probe = ProbeOptions
+		}
+		_, err := self.request(ctx, probe, target, noSelection)
 		if errors.Is(err, fc.NotFoundError) {
 			return false, nil
 		}
@@ -173,17 +437,151 @@ func (self *clientNode) validNavigation(target *node.Path) (bool, error) {
 	return true, nil
 }
 
-func (self *clientNode) get(p *node.Path, params string) (node.Node, error) {
-	return self.support.clientDo("GET", params, p, nil)
+func (self *clientNode) get(ctx context.Context, p *node.Path, params string) (node.Node, error) {
+	return self.do(ctx, "GET", params, p, nil)
+}
+
+func (self *clientNode) do(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+	if self.datastore != "" {
+		if _, found := datastoreFrom(ctx); !found {
+			ctx = WithDatastore(ctx, self.datastore)
+		}
+	}
+	return self.support.clientDo(ctx, method, params, p, payload)
 }
 
-func (self *clientNode) request(method string, p *node.Path, in node.Selection) (node.Node, error) {
+func (self *clientNode) request(ctx context.Context, method string, p *node.Path, in node.Selection) (node.Node, error) {
+	if self.streamEdits && !in.IsNil() {
+		return self.streamRequest(ctx, method, p, in)
+	}
 	var payload bytes.Buffer
 	if !in.IsNil() {
-		js := &nodeutil.JSONWtr{Out: &payload}
-		if err := in.InsertInto(js.Node()).LastErr; err != nil {
+		if err := codecOrDefault(self.codec).Write(&payload, in); err != nil {
 			return nil, err
 		}
 	}
-	return self.support.clientDo(method, "", p, &payload)
+	return self.do(ctx, method, "", p, &payload)
+}
+
+// streamRequest avoids holding entire payload in memory by writing into
+// request body as http client reads it
+func (self *clientNode) streamRequest(ctx context.Context, method string, p *node.Path, in node.Selection) (node.Node, error) {
+	rdr, wtr := io.Pipe()
+	// unblocks writer if request never reads entire body
+	defer rdr.Close()
+	go func() {
+		wtr.CloseWithError(codecOrDefault(self.codec).Write(wtr, in))
+	}()
+	return self.do(ctx, method, "", p, rdr)
+}
+
+// binaryEdits holds binary values on the side because reflect node can store
+// them but cannot convert them back into values when edit is sent.
+func binaryEdits(n node.Node, values map[string]val.Value) node.Node {
+	return &nodeutil.Extend{
+		Base: n,
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			child, err := p.Child(r)
+			if child == nil || err != nil {
+				return child, err
+			}
+			return binaryEdits(child, values), nil
+		},
+		OnNext: func(p node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			entry, key, err := p.Next(r)
+			if entry == nil || err != nil {
+				return entry, key, err
+			}
+			return binaryEdits(entry, values), key, nil
+		},
+		OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if !isBinary(r.Meta) {
+				return p.Field(r, hnd)
+			}
+			id := r.Selection.Path.String() + "/" + r.Meta.Ident()
+			if r.Write {
+				values[id] = hnd.Val
+				// still stored so diff can see it
+				return p.Field(r, hnd)
+			}
+			hnd.Val = values[id]
+			return nil
+		},
+	}
+}
+
+const (
+	// ContentConfig reads only configuration
+	ContentConfig = "config"
+
+	// ContentNonConfig reads only operational state
+	ContentNonConfig = "nonconfig"
+
+	// ContentAll reads both configuration and operational state
+	ContentAll = "all"
+)
+
+type contentKey struct{}
+
+// WithContent limits reads made with this context to configuration or
+// operational state.
+//
+//	b.RootWithContext(restconf.WithContent(ctx, restconf.ContentNonConfig))
+func WithContent(ctx context.Context, content string) context.Context {
+	return context.WithValue(ctx, contentKey{}, content)
+}
+
+func contentFrom(ctx context.Context) (string, bool) {
+	content, found := ctx.Value(contentKey{}).(string)
+	return content, found
+}
+
+// contentParam is RFC 8040 content param or legacy config param some older
+// servers understand instead.  Legacy form has no way to ask for all, which
+// is default anyway, so nothing is sent.
+func contentParam(content string, legacy bool) string {
+	if !legacy {
+		return "content=" + content
+	}
+	switch content {
+	case ContentConfig:
+		return "config=true"
+	case ContentNonConfig:
+		return "config=false"
+	}
+	return ""
+}
+
+// with-defaults modes (RFC 6243) for WithDefaults and Client.EditWithDefaults
+const (
+	// DefaultsReportAll includes leaves that have their default value even if
+	// they were never set
+	DefaultsReportAll = "report-all"
+
+	// DefaultsReportAllTagged is like DefaultsReportAll but defaulted leaves
+	// are annotated so they can be told apart
+	DefaultsReportAllTagged = "report-all-tagged"
+
+	// DefaultsTrim leaves out leaves that equal their default value
+	DefaultsTrim = "trim"
+
+	// DefaultsExplicit includes leaves that were set even if set to their
+	// default value
+	DefaultsExplicit = "explicit"
+)
+
+type withDefaultsKey struct{}
+
+// WithDefaults controls if leaves w/default values are included in reads
+// made with this context.  Reads made before an edit always use
+// Client.EditWithDefaults.
+//
+//	b.RootWithContext(restconf.WithDefaults(ctx, restconf.DefaultsReportAll))
+func WithDefaults(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, withDefaultsKey{}, mode)
+}
+
+func withDefaultsFrom(ctx context.Context) (string, bool) {
+	mode, found := ctx.Value(withDefaultsKey{}).(string)
+	return mode, found && mode != ""
 }
@@ -2,8 +2,10 @@ package restconf
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"io/ioutil"
@@ -66,17 +68,20 @@ type testDriverFlowSupport struct {
 	get  map[string]string
 	put  map[string]string
 	post map[string]string
+
+	getParams []string
 }
 
-func (self *testDriverFlowSupport) clientDo(method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+func (self *testDriverFlowSupport) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
 	path := p.StringNoModule()
 	switch method {
 	case "GET":
+		self.getParams = append(self.getParams, params)
 		in, found := self.get[path]
 		if !found {
 			return node.ErrorNode{Err: fmt.Errorf("no response for %s", path)}, nil
 		}
-		return nodeutil.ReadJSON(in), nil
+		return JSONCodec.Reader(strings.NewReader(in)), nil
 	case "PUT":
 		body, _ := ioutil.ReadAll(payload)
 		self.put = map[string]string{
@@ -94,3 +99,285 @@ func (self *testDriverFlowSupport) clientDo(method string, params string, p *nod
 func (self *testDriverFlowSupport) clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error) {
 	panic("not implemented")
 }
+
+func Test_ClientBeforeSend(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf color {
+				type string;
+			}
+			leaf owner {
+				type string;
+			}
+			container mileage {
+				leaf odometer {
+					type int32;
+				}
+				leaf trip {
+					type int32;
+				}
+			}
+			list tire {
+				key "pos";
+				leaf pos {
+					type string;
+				}
+				leaf psi {
+					type int32;
+				}
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support := &testDriverFlowSupport{
+		t: t,
+		get: map[string]string{
+			"car": `{"color":"red","owner":"joe"}`,
+		},
+	}
+	var diff Diff
+	veto := errors.New("vetoed")
+	d := &clientNode{
+		support: support,
+		onBeforeSend: func(method string, p *node.Path, actual Diff) error {
+			fc.AssertEqual(t, "PUT", method)
+			fc.AssertEqual(t, "x/car", p.String())
+			diff = actual
+			return veto
+		},
+	}
+	b := node.NewBrowser(m, d.node())
+	edit := nodeutil.ReadJSON(`{"color":"blue","mileage":{"trip":0},"tire":[{"pos":"fr","psi":32}]}`)
+	err = b.Root().Find("car").UpsertFrom(edit).LastErr
+	if !errors.Is(err, veto) {
+		t.Error(err)
+	}
+	fc.AssertEqual(t, 0, len(support.put))
+	fc.AssertEqual(t, "mileage/trip,tire=fr/pos,tire=fr/psi", diffPaths(diff.Added))
+	fc.AssertEqual(t, "color", diffPaths(diff.Changed))
+	fc.AssertEqual(t, "owner", diffPaths(diff.Removed))
+	fc.AssertEqual(t, "red", diff.Changed[0].Old)
+	fc.AssertEqual(t, "blue", diff.Changed[0].New)
+}
+
+func diffPaths(diffs []LeafDiff) string {
+	paths := make([]string, len(diffs))
+	for i, d := range diffs {
+		paths[i] = d.Path
+	}
+	return strings.Join(paths, ",")
+}
+
+func Test_ClientEditWithDefaults(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf color {
+				type string;
+				default "white";
+			}
+			leaf owner {
+				type string;
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// color was explicitly set to it's default value, trim hides that
+	byMode := map[string]string{
+		"trim":     `{"owner":"joe"}`,
+		"explicit": `{"color":"white","owner":"joe"}`,
+	}
+	for mode, existing := range byMode {
+		support := &testDriverFlowSupport{
+			t:   t,
+			get: map[string]string{"car": existing},
+		}
+		var diff Diff
+		d := &clientNode{
+			support:          support,
+			editWithDefaults: mode,
+			onBeforeSend: func(method string, p *node.Path, actual Diff) error {
+				diff = actual
+				return nil
+			},
+		}
+		b := node.NewBrowser(m, d.node())
+		edit := nodeutil.ReadJSON(`{"color":"white","owner":"joe"}`)
+		if err := b.Root().Find("car").UpsertFrom(edit).LastErr; err != nil {
+			t.Fatal(err)
+		}
+		fc.AssertEqual(t, "content=config&with-defaults="+mode, support.getParams[0])
+		fc.AssertEqual(t, `{"color":"white","owner":"joe"}`, support.put["car"])
+		if mode == "explicit" {
+			fc.AssertEqual(t, true, diff.Empty())
+		} else {
+			fc.AssertEqual(t, "color", diffPaths(diff.Added))
+		}
+	}
+}
+
+func Test_ClientContentParam(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf color {
+				type string;
+			}
+			leaf speed {
+				type int32;
+				config false;
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		legacy  bool
+		content string
+		read    string
+		edit    string
+	}{
+		{false, ContentNonConfig, "content=nonconfig", "depth=1&content=config&with-defaults=trim"},
+		{false, ContentAll, "content=all", "depth=1&content=config&with-defaults=trim"},
+		{true, ContentNonConfig, "config=false", "depth=1&config=true&with-defaults=trim"},
+		{true, ContentConfig, "config=true", "depth=1&config=true&with-defaults=trim"},
+		{true, ContentAll, "", "depth=1&config=true&with-defaults=trim"},
+	}
+	for _, test := range tests {
+		support := &testDriverFlowSupport{
+			t:   t,
+			get: map[string]string{"car": `{"speed":10}`},
+		}
+		newBrowser := func() *node.Browser {
+			return node.NewBrowser(m, (&clientNode{support: support, legacyConfig: test.legacy}).node())
+		}
+		ctx := WithContent(context.Background(), test.content)
+		_, err := nodeutil.WriteJSON(newBrowser().RootWithContext(ctx).Find("car"))
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.read, support.getParams[0])
+
+		edit := nodeutil.ReadJSON(`{"color":"blue"}`)
+		fc.AssertEqual(t, nil, newBrowser().Root().Find("car").UpsertFrom(edit).LastErr)
+		fc.AssertEqual(t, test.edit, support.getParams[1])
+	}
+}
+
+func Test_ClientWithDefaults(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf color {
+				type string;
+				default "red";
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support := &testDriverFlowSupport{
+		t:   t,
+		get: map[string]string{"car": `{"color":"red"}`},
+	}
+	b := node.NewBrowser(m, (&clientNode{support: support}).node())
+	ctx := WithContent(WithDefaults(context.Background(), DefaultsReportAll), ContentConfig)
+	_, err = nodeutil.WriteJSON(b.RootWithContext(ctx).Find("car"))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "content=config&with-defaults=report-all", support.getParams[0])
+
+	// edits still compare against trimmed config
+	b = node.NewBrowser(m, (&clientNode{support: support}).node())
+	edit := nodeutil.ReadJSON(`{"color":"blue"}`)
+	fc.AssertEqual(t, nil, b.RootWithContext(ctx).Find("car").UpsertFrom(edit).LastErr)
+	fc.AssertEqual(t, "depth=1&content=config&with-defaults=trim", support.getParams[1])
+}
+
+func Test_ClientRequireKeys(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container fleet {
+			list car {
+				key "make model";
+				leaf make {
+					type string;
+				}
+				leaf model {
+					type string;
+				}
+				leaf color {
+					type string;
+				}
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support := &testDriverFlowSupport{
+		t:   t,
+		get: map[string]string{"fleet": `{}`},
+	}
+	d := &clientNode{support: support}
+	b := node.NewBrowser(m, d.node())
+	edit := nodeutil.ReadJSON(`{"car":[{"make":"vw","model":"bug"},{"make":"ford","color":"red"}]}`)
+	err = b.Root().Find("fleet").UpsertFrom(edit).LastErr
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+	fc.AssertEqual(t, true, strings.Contains(err.Error(), "missing key model"))
+	fc.AssertEqual(t, 0, len(support.put))
+
+	edit = nodeutil.ReadJSON(`{"car":[{"make":"ford","model":"t","color":"red"}]}`)
+	b = node.NewBrowser(m, (&clientNode{support: support}).node())
+	fc.AssertEqual(t, nil, b.Root().Find("fleet").UpsertFrom(edit).LastErr)
+	fc.AssertEqual(t, `{"car":[{"make":"ford","model":"t","color":"red"}]}`, support.put["fleet"])
+}
+
+func Test_ClientBinaryAndAnydata(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf photo {
+				type binary;
+			}
+			anydata extra;
+			container engine {
+				leaf firmware {
+					type binary;
+				}
+			}
+			list tire {
+				key pos;
+				leaf pos {
+					type string;
+				}
+				leaf tag {
+					type binary;
+				}
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "hello" and "world" base64 encoded
+	expected := `{"photo":"aGVsbG8=","extra":{"any":["thing",1]},"engine":{"firmware":"d29ybGQ="},"tire":[{"pos":"fr","tag":"aGVsbG8="}]}`
+	support := &testDriverFlowSupport{
+		t:   t,
+		get: map[string]string{"car": expected},
+	}
+	d := &clientNode{support: support}
+	b := node.NewBrowser(m, d.node())
+	car := b.Root().Find("car")
+	photo, err := car.Get("photo")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "hello", string(photo.([]byte)))
+	actual, err := nodeutil.WriteJSON(car)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, expected, actual)
+
+	support.get = map[string]string{"car": `{}`}
+	edit := JSONCodec.Reader(strings.NewReader(`{"photo":"aGVsbG8=","extra":{"any":["thing",1]}}`))
+	if err := b.Root().Find("car").UpsertFrom(edit).LastErr; err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, `{"photo":"aGVsbG8=","extra":{"any":["thing",1]}}`, support.put["car"])
+}
@@ -0,0 +1,151 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// recordedCall is a single request/response pair as it is stored in a recording.
+// Recordings are newline delimited JSON of these.
+type recordedCall struct {
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Params    string `json:"params,omitempty"`
+	Datastore string `json:"datastore,omitempty"`
+	Request   string `json:"request,omitempty"`
+	Response  string `json:"response,omitempty"`
+	Err       string `json:"err,omitempty"`
+	Status    int    `json:"status,omitempty"`
+}
+
+// recorder captures every interaction w/device like a VCR cassette so it can
+// be replayed offline w/player.
+type recorder struct {
+	support clientSupport
+	mu      sync.Mutex
+	out     *json.Encoder
+}
+
+func newRecorder(support clientSupport, out io.Writer) *recorder {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	return &recorder{support: support, out: enc}
+}
+
+func (self *recorder) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+	call := recordedCall{
+		Method: method,
+		Path:   p.String(),
+		Params: params,
+	}
+	if ctx != nil {
+		call.Datastore, _ = datastoreFrom(ctx)
+	}
+	if payload != nil {
+		body, err := ioutil.ReadAll(payload)
+		if err != nil {
+			return nil, err
+		}
+		call.Request = string(body)
+		payload = bytes.NewReader(body)
+	}
+	resp, err := self.support.clientDo(ctx, method, params, p, payload)
+	if err == nil && resp != nil {
+		// responses are decoded up front so reading it here doesn't interfere
+		// w/caller reading it again.  Responses that cannot be read, like an
+		// empty OPTIONS response, are recorded as empty.
+		sel := node.Selection{
+			Node:        resp,
+			Path:        p,
+			Constraints: &node.Constraints{},
+			Context:     context.Background(),
+		}
+		if recorded, readErr := nodeutil.WriteJSON(sel); readErr == nil {
+			call.Response = recorded
+		}
+	}
+	if err != nil {
+		call.Err = err.Error()
+		call.Status = fc.HttpStatusCode(err)
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if encErr := self.out.Encode(call); encErr != nil {
+		fc.Err.Printf("could not record %s %s. %s", method, call.Path, encErr)
+	}
+	return resp, err
+}
+
+func (self *recorder) clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error) {
+	// notifications are not recorded
+	return self.support.clientStream(params, p, ctx)
+}
+
+// player serves requests from a recording made by recorder.  Calls are matched
+// on method, path, params and request body and each recorded call is only
+// served once so repeated calls play back in order they were recorded.
+type player struct {
+	mu    sync.Mutex
+	calls []*recordedCall
+}
+
+func newPlayer(in io.Reader) (*player, error) {
+	p := &player{}
+	d := json.NewDecoder(in)
+	for {
+		var call recordedCall
+		if err := d.Decode(&call); err == io.EOF {
+			return p, nil
+		} else if err != nil {
+			return nil, err
+		}
+		p.calls = append(p.calls, &call)
+	}
+}
+
+func (self *player) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = ioutil.ReadAll(payload); err != nil {
+			return nil, err
+		}
+	}
+	path := p.String()
+	var ds string
+	if ctx != nil {
+		ds, _ = datastoreFrom(ctx)
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for i, call := range self.calls {
+		if call.Method == method && call.Path == path && call.Params == params && call.Request == string(body) && call.Datastore == ds {
+			self.calls = append(self.calls[:i], self.calls[i+1:]...)
+			if call.Err != "" {
+				if sentinel := statusSentinel(call.Status); sentinel != nil {
+					return nil, fmt.Errorf("%w. %s", sentinel, call.Err)
+				}
+				return nil, errors.New(call.Err)
+			}
+			if call.Response == "" {
+				return nil, nil
+			}
+			return nodeutil.ReadJSON(call.Response), nil
+		}
+	}
+	return nil, fmt.Errorf("%w. no recording for %s %s", fc.NotFoundError, method, path)
+}
+
+func (self *player) clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error) {
+	return nil, fmt.Errorf("%w. notifications cannot be played back", fc.NotImplementedError)
+}
@@ -0,0 +1,65 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestRecordPlayback(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf speed {
+				type int32;
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := &testDriverFlowSupport{
+		t: t,
+		get: map[string]string{
+			"car": `{"speed":10}`,
+		},
+	}
+	var cassette bytes.Buffer
+	rec := newRecorder(live, &cassette)
+	b := node.NewBrowser(m, (&clientNode{support: rec}).node())
+	actual, err := nodeutil.WriteJSON(b.Root().Find("car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, `{"speed":10}`, actual)
+	edit := nodeutil.ReadJSON(`{"speed":20}`)
+	if err := b.Root().Find("car").UpsertFrom(edit).LastErr; err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, 4, bytes.Count(cassette.Bytes(), []byte("\n")))
+
+	play, err := newPlayer(&cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = node.NewBrowser(m, (&clientNode{support: play}).node())
+	actual, err = nodeutil.WriteJSON(b.Root().Find("car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, `{"speed":10}`, actual)
+	if err := b.Root().Find("car").UpsertFrom(edit).LastErr; err != nil {
+		t.Fatal(err)
+	}
+
+	// all recorded calls have been used up
+	_, err = play.clientDo(context.Background(), "GET", "", b.Root().Find("car").Path, nil)
+	if !errors.Is(err, fc.NotFoundError) {
+		t.Error(err)
+	}
+}
@@ -1,20 +1,32 @@
 package restconf
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"bytes"
 
 	"io/ioutil"
 
+	"github.com/freeconf/restconf/device"
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
 	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
 	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+	"github.com/freeconf/yang/val"
 )
 
 func TestClient(t *testing.T) {
@@ -40,6 +52,14 @@ func TestClient(t *testing.T) {
 
 	ls := b.sel(b.ddef(`list x { key "y"; leaf y { type string; } }`), `{"x":[{"y":"hi"}]}`)
 	support.reset().node().Next(b.lr(ls, "hi"))
+	fc.AssertEqual(t, "GET path=x=hi", support.log())
+
+	mls := b.sel(b.ddef(`list x { key "a b"; leaf a { type string; } leaf b { type int32; } }`), `{"x":[]}`)
+	support.reset().node().Next(b.lr(mls, []interface{}{"hi", 9}))
+	fc.AssertEqual(t, "GET path=x=hi,9", support.log())
+
+	ls = b.sel(b.ddef(`list x { key "y"; leaf y { type string; } }`), `{"x":[{"y":"hi"}]}`)
+	support.reset().node().Next(b.lr(ls, nil))
 	fc.AssertEqual(t, "GET path=x", support.log())
 
 	// nav
@@ -84,6 +104,7 @@ func TestClient(t *testing.T) {
 type testDriverSupport struct {
 	_log        string
 	doResponse  node.Node
+	doErr       error
 	_subs       int
 	ws          bytes.Buffer
 	subPayloads string
@@ -92,6 +113,7 @@ type testDriverSupport struct {
 func (self *testDriverSupport) reset() *clientNode {
 	self._log = ""
 	self._subs = 0
+	self.doErr = nil
 	self.doResponse = &nodeutil.Basic{}
 	self.ws.Reset()
 	return &clientNode{support: self}
@@ -110,7 +132,7 @@ func (self *testDriverSupport) log() string {
 	return s
 }
 
-func (self *testDriverSupport) clientDo(method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+func (self *testDriverSupport) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
 	self._log += fmt.Sprintf("%s path=%s", method, p.String())
 	if params != "" {
 		self._log += " params=" + params
@@ -122,6 +144,9 @@ func (self *testDriverSupport) clientDo(method string, params string, p *node.Pa
 			self._log += fmt.Sprintf(" payload=%s", string(payloadBytes))
 		}
 	}
+	if self.doErr != nil {
+		return nil, self.doErr
+	}
 	return self.doResponse, nil
 }
 
@@ -130,6 +155,39 @@ func (self *testDriverSupport) clientStream(params string, p *node.Path, ctx con
 	return nil, nil
 }
 
+// newTestClient talks to srv as a device at srv's /restconf w/modules
+// already loaded
+func newTestClient(srv *httptest.Server, modules ...*meta.Module) *client {
+	address, err := NewAddress(srv.URL + "/restconf")
+	if err != nil {
+		panic(err)
+	}
+	c := &client{
+		address: address,
+		modules: make(map[string]*meta.Module),
+	}
+	c.client = srv.Client()
+	for _, m := range modules {
+		c.modules[m.Ident()] = m
+	}
+	c.support = c
+	return c
+}
+
+// waitFor polls until done is true, failing test if it doesn't happen soon
+// enough.  For state updated asynchronously w/nothing to wait on.
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		runtime.Gosched()
+		time.Sleep(time.Millisecond)
+	}
+}
+
 type requestBuilder struct {
 }
 
@@ -149,7 +207,13 @@ func (requestBuilder) lr(s node.Selection, key interface{}) node.ListRequest {
 		},
 		Meta: s.Meta().(*meta.List),
 	}
-	if key != nil {
+	if multi, isMulti := key.([]interface{}); isMulti {
+		var err error
+		r.Key, err = node.NewValues(r.Meta.KeyMeta(), multi...)
+		if err != nil {
+			panic(err)
+		}
+	} else if key != nil {
 		var err error
 		r.Key, err = node.NewValues(r.Meta.KeyMeta(), key)
 		if err != nil {
@@ -255,6 +319,12 @@ func (self requestBuilder) ddef(y string) meta.Definition {
 	return self.m(y).DataDefinitions()[0]
 }
 
+// path to first definition as it would be when navigating from module root
+func (self requestBuilder) path(y string) *node.Path {
+	m := self.m(y)
+	return node.NewContainerPath(node.NewRootPath(m), m.DataDefinitions()[0].(meta.HasDefinitions))
+}
+
 func (requestBuilder) m(y string) *meta.Module {
 	mstr := fmt.Sprint(`module m { namespace ""; prefix ""; revision 0; `, y, `}`)
 	m, err := parser.LoadModuleFromString(nil, mstr)
@@ -263,3 +333,1348 @@ func (requestBuilder) m(y string) *meta.Module {
 	}
 	return m
 }
+
+func TestClientDoStatusErr(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	p := requestBuilder{}.path(`container x {}`)
+	_, err := c.clientDo(context.Background(), "GET", "", p, nil)
+	if !errors.Is(err, fc.NotFoundError) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	fc.AssertEqual(t, true, errors.Is(statusErr(409, ""), fc.ConflictError))
+	fc.AssertEqual(t, "(500) bang", statusErr(500, "bang").Error())
+}
+
+func TestClientStreamEdits(t *testing.T) {
+	support := &testDriverSupport{}
+	b := requestBuilder{}
+	s := b.sel(b.ddef(`container x { leaf z { type string; } }`), `{"z":"hi"}`)
+	n := support.reset()
+	n.streamEdits = true
+	nr := b.nr(s)
+	nn := n.node()
+	nn.BeginEdit(nr)
+	support.log()
+	nn.Field(b.frw(s, "z", "hi"))
+	nn.EndEdit(nr)
+	fc.AssertEqual(t, `PUT path=x payload={"z":"hi"}`, support.log())
+}
+
+type discardSupport struct{}
+
+func (discardSupport) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+	if payload != nil {
+		io.Copy(ioutil.Discard, payload)
+	}
+	return nil, nil
+}
+
+func (discardSupport) clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error) {
+	return nil, nil
+}
+
+func benchmarkEdit(bench *testing.B, stream bool) {
+	b := requestBuilder{}
+	m := b.m(`list x { key "id"; leaf id { type int32; } leaf v { type string; } }`)
+	var data bytes.Buffer
+	data.WriteString(`{"x":[`)
+	for i := 0; i < 20000; i++ {
+		if i > 0 {
+			data.WriteRune(',')
+		}
+		fmt.Fprintf(&data, `{"id":%d,"v":"some reasonably long value to make payload large"}`, i)
+	}
+	data.WriteString(`]}`)
+	src := node.NewBrowser(m, nodeutil.ReadJSON(data.String()))
+	sel := src.Root().Find("x")
+	n := &clientNode{support: discardSupport{}, streamEdits: stream}
+	bench.ReportAllocs()
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		if _, err := n.request(sel.Context, "PUT", sel.Path, sel); err != nil {
+			bench.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEditBuffered(b *testing.B) {
+	benchmarkEdit(b, false)
+}
+
+func BenchmarkEditStreamed(b *testing.B) {
+	benchmarkEdit(b, true)
+}
+
+func TestClientListEntryNotFound(t *testing.T) {
+	support := &testDriverSupport{}
+	b := requestBuilder{}
+	ls := b.sel(b.ddef(`list x { key "y"; leaf y { type string; } }`), `{"x":[]}`)
+	n := support.reset()
+	support.doErr = fc.NotFoundError
+	found, _, err := n.node().Next(b.lr(ls, "nope"))
+	if err != nil {
+		t.Error(err)
+	}
+	if found != nil {
+		t.Error("expected no entry")
+	}
+}
+
+func TestClientBootstrapTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/restconf/schema/"):
+			// device hangs while serving schema
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		case r.Method == "GET":
+			w.Write([]byte(`{"module":[{"name":"slow","revision":"0","namespace":"s"}]}`))
+		}
+	}))
+	defer srv.Close()
+	factory := Client{
+		YangPath:         source.Dir("./yang"),
+		BootstrapTimeout: 100 * time.Millisecond,
+	}
+	t0 := time.Now()
+	_, err := factory.NewDevice(srv.URL + "/restconf")
+	if err == nil {
+		t.Fatal("expected timeout")
+	}
+	if time.Since(t0) > 3*time.Second {
+		t.Error("bootstrap timeout not honored")
+	}
+	if !strings.Contains(err.Error(), "aborted loading schema slow after loading 0 modules") {
+		t.Error(err)
+	}
+}
+
+func TestParseAllow(t *testing.T) {
+	fc.AssertEqual(t, "GET,PUT,POST,DELETE,OPTIONS", strings.Join(parseAllow("GET, PUT,post , DELETE, OPTIONS"), ","))
+	fc.AssertEqual(t, 0, len(parseAllow("")))
+}
+
+func TestClientAllowedMethods(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Header().Set("Allow", "GET, OPTIONS")
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	p := requestBuilder{}.path(`container x {}`)
+	if _, err := c.clientDo(context.Background(), "OPTIONS", "", p, nil); err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, "GET,OPTIONS", strings.Join(c.AllowedMethods("m:x"), ","))
+	_, err := c.clientDo(context.Background(), "PUT", "", p, nil)
+	if !errors.Is(err, ErrMethodNotAllowed) {
+		t.Error(err)
+	}
+	fc.AssertEqual(t, "OPTIONS", strings.Join(methods, ","))
+}
+
+func TestClientCompress(t *testing.T) {
+	var encoding, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Encoding", "gzip")
+		encoding = r.Header.Get("Content-Encoding")
+		in := r.Body
+		if encoding == "gzip" {
+			var err error
+			if in, err = gzip.NewReader(r.Body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		data, _ := ioutil.ReadAll(in)
+		body = string(data)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	c.compressThreshold = 100
+	p := requestBuilder{}.path(`container x {}`)
+	big := `{"x":"` + strings.Repeat("a", 200) + `"}`
+
+	// server has not advertised support yet
+	c.clientDo(context.Background(), "PUT", "", p, strings.NewReader(big))
+	fc.AssertEqual(t, "", encoding)
+
+	c.clientDo(context.Background(), "PUT", "", p, strings.NewReader(big))
+	fc.AssertEqual(t, "gzip", encoding)
+	fc.AssertEqual(t, big, body)
+
+	small := `{"x":"a"}`
+	c.clientDo(context.Background(), "PUT", "", p, strings.NewReader(small))
+	fc.AssertEqual(t, "", encoding)
+	fc.AssertEqual(t, small, body)
+}
+
+func TestClientActionRaw(t *testing.T) {
+	var reqBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		reqBody = string(data)
+		w.Header().Set("X-RateLimit-Remaining", "9")
+		fmt.Fprint(w, `{"out":"bye"}`)
+	}))
+	defer srv.Close()
+	m := requestBuilder{}.m(`rpc x { input { leaf in { type string; } } output { leaf out { type string; } } }`)
+	c := newTestClient(srv, m)
+	resp, output, err := c.ActionRaw(context.Background(), "m:x", nodeutil.ReadJSON(`{"in":"hi"}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "9", resp.Header.Get("X-RateLimit-Remaining"))
+	fc.AssertEqual(t, `{"in":"hi"}`, reqBody)
+	out, err := nodeutil.WriteJSON(node.Selection{
+		Node:        output,
+		Path:        node.NewRootPath(m.Actions()["x"].Output()),
+		Constraints: &node.Constraints{},
+		Context:     context.Background(),
+	})
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"out":"bye"}`, out)
+
+	_, _, err = c.ActionRaw(context.Background(), "m:nope", nil)
+	fc.AssertEqual(t, true, err != nil)
+}
+
+func TestClientValidateActionOutput(t *testing.T) {
+	var respBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, respBody)
+	}))
+	defer srv.Close()
+	m := requestBuilder{}.m(`rpc x { output { leaf out { type string; mandatory true; } leaf extra { type string; } } }`)
+	c := newTestClient(srv, m)
+	c.validateOutput = true
+	ctx := context.Background()
+	respBody = `{"out":"bye"}`
+	_, _, err := c.ActionRaw(ctx, "m:x", nil)
+	fc.AssertEqual(t, nil, err)
+
+	respBody = `{"m:out":"bye","bogus":1}`
+	_, _, err = c.ActionRaw(ctx, "m:x", nil)
+	fc.AssertEqual(t, true, errors.Is(err, ErrUnexpectedOutput))
+
+	respBody = `{"extra":"bye"}`
+	_, _, err = c.ActionRaw(ctx, "m:x", nil)
+	fc.AssertEqual(t, true, errors.Is(err, ErrUnexpectedOutput))
+
+	p, _ := c.parsePath("m:x")
+	_, err = c.clientDo(ctx, "POST", "", p, nil)
+	fc.AssertEqual(t, true, errors.Is(err, ErrUnexpectedOutput))
+
+	c.validateOutput = false
+	_, _, err = c.ActionRaw(ctx, "m:x", nil)
+	fc.AssertEqual(t, nil, err)
+}
+
+func TestClientActionChunkedOutput(t *testing.T) {
+	var respBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// flushing before body forces chunked encoding w/o a content length
+		w.(http.Flusher).Flush()
+		fmt.Fprint(w, respBody)
+	}))
+	defer srv.Close()
+	m := requestBuilder{}.m(`rpc x { output { leaf out { type string; } } }`)
+	c := newTestClient(srv, m)
+	b := node.NewBrowser(m, c.newClientNode().node())
+	for _, empty := range []string{"", "\n", "  \r\n"} {
+		respBody = empty
+		out := b.Root().Find("x").Action(nil)
+		fc.AssertEqual(t, nil, out.LastErr)
+		fc.AssertEqual(t, true, out.IsNil())
+		_, output, err := c.ActionRaw(context.Background(), "m:x", nil)
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, nil, output)
+	}
+
+	respBody = `{"out":"bye"}`
+	out := b.Root().Find("x").Action(nil)
+	fc.AssertEqual(t, nil, out.LastErr)
+	actual, err := nodeutil.WriteJSON(out)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"out":"bye"}`, actual)
+}
+
+func TestClientDatastore(t *testing.T) {
+	var urlPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath = r.URL.Path
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	p := requestBuilder{}.path(`container x {}`)
+	ctx := context.Background()
+	c.clientDo(ctx, "GET", "", p, nil)
+	fc.AssertEqual(t, "/restconf/data/m:x", urlPath)
+
+	c.datastore = "ietf-datastores:running"
+	c.clientDo(ctx, "GET", "", p, nil)
+	fc.AssertEqual(t, "/restconf/ds/ietf-datastores:running/m:x", urlPath)
+
+	c.clientDo(WithDatastore(ctx, "ietf-datastores:operational"), "GET", "", p, nil)
+	fc.AssertEqual(t, "/restconf/ds/ietf-datastores:operational/m:x", urlPath)
+}
+
+func TestClientSharedTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"module":[]}`))
+	}))
+	defer srv.Close()
+	factory := Client{
+		YangPath:            source.Dir("./yang"),
+		MaxIdleConnsPerHost: 5,
+	}
+	a, err := factory.NewDevice(srv.URL + "/restconf=a")
+	fc.AssertEqual(t, nil, err)
+	b, err := factory.NewDevice(srv.URL + "/restconf=b")
+	fc.AssertEqual(t, nil, err)
+	shared := a.(*client).client.Transport
+	fc.AssertEqual(t, true, shared == b.(*client).client.Transport)
+	fc.AssertEqual(t, 5, shared.(*http.Transport).MaxIdleConnsPerHost)
+
+	var devices []string
+	factory.DeviceTransport = func(url string, shared *http.Transport) http.RoundTripper {
+		devices = append(devices, url)
+		return shared
+	}
+	c, err := factory.NewDevice(srv.URL + "/restconf=c")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, shared == c.(*client).client.Transport)
+	fc.AssertEqual(t, 1, len(devices))
+}
+
+func TestClientUrlPathKeys(t *testing.T) {
+	m := requestBuilder{}.m(`
+		list a {
+			key id;
+			leaf id { type string; }
+			list b {
+				key id;
+				leaf id { type string; }
+				list c {
+					key "x y";
+					leaf x { type string; }
+					leaf y { type string; }
+				}
+			}
+		}`)
+	a := m.DataDefinitions()[0].(*meta.List)
+	b := a.DataDefinitions()[1].(*meta.List)
+	c := b.DataDefinitions()[1].(*meta.List)
+	key := func(l *meta.List, s ...string) []val.Value {
+		k, err := node.NewValuesByString(l.KeyMeta(), s...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	pa := node.NewListItemPath(node.NewRootPath(m), a, key(a, "eth0"))
+	pb := node.NewListItemPath(pa, b, key(b, "10.0.0.1/24"))
+	fc.AssertEqual(t, "a=eth0/b=10.0.0.1%2F24", urlPath(pb))
+	pc := node.NewListItemPath(pb, c, key(c, "x,y", "a+b c"))
+	fc.AssertEqual(t, "a=eth0/b=10.0.0.1%2F24/c=x%2Cy,a%2Bb%20c", urlPath(pc))
+
+	// server decodes each key back to original
+	var rawPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawPath = r.URL.EscapedPath()
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+	cl := newTestClient(srv)
+	cl.clientDo(context.Background(), "GET", "", pc, nil)
+	fc.AssertEqual(t, "/restconf/data/m:a=eth0/b=10.0.0.1%2F24/c=x%2Cy,a%2Bb%20c", rawPath)
+	decoded, err := node.ParsePath(urlPath(pc), m)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, decoded.Tail.Equal(pc))
+}
+
+func TestClientKeyFormat(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		identity base;
+		identity eth { base base; }
+		list a {
+			key "on";
+			leaf on { type boolean; }
+			list b {
+				key "kind";
+				leaf kind { type enumeration { enum up { value 7; } enum down; } }
+				list c {
+					key "type";
+					leaf type { type identityref { base base; } }
+				}
+			}
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	a := meta.Find(m, "a").(*meta.List)
+	b := meta.Find(a, "b").(*meta.List)
+	c := meta.Find(b, "c").(*meta.List)
+	key := func(l *meta.List, s string) []val.Value {
+		k, err := node.NewValuesByString(l.KeyMeta(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	pa := node.NewListItemPath(node.NewRootPath(m), a, key(a, "true"))
+	pb := node.NewListItemPath(pa, b, key(b, "up"))
+	pc := node.NewListItemPath(pb, c, key(c, "eth"))
+
+	var rawPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawPath = r.URL.EscapedPath()
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+	cl := newTestClient(srv)
+	cl.clientDo(context.Background(), "GET", "", pc, nil)
+	fc.AssertEqual(t, "/restconf/data/m:a=true/b=up/c=eth", rawPath)
+
+	cl.keyFormat = func(k meta.Leafable, v val.Value) string {
+		switch x := v.(type) {
+		case val.Bool:
+			if x {
+				return "1"
+			}
+			return "0"
+		case val.Enum:
+			return fmt.Sprint(x.Id)
+		case val.IdentRef:
+			return "m:" + x.Label
+		}
+		return DefaultKeyFormat(k, v)
+	}
+	cl.clientDo(context.Background(), "GET", "", pc, nil)
+	fc.AssertEqual(t, "/restconf/data/m:a=1/b=7/c=m:eth", rawPath)
+}
+
+// sseStreams answers every request w/an event stream test writes to.  Pipe
+// writes only return once client reads them so each write is a handshake w/
+// client's decoder.
+type sseStreams chan *io.PipeWriter
+
+func (self sseStreams) RoundTrip(r *http.Request) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	self <- pw
+	return &http.Response{
+		StatusCode: 200,
+		Proto:      "HTTP/1.1",
+		Header:     http.Header{"Content-Type": {mimeEventStream}},
+		Body:       pr,
+		Request:    r,
+	}, nil
+}
+
+// sendEvents n thru n+count-1 to stream.  Decoder only reads an event after
+// handing previous one to client so when this returns, every event but last
+// has been queued or dropped.
+func sendEvents(t *testing.T, w io.Writer, n int, count int) {
+	t.Helper()
+	for i := n; i < n+count; i++ {
+		if _, err := fmt.Fprintf(w, "data: {\"n\":%d}\n\n", i); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+}
+
+// syncEvents returns once client has queued or dropped every event sent so
+// far.  Error frames aren't counted as notifications.
+func syncEvents(t *testing.T, w io.Writer) {
+	t.Helper()
+	for _, frame := range []string{"event: error\ndata: sync\n\n", ": sync\n"} {
+		if _, err := io.WriteString(w, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClientStreamBuffer(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	p := requestBuilder{}.path(`container x {}`)
+	newStream := func(drop bool) (*client, <-chan node.Node, *io.PipeWriter, context.CancelFunc) {
+		c := newTestClient(srv)
+		streams := make(sseStreams, 1)
+		c.client = &http.Client{Transport: streams}
+		c.streamBuffer = 2
+		c.streamDrop = drop
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := c.clientStream("", p, ctx)
+		fc.AssertEqual(t, nil, err)
+		return c, events, <-streams, cancel
+	}
+	// slow subscriber doesn't start reading until stream cannot take more
+	c, events, w, cancel := newStream(false)
+	defer cancel()
+	sendEvents(t, w, 0, 4)
+	fc.AssertEqual(t, 2, c.StreamStats()[0].Queued)
+	go sendEvents(t, w, 4, 6)
+	for i := 0; i < 10; i++ {
+		select {
+		case <-events:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("notification %d never arrived", i)
+		}
+	}
+
+	c, events, w, cancel = newStream(true)
+	defer cancel()
+	sendEvents(t, w, 0, 10)
+	syncEvents(t, w)
+	stats := c.StreamStats()[0]
+	fc.AssertEqual(t, uint64(10), stats.Received)
+	fc.AssertEqual(t, uint64(8), stats.Dropped)
+	fc.AssertEqual(t, 2, len(events))
+}
+
+func TestClientStreamCancel(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	p := requestBuilder{}.path(`container x {}`)
+	c := newTestClient(srv)
+	streams := make(sseStreams, 1)
+	c.client = &http.Client{Transport: streams}
+	// one subscriber never reads what device sent and the other is waiting
+	// on a device that never sends anything
+	var closed []<-chan node.Node
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := c.clientStream("", p, ctx)
+		fc.AssertEqual(t, nil, err)
+		w := <-streams
+		if i == 0 {
+			// stream is stuck on subscriber once decoder takes second event
+			sendEvents(t, w, 0, 2)
+		}
+		cancel()
+		closed = append(closed, events)
+	}
+	for i, events := range closed {
+		for open := true; open; {
+			select {
+			case _, open = <-events:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("stream %d never closed", i)
+			}
+		}
+	}
+	// decoders exit after stream closes their body
+	waitFor(t, "decoders to exit", func() bool {
+		buf := make([]byte, 1<<20)
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		return strings.Count(stacks, "restconf.decodeSse") == 0
+	})
+}
+
+func TestClientUserAgent(t *testing.T) {
+	var agents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Write([]byte(`{"module":[]}`))
+	}))
+	defer srv.Close()
+	factory := Client{YangPath: source.Dir("./yang")}
+	dev, err := factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "freeconf-restconf", agents[0])
+
+	factory = Client{YangPath: source.Dir("./yang"), UserAgent: "my-controller/1.2"}
+	dev, err = factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	agents = nil
+	dev.UiSource()("index", ".html")
+	fc.AssertEqual(t, "my-controller/1.2", agents[0])
+}
+
+func TestClientBootstrapErr(t *testing.T) {
+	ylib := func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/restconf/schema/"):
+			w.Write([]byte(`module bad {`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"module":[{"name":"bad","revision":"0","namespace":"b"}]}`))
+		}
+	}
+	noYlib := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", 404)
+	}
+	stopped := httptest.NewServer(http.HandlerFunc(noYlib))
+	stopped.Close()
+	missing := httptest.NewServer(http.HandlerFunc(noYlib))
+	defer missing.Close()
+	unparsable := httptest.NewServer(http.HandlerFunc(ylib))
+	defer unparsable.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(ylib))
+	defer secure.Close()
+	tests := []struct {
+		url      string
+		expected error
+		module   string
+		pins     []string
+		verify   bool
+	}{
+		{stopped.URL, ErrDeviceUnreachable, "", nil, false},
+		{missing.URL, ErrNoYangLibrary, "", nil, false},
+		{unparsable.URL, ErrSchemaParse, "bad", nil, false},
+		{secure.URL, ErrCertNotPinned, "", []string{strings.Repeat("ab", 32)}, false},
+		{secure.URL, ErrDeviceUntrusted, "", nil, true},
+	}
+	for _, test := range tests {
+		factory := Client{YangPath: source.Dir("./yang"), PinnedCertSHA256: test.pins}
+		if test.verify {
+			factory.DeviceTransport = func(url string, shared *http.Transport) http.RoundTripper {
+				verifying := shared.Clone()
+				verifying.TLSClientConfig = &tls.Config{}
+				return verifying
+			}
+		}
+		_, err := factory.NewDevice(test.url + "/restconf")
+		if test.expected != ErrDeviceUnreachable && errors.Is(err, ErrDeviceUnreachable) {
+			t.Errorf("%s mislabeled as unreachable", test.expected)
+		}
+		if !errors.Is(err, test.expected) {
+			t.Errorf("expected %s got %v", test.expected, err)
+		}
+		var berr *BootstrapError
+		if !errors.As(err, &berr) {
+			t.Fatalf("expected BootstrapError got %T", err)
+		}
+		fc.AssertEqual(t, test.module, berr.Module)
+		fc.AssertEqual(t, true, errors.Unwrap(err) != nil)
+	}
+}
+
+func TestClientSchemaName(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/restconf/schema/car@2020-01-01.yang":
+			w.Write([]byte(`module car { namespace "c"; prefix "c"; revision 2020-01-01; leaf speed { type int32; } }`))
+		case strings.HasPrefix(r.URL.Path, "/restconf/schema/"):
+			http.Error(w, "not found", 404)
+		case r.Method == "GET":
+			w.Write([]byte(`{"module":[{"name":"car","revision":"2020-01-01","namespace":"c"}]}`))
+		}
+	}))
+	defer srv.Close()
+	factory := Client{
+		YangPath: source.Dir("./yang"),
+		SchemaName: func(name string, ext string) (string, error) {
+			return name + "@2020-01-01" + ext, nil
+		},
+	}
+	dev, err := factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, dev.Modules()["car"] != nil)
+}
+
+func TestClientConditionalEdits(t *testing.T) {
+	var header http.Header
+	var ifMatch, ifUnmodified string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		ifMatch = r.Header.Get("If-Match")
+		ifUnmodified = r.Header.Get("If-Unmodified-Since")
+		if ifMatch == `"stale"` {
+			w.WriteHeader(http.StatusPreconditionFailed)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	c.conditionalEdits = true
+	p := requestBuilder{}.path(`container x {}`)
+	ctx := context.Background()
+	lastMod := "Wed, 21 Oct 2015 07:28:00 GMT"
+
+	// server only sends last modified
+	header = http.Header{"Last-Modified": {lastMod}}
+	c.clientDo(ctx, "GET", "", p, nil)
+	_, err := c.clientDo(ctx, "PUT", "", p, strings.NewReader(`{}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, lastMod, ifUnmodified)
+	fc.AssertEqual(t, "", ifMatch)
+
+	// no longer have valid version after edit
+	c.clientDo(ctx, "DELETE", "", p, nil)
+	fc.AssertEqual(t, "", ifUnmodified)
+
+	// etag preferred when server sends both
+	header = http.Header{"Last-Modified": {lastMod}, "Etag": {`"stale"`}}
+	c.clientDo(ctx, "GET", "", p, nil)
+	_, err = c.clientDo(ctx, "DELETE", "", p, nil)
+	fc.AssertEqual(t, `"stale"`, ifMatch)
+	fc.AssertEqual(t, "", ifUnmodified)
+	fc.AssertEqual(t, true, errors.Is(err, ErrConflict))
+	fc.AssertEqual(t, true, errors.Is(err, fc.ConflictError))
+}
+
+func TestClientEditReturn(t *testing.T) {
+	var prefer string
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{"a":"old"}`)
+			return
+		}
+		prefer = r.Header.Get("Prefer")
+		w.WriteHeader(status)
+		if status == 200 {
+			fmt.Fprint(w, `{"a":"NEW"}`)
+		}
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type string; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	c.editReturn = ReturnMinimal
+	edit := func(ctx context.Context) error {
+		b, err := c.Browser("m")
+		fc.AssertEqual(t, nil, err)
+		return b.RootWithContext(ctx).Find("x").UpsertFrom(nodeutil.ReadJSON(`{"a":"new"}`)).LastErr
+	}
+
+	// server honors minimal and sends no body
+	status = 204
+	fc.AssertEqual(t, nil, edit(context.Background()))
+	fc.AssertEqual(t, "return=minimal", prefer)
+
+	var actual string
+	onResult := func(sel node.Selection) error {
+		actual, err = nodeutil.WriteJSON(sel)
+		return err
+	}
+	status = 200
+	fc.AssertEqual(t, nil, edit(WithEditResult(context.Background(), onResult)))
+	fc.AssertEqual(t, "return=representation", prefer)
+	fc.AssertEqual(t, `{"a":"NEW"}`, actual)
+
+	// server ignores preference
+	actual = ""
+	status = 204
+	fc.AssertEqual(t, nil, edit(WithEditResult(context.Background(), onResult)))
+	fc.AssertEqual(t, "", actual)
+}
+
+func TestClientCsrfTokens(t *testing.T) {
+	valid := "t1"
+	var fetches int
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-CSRF-Token") == "Fetch" {
+			fetches++
+			w.Header().Set("X-CSRF-Token", valid)
+			return
+		}
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		sent = append(sent, r.Header.Get("X-CSRF-Token"))
+		if r.Header.Get("X-CSRF-Token") != valid {
+			w.Header().Set("X-CSRF-Token", "Required")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprint(w, string(body))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	c.csrf = &HeaderTokens{}
+	p := requestBuilder{}.path(`container x {}`)
+	ctx := context.Background()
+
+	// safe requests do not need token
+	_, err := c.clientDo(ctx, "GET", "", p, nil)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 0, fetches)
+
+	_, err = c.clientDo(ctx, "PUT", "", p, bytes.NewBufferString(`{}`))
+	fc.AssertEqual(t, nil, err)
+	_, err = c.clientDo(ctx, "DELETE", "", p, nil)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 1, fetches)
+
+	// token expired, new one is fetched and edit is sent again
+	valid = "t2"
+	_, err = c.clientDo(ctx, "PUT", "", p, bytes.NewBufferString(`{}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 2, fetches)
+	fc.AssertEqual(t, "t1 t1 t1 t2", strings.Join(sent, " "))
+}
+
+func TestClientErrorMapper(t *testing.T) {
+	errRetryLater := errors.New("retry later")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	var retryAfter, body string
+	c := newTestClient(srv)
+	c.errorMapper = func(resp *http.Response, msg []byte) error {
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter = resp.Header.Get("Retry-After")
+			body = string(msg)
+			return errRetryLater
+		}
+		return nil
+	}
+	p := requestBuilder{}.path(`container x {}`)
+	_, err := c.clientDo(context.Background(), "GET", "", p, nil)
+	fc.AssertEqual(t, true, errors.Is(err, errRetryLater))
+	fc.AssertEqual(t, "5", retryAfter)
+	fc.AssertEqual(t, "slow down\n", body)
+
+	// not mapped, default applies
+	_, err = c.clientDo(context.Background(), "DELETE", "", p, nil)
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+}
+
+func TestClientGetRaw(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		if r.URL.Path != "/restconf/data/m:x" {
+			http.Error(w, "nope", 404)
+			return
+		}
+		// formatting is kept as is
+		fmt.Fprint(w, "{ \"a\" : 1,\n  \"extra\": true }")
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+	raw, err := c.GetRaw(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "{ \"a\" : 1,\n  \"extra\": true }", string(raw))
+	fc.AssertEqual(t, "application/json", accept)
+
+	rdr, err := c.GetRawStream(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	raw, _ = ioutil.ReadAll(rdr)
+	rdr.Close()
+	fc.AssertEqual(t, "{ \"a\" : 1,\n  \"extra\": true }", string(raw))
+
+	c.address.Data = srv.URL + "/restconf/other/"
+	_, err = c.GetRaw(ctx, "m:x")
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+}
+
+func TestClientNavigationProbe(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+		container y { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.URL.Path != "/restconf/data/m:x" {
+			http.Error(w, "nope", 404)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+	}))
+	defer srv.Close()
+	for _, probe := range []string{"", ProbeOptions, ProbeHead} {
+		methods = nil
+		c := newTestClient(srv, m)
+		c.probe = probe
+		c.conditionalEdits = true
+		find := func(path string) node.Selection {
+			b, err := c.Browser("m")
+			fc.AssertEqual(t, nil, err)
+			return b.Root().Find(path)
+		}
+		x := find("x")
+		fc.AssertEqual(t, nil, x.LastErr)
+		fc.AssertEqual(t, false, x.IsNil())
+		y := find("y")
+		fc.AssertEqual(t, nil, y.LastErr)
+		fc.AssertEqual(t, true, y.IsNil())
+		expected := ProbeOptions
+		if probe == ProbeHead {
+			expected = ProbeHead
+			// existence check also learned resource version
+			fc.AssertEqual(t, `"v1"`, c.validators["m:x"].etag)
+		} else {
+			fc.AssertEqual(t, "", c.validators["m:x"].etag)
+		}
+		fc.AssertEqual(t, expected+" "+expected, strings.Join(methods, " "))
+	}
+}
+
+func TestClientYangLibraryRevisions(t *testing.T) {
+	schema := map[string]string{
+		"car":     `module car { namespace "c"; prefix "c"; revision 2020-01-01; feature turbo; leaf speed { type int32; } }`,
+		"car-dev": `module car-dev { namespace "d"; prefix "d"; revision 2020-02-02; }`,
+	}
+	serveSchema := func(w http.ResponseWriter, r *http.Request) bool {
+		if !strings.HasPrefix(r.URL.Path, "/restconf/schema/") {
+			return false
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/restconf/schema/"), ".yang")
+		if s, found := schema[name]; found {
+			w.Write([]byte(s))
+		} else {
+			http.Error(w, "not found", 404)
+		}
+		return true
+	}
+	rev2016 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case serveSchema(w, r):
+		case r.Method == "GET" && r.URL.Path == "/restconf/data/ietf-yang-library:modules-state/module":
+			w.Write([]byte(`{"module":[
+				{"name":"car","revision":"2020-01-01","namespace":"c","feature":["turbo"],"conformance-type":"implement",
+					"deviation":[{"name":"car-dev","revision":"2020-02-02"}]},
+				{"name":"car-dev","revision":"2020-02-02","namespace":"d","conformance-type":"implement"}
+			]}`))
+		}
+	}))
+	defer rev2016.Close()
+	rev2019 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case serveSchema(w, r):
+		case strings.Contains(r.URL.Path, "modules-state"):
+			http.Error(w, "not found", 404)
+		case r.Method == "GET" && r.URL.Path == "/restconf/data/ietf-yang-library:yang-library":
+			w.Write([]byte(`{"ietf-yang-library:yang-library":{"module-set":[{"name":"all","module":[
+				{"name":"car","revision":"2020-01-01","namespace":"c","feature":["turbo"],"deviation":["car-dev"]},
+				{"name":"car-dev","revision":"2020-02-02","namespace":"d"}
+			]}]}}`))
+		}
+	}))
+	defer rev2019.Close()
+	factory := Client{YangPath: source.Dir("./yang")}
+	var infos [][]ModuleInfo
+	for _, srv := range []*httptest.Server{rev2016, rev2019} {
+		dev, err := factory.NewDevice(srv.URL + "/restconf")
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, 2, len(dev.Modules()))
+		fc.AssertEqual(t, true, dev.Modules()["car"] != nil)
+		fc.AssertEqual(t, true, dev.Modules()["car-dev"] != nil)
+		infos = append(infos, dev.(*client).ModuleInfo())
+	}
+	fc.AssertEqual(t, fmt.Sprintf("%+v", infos[0]), fmt.Sprintf("%+v", infos[1]))
+	fc.AssertEqual(t, "turbo", infos[1][0].Features[0])
+	fc.AssertEqual(t, "car-dev", infos[1][0].Deviations[0].Name)
+	fc.AssertEqual(t, "2020-02-02", infos[1][0].Deviations[0].Revision)
+}
+
+func TestClientRemoteDeviatedSchema(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/restconf/schema/car.yang":
+			// as device implements it
+			w.Write([]byte(`module car { namespace "car"; prefix "car"; revision 0; container engine { leaf deviated { type string; } } }`))
+		case "/restconf/schema/car-dev.yang":
+			w.Write([]byte(`module car-dev { namespace "d"; prefix "d"; revision 0; }`))
+		case "/restconf/data/ietf-yang-library:modules-state/module":
+			w.Write([]byte(`{"module":[
+				{"name":"car","revision":"0","namespace":"car","deviation":[{"name":"car-dev","revision":"0"}]},
+				{"name":"car-dev","revision":"0","namespace":"d"}
+			]}`))
+		default:
+			http.Error(w, "not found", 404)
+		}
+	}))
+	defer srv.Close()
+	ypath := source.Path("./testdata:./yang")
+	hasDeviated := func(dev device.Device) bool {
+		engine := meta.Find(dev.Modules()["car"], "engine").(meta.HasDataDefinitions)
+		return meta.Find(engine, "deviated") != nil
+	}
+
+	// local copy
+	factory := Client{YangPath: ypath}
+	dev, err := factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, hasDeviated(dev))
+
+	// device's copy
+	factory = Client{YangPath: ypath, RemoteDeviatedSchema: true}
+	dev, err = factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, hasDeviated(dev))
+}
+
+func TestClientConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/restconf/schema/car.yang":
+			w.Write([]byte(`module car { namespace "c"; prefix "c"; revision 0; container x { leaf speed { type int32; } } }`))
+		case r.Method == "OPTIONS":
+			w.Header().Set("Allow", "GET, PUT, OPTIONS")
+		default:
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `{"speed":10}`)
+		}
+	}))
+	defer srv.Close()
+	schema := httpStream{client: srv.Client(), url: srv.URL + "/restconf/schema/"}
+	c := newTestClient(srv)
+	c.schemaPath = schema.OpenStream
+	c.conditionalEdits = true
+	// run w/-race to be useful
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b, err := c.Browser("car")
+			if err != nil {
+				errs <- err
+				return
+			}
+			actual, err := nodeutil.WriteJSON(b.Root().Find("x"))
+			if err != nil {
+				errs <- err
+				return
+			}
+			if actual != `{"speed":10}` {
+				errs <- fmt.Errorf("unexpected %s", actual)
+			}
+			for range c.Modules() {
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	fc.AssertEqual(t, 1, len(c.Modules()))
+}
+
+func TestClientActionUrl(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		rpc reboot {}
+		container x {
+			action reset {}
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = append(posted, r.URL.Path)
+	}))
+	defer srv.Close()
+	tests := []struct {
+		actionUrl string
+		reboot    string
+		reset     string
+	}{
+		{"", "/restconf/data/m:reboot", "/restconf/data/m:x/reset"},
+		{ActionUrlData, "/restconf/data/m:reboot", "/restconf/data/m:x/reset"},
+		{ActionUrlOperations, "/restconf/operations/m:reboot", "/restconf/operations/m:x/reset"},
+		{ActionUrlAuto, "/restconf/operations/m:reboot", "/restconf/data/m:x/reset"},
+	}
+	ctx := context.Background()
+	for _, test := range tests {
+		posted = nil
+		c := newTestClient(srv, m)
+		c.actionUrl = test.actionUrl
+		_, _, err = c.ActionRaw(ctx, "m:reboot", nil)
+		fc.AssertEqual(t, nil, err)
+		_, _, err = c.ActionRaw(ctx, "m:x/reset", nil)
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.reboot+" "+test.reset, strings.Join(posted, " "))
+	}
+}
+
+func TestClientProtocol(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"a":1}`)
+	})
+	srv := httptest.NewUnstartedServer(handler)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+	tests := []struct {
+		factory  *Client
+		expected string
+	}{
+		{&Client{}, "HTTP/2.0"},
+		{&Client{ForceHTTP1: true}, "HTTP/1.1"},
+	}
+	for _, test := range tests {
+		factory := test.factory
+		c := newTestClient(srv, m)
+		c.client = &http.Client{Transport: factory.newTransport()}
+		fc.AssertEqual(t, "", c.Protocol())
+		_, err := c.GetRaw(context.Background(), "m:x")
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, c.Protocol())
+	}
+}
+
+func TestClientRequestError(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.address.DeviceId = "dev1"
+	_, err = c.GetRaw(context.Background(), "m:x")
+	var reqErr *RequestError
+	fc.AssertEqual(t, true, errors.As(err, &reqErr))
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+	fc.AssertEqual(t, "GET", reqErr.Method)
+	fc.AssertEqual(t, "dev1", reqErr.Device)
+	fc.AssertEqual(t, true, strings.HasPrefix(reqErr.Url, srv.URL+"/restconf/data/m:x"))
+}
+
+func TestRedactUrl(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"http://h/restconf/data/m:x", "http://h/restconf/data/m:x"},
+		{"http://joe:secret@h/restconf/data/m:x", "http://joe:REDACTED@h/restconf/data/m:x"},
+		{"http://h/x?depth=1&access_token=abc", "http://h/x?access_token=REDACTED&depth=1"},
+		{"http://h/x?api-key=abc", "http://h/x?api-key=REDACTED"},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, redactUrl(test.url))
+	}
+}
+
+func TestClientReadOnly(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		rpc reboot {}
+		container x { leaf a { type int32; } }
+		list y { key id; leaf id { type string; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		fmt.Fprint(w, `{"a":1}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.readOnly = true
+	ctx := context.Background()
+	_, _, err = c.ActionRaw(ctx, "m:reboot", nil)
+	fc.AssertEqual(t, true, errors.Is(err, ErrReadOnly))
+	_, err = c.Transaction(ctx)
+	fc.AssertEqual(t, true, errors.Is(err, ErrReadOnly))
+
+	b, err := c.Browser("m")
+	fc.AssertEqual(t, nil, err)
+	err = b.Root().Find("x").UpsertFrom(nodeutil.ReadJSON(`{"a":2}`)).LastErr
+	fc.AssertEqual(t, true, errors.Is(err, ErrReadOnly))
+	err = b.Root().InsertFrom(nodeutil.ReadJSON(`{"y":[{"id":"k"}]}`)).LastErr
+	fc.AssertEqual(t, true, errors.Is(err, ErrReadOnly))
+	// Selection.Delete does not return node's delete error
+	b.Root().Find("x").Delete()
+	_, err = c.clientDo(ctx, "DELETE", "", b.Root().Find("x").Path, nil)
+	fc.AssertEqual(t, true, errors.Is(err, ErrReadOnly))
+	for _, method := range methods {
+		fc.AssertEqual(t, false, isUnsafeMethod(method))
+	}
+
+	methods = nil
+	raw, err := c.GetRaw(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"a":1}`, string(raw))
+	fc.AssertEqual(t, "GET", strings.Join(methods, " "))
+}
+
+func TestClientTrailingSlash(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		fmt.Fprint(w, `{"a":1}`)
+	}))
+	defer srv.Close()
+	tests := []struct {
+		trailingSlash bool
+		expected      string
+	}{
+		{false, "/restconf/data/m:x"},
+		{true, "/restconf/data/m:x/"},
+	}
+	ctx := context.Background()
+	for _, test := range tests {
+		c := newTestClient(srv, m)
+		c.trailingSlash = test.trailingSlash
+		for _, path := range []string{"m:x", "m:x/", "M:x"} {
+			requested = nil
+			_, err := c.GetRaw(ctx, path)
+			fc.AssertEqual(t, nil, err)
+			fc.AssertEqual(t, test.expected, strings.Join(requested, " "))
+		}
+	}
+}
+
+func TestClientDuplicateKeys(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			list y { key "id"; leaf id { type int32; } leaf v { type string; } }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"y":[{"id":1,"v":"a"},{"id":2,"v":"b"},{"id":1,"v":"c"}]}`)
+	}))
+	defer srv.Close()
+	tests := []struct {
+		policy   string
+		expected string
+	}{
+		{"", `{"y":[{"id":1,"v":"a"},{"id":2,"v":"b"},{"id":1,"v":"c"}]}`},
+		{DuplicateKeysKeepFirst, `{"y":[{"id":1,"v":"a"},{"id":2,"v":"b"}]}`},
+		{DuplicateKeysKeepLast, `{"y":[{"id":2,"v":"b"},{"id":1,"v":"c"}]}`},
+		{DuplicateKeysStrict, ""},
+	}
+	for _, test := range tests {
+		c := newTestClient(srv, m)
+		c.duplicateKeys = test.policy
+		b := node.NewBrowser(m, c.newClientNode().node())
+		actual, err := nodeutil.WriteJSON(b.Root().Find("x"))
+		if test.policy == DuplicateKeysStrict {
+			fc.AssertEqual(t, true, errors.Is(err, ErrDuplicateKey))
+			fc.AssertEqual(t, "list has duplicate key. y=1", err.Error())
+			continue
+		}
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, actual)
+	}
+}
+
+func TestClientDatastoreModules(t *testing.T) {
+	schema := map[string]string{
+		"car":       `module car { namespace "c"; prefix "c"; revision 0; leaf speed { type int32; } }`,
+		"car-state": `module car-state { namespace "s"; prefix "s"; revision 0; leaf temp { type int32; config false; } }`,
+	}
+	var reqPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/restconf/schema/"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/restconf/schema/"), ".yang")
+			w.Write([]byte(schema[name]))
+		case strings.Contains(r.URL.Path, "modules-state"):
+			http.Error(w, "not found", 404)
+		case r.URL.Path == "/restconf/data/ietf-yang-library:yang-library":
+			w.Write([]byte(`{"ietf-yang-library:yang-library":{
+				"module-set":[
+					{"name":"config","module":[{"name":"car","revision":"0","namespace":"c"}]},
+					{"name":"state","module":[{"name":"car-state","revision":"0","namespace":"s"}]}
+				],
+				"schema":[
+					{"name":"config-schema","module-set":["config"]},
+					{"name":"state-schema","module-set":["config","state"]}
+				],
+				"datastore":[
+					{"name":"ietf-datastores:running","schema":"config-schema"},
+					{"name":"ietf-datastores:operational","schema":"state-schema"}
+				]
+			}}`))
+		default:
+			reqPath = r.URL.Path
+			w.Write([]byte(`{"temp":99}`))
+		}
+	}))
+	defer srv.Close()
+	factory := Client{YangPath: source.Dir("./yang")}
+	dev, err := factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	c := dev.(*client)
+	fc.AssertEqual(t, 2, len(c.Modules()))
+	running := c.DatastoreModules("ietf-datastores:running")
+	fc.AssertEqual(t, 1, len(running))
+	fc.AssertEqual(t, true, running["car"] != nil)
+	operational := c.DatastoreModules("ietf-datastores:operational")
+	fc.AssertEqual(t, 2, len(operational))
+	fc.AssertEqual(t, true, operational["car-state"] != nil)
+	fc.AssertEqual(t, 0, len(c.DatastoreModules("ietf-datastores:intended")))
+
+	_, err = c.DatastoreBrowser("ietf-datastores:running", "car-state")
+	fc.AssertEqual(t, true, errors.Is(err, fc.NotFoundError))
+
+	b, err := c.DatastoreBrowser("ietf-datastores:operational", "car-state")
+	fc.AssertEqual(t, nil, err)
+	temp, err := b.Root().Get("temp")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 99, temp)
+	fc.AssertEqual(t, "/restconf/ds/ietf-datastores:operational/car-state:", reqPath)
+}
+
+func TestClientMaxDepth(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container a {
+			container b { container c { container d { leaf v { type int32; } } } }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var query string
+	honorsDepth := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if honorsDepth {
+			fmt.Fprint(w, `{"b":{"c":{}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"b":{"c":{"d":{"v":1}}}}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.maxDepth = 2
+	read := func() (string, error) {
+		b := node.NewBrowser(m, c.newClientNode().node())
+		return nodeutil.WriteJSON(b.Root().Find("a"))
+	}
+
+	actual, err := read()
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"b":{"c":{}}}`, actual)
+	fc.AssertEqual(t, "depth=2", query)
+
+	// device ignores depth
+	honorsDepth = false
+	_, err = read()
+	fc.AssertEqual(t, true, errors.Is(err, ErrDepthExceeded))
+}
@@ -0,0 +1,99 @@
+package restconf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// readGroup coalesces identical GETs that are in flight at same time into a
+// single request to device.  Every caller gets it's own copy of response or
+// same error.  Response body is read into memory so it can be shared.  nil
+// group coalesces nothing, zero group is ready to use.
+type readGroup struct {
+	lock  sync.Mutex
+	calls map[string]*readCall
+}
+
+type readCall struct {
+	done    chan struct{}
+	waiters int
+	resp    *http.Response
+	body    []byte
+	err     error
+}
+
+func newReadGroup(enabled bool) *readGroup {
+	if !enabled {
+		return nil
+	}
+	return &readGroup{calls: make(map[string]*readCall)}
+}
+
+// coalesced is whether req can share response w/other requests
+func (self *readGroup) coalesced(req *http.Request) bool {
+	return self != nil && req.Method == "GET" && req.Header.Get("Range") == ""
+}
+
+func readKey(req *http.Request) string {
+	return cacheKey(req) + " " + req.Header.Get("If-None-Match") + " " + req.Header.Get("If-Modified-Since") + " " + req.Header.Get("If-Match")
+}
+
+// do sends req w/fetch unless an identical request is already in flight in
+// which case it waits for that response.  If first caller's context ends
+// before device responds, all callers waiting on it get that error.
+func (self *readGroup) do(req *http.Request, fetch func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	key := readKey(req)
+	self.lock.Lock()
+	if self.calls == nil {
+		self.calls = make(map[string]*readCall)
+	}
+	if call, found := self.calls[key]; found {
+		call.waiters++
+		self.lock.Unlock()
+		select {
+		case <-call.done:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return call.response(req)
+	}
+	call := &readCall{done: make(chan struct{})}
+	self.calls[key] = call
+	self.lock.Unlock()
+
+	call.resp, call.err = fetch(req)
+	if call.err == nil {
+		call.body, call.err = ioutil.ReadAll(call.resp.Body)
+		call.resp.Body.Close()
+	}
+	self.lock.Lock()
+	delete(self.calls, key)
+	self.lock.Unlock()
+	close(call.done)
+	return call.response(req)
+}
+
+func (self *readCall) response(req *http.Request) (*http.Response, error) {
+	if self.err != nil {
+		return nil, self.err
+	}
+	resp := *self.resp
+	resp.Header = self.resp.Header.Clone()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(self.body))
+	resp.ContentLength = int64(len(self.body))
+	resp.Request = req
+	return &resp, nil
+}
+
+// waiting is how many callers are waiting on another's request
+func (self *readGroup) waiting() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	n := 0
+	for _, call := range self.calls {
+		n += call.waiters
+	}
+	return n
+}
@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientCoalesceReads(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var requests int32
+	var status int
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		w.WriteHeader(status)
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.reads = newReadGroup(true)
+	const n = 10
+	readAll := func() ([]string, []error) {
+		bodies := make([]string, n)
+		errs := make([]error, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				body, err := c.GetRaw(context.Background(), "m:x")
+				bodies[i], errs[i] = string(body), err
+			}(i)
+		}
+		for c.reads.waiting() < n-1 {
+			time.Sleep(time.Millisecond)
+		}
+		release <- struct{}{}
+		wg.Wait()
+		return bodies, errs
+	}
+
+	status = http.StatusOK
+	bodies, errs := readAll()
+	fc.AssertEqual(t, int32(1), atomic.LoadInt32(&requests))
+	for i := 0; i < n; i++ {
+		fc.AssertEqual(t, nil, errs[i])
+		fc.AssertEqual(t, `{"a":1}`, bodies[i])
+	}
+
+	status = http.StatusNotFound
+	_, errs = readAll()
+	fc.AssertEqual(t, int32(2), atomic.LoadInt32(&requests))
+	for i := 0; i < n; i++ {
+		fc.AssertEqual(t, true, errors.Is(errs[i], fc.NotFoundError))
+	}
+
+	// reads not in flight at same time are not shared
+	close(release)
+	status = http.StatusOK
+	for i := 0; i < 2; i++ {
+		_, err := c.GetRaw(context.Background(), "m:x")
+		fc.AssertEqual(t, nil, err)
+	}
+	fc.AssertEqual(t, int32(4), atomic.LoadInt32(&requests))
+	fc.AssertEqual(t, 0, c.reads.waiting())
+}
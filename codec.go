@@ -0,0 +1,84 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// Codec is the wire format used to exchange data with a RESTCONF server. Client
+// picks one codec per device and uses it for both reading responses and
+// writing edits.
+type Codec interface {
+
+	// MimeType is used in both Content-Type and Accept headers
+	MimeType() string
+
+	// Reader converts response body into a node to read from
+	Reader(in io.Reader) node.Node
+
+	// Write serializes selection into request body
+	Write(out io.Writer, sel node.Selection) error
+}
+
+// JSONCodec is the default, application/yang-data+json but sent as plain
+// application/json for compatibility with older servers
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MimeType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Reader(in io.Reader) node.Node {
+	return readJSONIO(in)
+}
+
+func (jsonCodec) Write(out io.Writer, sel node.Selection) error {
+	js := &nodeutil.JSONWtr{Out: out}
+	return sel.InsertInto(js.Node()).LastErr
+}
+
+// CanonicalJSONCodec writes edits and actions as JSON w/object members sorted
+// by name and no insignificant whitespace so same data is always same bytes
+// such as when payloads are signed or compared.  Reading is same as
+// JSONCodec.
+var CanonicalJSONCodec Codec = canonicalJSONCodec{}
+
+type canonicalJSONCodec struct {
+	jsonCodec
+}
+
+func (self canonicalJSONCodec) Write(out io.Writer, sel node.Selection) error {
+	var buf bytes.Buffer
+	if err := self.jsonCodec.Write(&buf, sel); err != nil {
+		return err
+	}
+	// encoding/json sorts map keys and numbers are kept exactly as written
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+	var data interface{}
+	if err := dec.Decode(&data); err != nil {
+		return err
+	}
+	var canonical bytes.Buffer
+	enc := json.NewEncoder(&canonical)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+	// drop newline encoder adds
+	_, err := out.Write(bytes.TrimSuffix(canonical.Bytes(), []byte("\n")))
+	return err
+}
+
+func codecOrDefault(c Codec) Codec {
+	if c == nil {
+		return JSONCodec
+	}
+	return c
+}
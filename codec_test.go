@@ -0,0 +1,95 @@
+package restconf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestCanonicalJSONCodec(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x { namespace "urn:x"; prefix "x"; revision 0;
+	container car {
+		leaf make { type string; }
+		leaf speed { type decimal64; }
+		list wheel {
+			key "pos";
+			leaf psi { type int32; }
+			leaf pos { type int32; }
+		}
+		container engine {
+			leaf cyl { type int32; }
+		}
+	}
+}`)
+	fc.AssertEqual(t, nil, err)
+	data := `{"car":{
+		"wheel":[{"pos":1,"psi":30},{"pos":2,"psi":32}],
+		"make":"a<b & c",
+		"engine":{"cyl":6},
+		"speed":1.5
+	}}`
+	write := func(c Codec) string {
+		b := node.NewBrowser(m, nodeutil.ReadJSON(data))
+		var buf bytes.Buffer
+		fc.AssertEqual(t, nil, c.Write(&buf, b.Root().Find("car")))
+		return buf.String()
+	}
+	expected := `{"engine":{"cyl":6},"make":"a<b & c","speed":1.5,"wheel":[{"pos":1,"psi":30},{"pos":2,"psi":32}]}`
+	for i := 0; i < 5; i++ {
+		fc.AssertEqual(t, expected, write(CanonicalJSONCodec))
+	}
+	fc.AssertEqual(t, JSONCodec.MimeType(), CanonicalJSONCodec.MimeType())
+}
+
+func TestJSONCodecNumbers(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x { namespace "urn:x"; prefix "x"; revision 0;
+	container counters {
+		leaf in-octets { type uint64; }
+		leaf drops { type int64; }
+		leaf-list samples { type uint64; }
+		leaf rate { type decimal64; }
+		list queue {
+			key "id";
+			leaf id { type uint64; }
+		}
+	}
+}`)
+	fc.AssertEqual(t, nil, err)
+	// all above 2^53 where float64 loses precision
+	data := `{"counters":{"in-octets":18446744073709551615,"drops":-9007199254740993,"samples":[9007199254740993,1],"rate":1.5,"queue":[{"id":9007199254740995}]}}`
+	b := node.NewBrowser(m, JSONCodec.Reader(strings.NewReader(data)))
+	actual, err := nodeutil.WriteJSON(b.Root())
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, data, actual)
+
+	v, err := b.Root().Find("counters").Get("in-octets")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, uint64(18446744073709551615), v)
+}
+
+func TestDecodeEventNumbers(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x { namespace "urn:x"; prefix "x"; revision 0;
+	notification overflow {
+		leaf in-octets { type uint64; }
+	}
+}`)
+	fc.AssertEqual(t, nil, err)
+	notif := meta.Find(m, "overflow")
+	frames := []string{
+		`{"in-octets":18446744073709551615}`,
+		`{"ietf-restconf:notification":{"eventTime":"2023-04-05T06:07:08Z","x:overflow":{"in-octets":18446744073709551615}}}`,
+	}
+	for _, frame := range frames {
+		n := decodeEvent(notif, sseFrame{data: []byte(frame)}, nil)
+		sel := node.Selection{Node: n, Path: node.NewRootPath(notif), Constraints: &node.Constraints{}}
+		v, err := sel.Get("in-octets")
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, uint64(18446744073709551615), v)
+	}
+}
@@ -0,0 +1,183 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+const restconfData = "ietf-restconf:data"
+
+// ExportConfig writes config, w/o defaults, of every module in Modules as a
+// single RFC 8040 ietf-restconf:data JSON document such as for a backup
+// that ImportConfig can restore.  Document is written to w as each module is
+// read, rather than returned, so only one top level node of device's data is
+// held in memory at a time.  See ExportConfigBytes for whole document.
+func (self *client) ExportConfig(ctx context.Context, w io.Writer) error {
+	mods := self.Modules()
+	if _, err := fmt.Fprintf(w, `{%q:{`, restconfData); err != nil {
+		return err
+	}
+	first := true
+	for _, name := range sortedModuleNames(mods) {
+		m := mods[name]
+		if !hasConfig(m) {
+			continue
+		}
+		params := editReadParams(DefaultsTrim, self.legacyConfig, true)
+		resp, err := self.send(ctx, "GET", params, node.NewRootPath(m), nil)
+		if errors.Is(err, fc.NotFoundError) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		err = copyTopLevel(w, m.Ident(), resp.Body, &first)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("%s. %w", name, err)
+		}
+	}
+	_, err := io.WriteString(w, "}}")
+	return err
+}
+
+// ExportConfigBytes is like ExportConfig for devices w/small enough config
+// to hold whole document in memory.
+func (self *client) ExportConfigBytes(ctx context.Context) ([]byte, error) {
+	var doc bytes.Buffer
+	if err := self.ExportConfig(ctx, &doc); err != nil {
+		return nil, err
+	}
+	return doc.Bytes(), nil
+}
+
+// copyTopLevel copies each member of module's JSON object qualified w/
+// module name as they must be in ietf-restconf:data
+func copyTopLevel(out io.Writer, module string, in io.Reader, first *bool) error {
+	dec := json.NewDecoder(in)
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return fmt.Errorf("expected object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		ident := tok.(string)
+		if !strings.ContainsRune(ident, ':') {
+			ident = module + ":" + ident
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		sep := ","
+		if *first {
+			sep = ""
+		}
+		*first = false
+		if _, err := fmt.Fprintf(out, "%s%q:%s", sep, ident, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportConfig merges config in document made by ExportConfig into device's
+// config as one PATCH per module, in module name order, so config on device
+// that is not in document is left as is.  Patch format is picked as w/
+// PatchEdits.  If device accepts no patch format, module is sent w/PUT which
+// RFC 8040 devices treat as replacing all of module's config.
+func (self *client) ImportConfig(ctx context.Context, doc io.Reader) error {
+	var data map[string]map[string]json.RawMessage
+	if err := json.NewDecoder(doc).Decode(&data); err != nil {
+		return fmt.Errorf("%w. %s", fc.BadRequestError, err)
+	}
+	top, found := data[restconfData]
+	if !found {
+		return fmt.Errorf("%w. expected %s document", fc.BadRequestError, restconfData)
+	}
+	byModule := make(map[string]map[string]json.RawMessage)
+	for ident, value := range top {
+		colon := strings.IndexRune(ident, ':')
+		if colon <= 0 {
+			return fmt.Errorf("%w. %s is not module qualified", fc.BadRequestError, ident)
+		}
+		module := ident[:colon]
+		if byModule[module] == nil {
+			byModule[module] = make(map[string]json.RawMessage)
+		}
+		byModule[module][ident[colon+1:]] = value
+	}
+	names := make([]string, 0, len(byModule))
+	for name := range byModule {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m, err := self.module(name)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(byModule[name])
+		if err != nil {
+			return err
+		}
+		if err := self.mergeModule(ctx, m, payload); err != nil {
+			return fmt.Errorf("%s. %w", name, err)
+		}
+	}
+	return nil
+}
+
+// mergeModule sends config of module as a patch even if client was not made
+// w/PatchEdits
+func (self *client) mergeModule(ctx context.Context, m *meta.Module, payload []byte) error {
+	p := node.NewRootPath(m)
+	target := m.Ident() + ":" + self.targetPath(p)
+	method, contentType, body := "PUT", codecOrDefault(self.codec).MimeType(), io.Reader(bytes.NewReader(payload))
+	if !self.readOnly {
+		method, contentType, body = self.patchEdit(ctx, target, p, body)
+	}
+	resp, err := self.sendRequest(ctx, request{
+		method:      method,
+		url:         self.resourceUrl(self.dataUrl(ctx), target),
+		target:      target,
+		contentType: contentType,
+		payload:     body,
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func sortedModuleNames(mods map[string]*meta.Module) []string {
+	names := make([]string, 0, len(mods))
+	for name := range mods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasConfig is whether module has any config data at top level
+func hasConfig(m *meta.Module) bool {
+	for _, def := range m.DataDefinitions() {
+		if c, valid := def.(meta.HasConfig); valid && c.Config() {
+			return true
+		}
+	}
+	return false
+}
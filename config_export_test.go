@@ -0,0 +1,95 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/restconf/testdata"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestClientExportImportConfig(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	m := parser.RequireModule(ypath, "bird")
+	birdServer := func(birds map[string]*testdata.Bird) *httptest.Server {
+		d := device.New(ypath)
+		d.AddBrowser(node.NewBrowser(m, testdata.BirdNode(birds)))
+		return httptest.NewServer(NewServer(d))
+	}
+	factory := Client{YangPath: ypath}
+	ctx := context.Background()
+
+	from := make(map[string]*testdata.Bird)
+	fromSrv := birdServer(from)
+	defer fromSrv.Close()
+	b := node.NewBrowser(m, testdata.BirdNode(from))
+	err := b.Root().UpsertFrom(nodeutil.ReadJSON(`{"bird":[
+		{"name":"swift","wingspan":40,"species":{"name":"apus","class":"aves"}},
+		{"name":"owl","wingspan":90}
+	]}`)).LastErr
+	fc.AssertEqual(t, nil, err)
+	fromDev, err := factory.NewDevice(fromSrv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	// loads bird among device's modules
+	_, err = fromDev.Browser("bird")
+	fc.AssertEqual(t, nil, err)
+	var doc bytes.Buffer
+	err = fromDev.(*client).ExportConfig(ctx, &doc)
+	fc.AssertEqual(t, nil, err)
+	var exported map[string]map[string]json.RawMessage
+	fc.AssertEqual(t, nil, json.Unmarshal(doc.Bytes(), &exported))
+	// fc-restconf config is also there but varies w/debug logging
+	fc.AssertEqual(t, `[{"name":"owl","wingspan":90},{"name":"swift","wingspan":40,"species":{"name":"apus","class":"aves"}}]`,
+		string(exported["ietf-restconf:data"]["bird:bird"]))
+	whole, err := fromDev.(Device).ExportConfigBytes(ctx)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, doc.String(), string(whole))
+
+	to := map[string]*testdata.Bird{
+		"robin": {Name: "robin", Wingspan: 20},
+	}
+	toSrv := birdServer(to)
+	defer toSrv.Close()
+	toDev, err := factory.NewDevice(toSrv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	err = toDev.(*client).ImportConfig(ctx, &doc)
+	fc.AssertEqual(t, nil, err)
+	// merged so birds only on device stay
+	fc.AssertEqual(t, 3, len(to))
+	fc.AssertEqual(t, 20, to["robin"].Wingspan)
+	fc.AssertEqual(t, 40, to["swift"].Wingspan)
+	fc.AssertEqual(t, "aves", to["swift"].Species.Class)
+	fc.AssertEqual(t, 90, to["owl"].Wingspan)
+}
+
+func TestClientImportConfigPatches(t *testing.T) {
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.Header().Set("Accept-Patch", "application/yang-data+json")
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		sent = append(sent, fmt.Sprint(r.Method, " ", r.URL.Path, " ", string(body)))
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix ""; revision 0; container x { leaf a { type int32; } } }`)
+	fc.AssertEqual(t, nil, err)
+	// merged even w/o PatchEdits
+	c := newTestClient(srv, m)
+	err = c.ImportConfig(context.Background(), strings.NewReader(`{"ietf-restconf:data":{"m:x":{"a":1}}}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `PATCH /restconf/data/m: {"x":{"a":1}}`, strings.Join(sent, "\n"))
+}
@@ -0,0 +1,138 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// CsrfTokens supplies anti-CSRF tokens for devices behind web gateways that
+// require one on unsafe requests (PUT, POST, PATCH and DELETE).  Token is
+// requested before each unsafe request and invalidated when device rejects
+// it.
+type CsrfTokens interface {
+
+	// Token for device at baseUrl as header name and value, fetching a new
+	// one if there isn't one already
+	Token(ctx context.Context, hc *http.Client, baseUrl string) (header string, value string, err error)
+
+	// Invalidate token for device at baseUrl because device rejected it
+	Invalidate(baseUrl string)
+}
+
+// HeaderTokens fetches token with a GET and reads it from a response
+// header.  Common convention is to send X-CSRF-Token: Fetch and have server
+// reply w/token in X-CSRF-Token.
+type HeaderTokens struct {
+
+	// Where to GET token relative to device's base url such as "data/" or
+	// a full url.  Empty is device's base url.
+	Path string
+
+	// Header both in request and response.  Default is X-CSRF-Token
+	Header string
+
+	tokens map[string]string
+	lock   sync.Mutex
+}
+
+func (self *HeaderTokens) header() string {
+	if self.Header == "" {
+		return "X-CSRF-Token"
+	}
+	return self.Header
+}
+
+func (self *HeaderTokens) Token(ctx context.Context, hc *http.Client, baseUrl string) (string, string, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if token, found := self.tokens[baseUrl]; found {
+		return self.header(), token, nil
+	}
+	tokenUrl := self.Path
+	if !strings.Contains(tokenUrl, "://") {
+		tokenUrl = baseUrl + tokenUrl
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", tokenUrl, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set(self.header(), "Fetch")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	resp.Body.Close()
+	token := resp.Header.Get(self.header())
+	if token == "" {
+		return "", "", fmt.Errorf("no %s header in response from %s", self.header(), tokenUrl)
+	}
+	if self.tokens == nil {
+		self.tokens = make(map[string]string)
+	}
+	self.tokens[baseUrl] = token
+	return self.header(), token, nil
+}
+
+func (self *HeaderTokens) Invalidate(baseUrl string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	delete(self.tokens, baseUrl)
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case "PUT", "POST", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// doCsrf attaches token to unsafe requests.  If device rejects token request
+// is sent once more w/a new token when request body can be replayed.
+func (self *client) doCsrf(req *http.Request) (*http.Response, error) {
+	if self.csrf == nil || !isUnsafeMethod(req.Method) {
+		return self.client.Do(req)
+	}
+	for attempt := 0; ; attempt++ {
+		header, token, err := self.csrf.Token(req.Context(), self.client, self.address.Base)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(header, token)
+		resp, err := self.client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusForbidden {
+			return resp, err
+		}
+		msg, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(msg))
+		if !csrfRejected(resp, header, msg) {
+			return resp, nil
+		}
+		self.csrf.Invalidate(self.address.Base)
+		if attempt > 0 || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+		fc.Debug.Printf("csrf token rejected, retrying %s %s", req.Method, redactUrl(req.URL.String()))
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// csrfRejected distinguishes a rejected token from any other reason for 403
+func csrfRejected(resp *http.Response, header string, msg []byte) bool {
+	if strings.EqualFold(resp.Header.Get(header), "Required") {
+		return true
+	}
+	return bytes.Contains(bytes.ToLower(msg), []byte("csrf"))
+}
@@ -0,0 +1,43 @@
+package restconf
+
+import (
+	"fmt"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// DatastoreModules are modules in schema of NMDA datastore such as
+// ietf-datastores:operational as device declared them in 2019 revision of
+// ietf-yang-library.  Devices that do not declare a schema for each
+// datastore have same modules in all datastores, see Modules.
+func (self *client) DatastoreModules(datastore string) map[string]*meta.Module {
+	if self.datastoreModules == nil {
+		return self.Modules()
+	}
+	mods := make(map[string]*meta.Module, len(self.datastoreModules[datastore]))
+	for name, m := range self.datastoreModules[datastore] {
+		mods[name] = m
+	}
+	return mods
+}
+
+// DatastoreBrowser is like Browser but module is from schema of datastore
+// and requests go to datastore unless context says otherwise.
+//
+//	b, err := dev.DatastoreBrowser("ietf-datastores:operational", "ietf-interfaces")
+func (self *client) DatastoreBrowser(datastore string, module string) (*node.Browser, error) {
+	var m *meta.Module
+	if self.datastoreModules == nil {
+		var err error
+		if m, err = self.module(module); err != nil {
+			return nil, err
+		}
+	} else if m = self.datastoreModules[datastore][module]; m == nil {
+		return nil, fmt.Errorf("%w. %s is not in schema of datastore %s", fc.NotFoundError, module, datastore)
+	}
+	d := self.newClientNode()
+	d.datastore = datastore
+	return node.NewBrowser(m, d.node()), nil
+}
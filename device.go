@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// Device is a remote device w/requests only RESTCONF can make.  Devices
+// from NewDevice implement it.
+//
+//	dev, err := factory.NewDevice(url)
+//	sub, err := dev.(restconf.Device).Subscribe(ctx, "car:update")
+type Device interface {
+	device.Device
+
+	// schema
+	ModuleInfo() []ModuleInfo
+	PreloadModules(ctx context.Context, names []string) error
+	MountBrowser(path string, module string) (*node.Browser, error)
+	DatastoreModules(datastore string) map[string]*meta.Module
+	DatastoreBrowser(datastore string, module string) (*node.Browser, error)
+
+	// capabilities learned from device's responses
+	AllowedMethods(path string) []string
+	AcceptedPatch(path string) []string
+	Protocol() string
+	ServerTime(ctx context.Context) (time.Time, time.Duration, error)
+
+	// reads
+	GetRaw(ctx context.Context, path string) ([]byte, error)
+	GetRawStream(ctx context.Context, path string) (io.ReadCloser, error)
+	GetWithTrace(ctx context.Context, path string) (node.Node, *Trace, error)
+	GetByInstanceID(ctx context.Context, iid string) (node.Node, error)
+	Exists(ctx context.Context, paths []string) (map[string]bool, error)
+	LeafListContains(ctx context.Context, path string, value string) (bool, error)
+	Download(ctx context.Context, path string, w io.Writer) (int64, error)
+	Snapshot(ctx context.Context) (*Snapshot, error)
+
+	// edits
+	Diff(ctx context.Context, path string, desired node.Selection) (Diff, error)
+	Apply(ctx context.Context, d Diff) error
+	YangPatch(ctx context.Context, path string, patchId string, edits []PatchEdit) error
+	Transaction(ctx context.Context) (*Transaction, error)
+	ExportConfig(ctx context.Context, w io.Writer) error
+	ExportConfigBytes(ctx context.Context) ([]byte, error)
+	ImportConfig(ctx context.Context, doc io.Reader) error
+
+	// actions
+	ActionRaw(ctx context.Context, path string, input node.Node) (*http.Response, node.Node, error)
+	ActionStream(ctx context.Context, path string, input node.Node) (<-chan node.Node, error)
+	ActionWithTrace(ctx context.Context, path string, input node.Node) (node.Node, *Trace, error)
+
+	// notifications
+	Subscribe(ctx context.Context, path string) (*Subscription, error)
+	LazySubscribe(ctx context.Context, path string) (*LazySubscription, error)
+	Streams(ctx context.Context) ([]StreamInfo, error)
+	StreamStats() []StreamStats
+}
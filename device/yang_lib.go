@@ -12,21 +12,43 @@ type ResolveModule interface {
 }
 
 func LoadModules(ietfYangLib *node.Browser, resolver ResolveModule) (map[string]*meta.Module, error) {
+	mods, _, err := LoadModuleHnds(ietfYangLib, resolver)
+	return mods, err
+}
+
+// LoadModuleHnds is like LoadModules but also returns module entries exactly as
+// device reported them including features and deviations.
+func LoadModuleHnds(ietfYangLib *node.Browser, resolver ResolveModule) (map[string]*meta.Module, []*ModuleHnd, error) {
 	mods := make(map[string]*meta.Module)
-	n := loadModulesListNode(mods, resolver)
+	var hnds []*ModuleHnd
+	n := loadModulesListNode(mods, &hnds, resolver)
 	if err := ietfYangLib.Root().Find("modules-state/module").InsertInto(n).LastErr; err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return mods, nil
+	return mods, hnds, nil
 }
 
-func loadModulesListNode(mods map[string]*meta.Module, resolver ResolveModule) node.Node {
+func loadModulesListNode(mods map[string]*meta.Module, hnds *[]*ModuleHnd, resolver ResolveModule) node.Node {
 	return &nodeutil.Basic{
 		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
 			key := r.Key
 			if r.New {
-				hnd := ModuleHnd{Name: r.Key[0].String()}
-				return loadModuleNode(mods, resolver, &hnd), key, nil
+				hnd := &ModuleHnd{Name: r.Key[0].String()}
+				*hnds = append(*hnds, hnd)
+				return loadModuleNode(mods, resolver, hnd), key, nil
+			}
+			return nil, nil, nil
+		},
+	}
+}
+
+func moduleHndListNode(hnds *[]*ModuleHnd) node.Node {
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if r.New {
+				hnd := &ModuleHnd{}
+				*hnds = append(*hnds, hnd)
+				return nodeutil.ReflectChild(hnd), r.Key, nil
 			}
 			return nil, nil, nil
 		},
@@ -36,6 +58,23 @@ func loadModulesListNode(mods map[string]*meta.Module, resolver ResolveModule) n
 func loadModuleNode(mods map[string]*meta.Module, resolver ResolveModule, hnd *ModuleHnd) node.Node {
 	return &nodeutil.Extend{
 		Base: nodeutil.ReflectChild(hnd),
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			switch r.Meta.Ident() {
+			case "deviation":
+				return moduleHndListNode(&hnd.Deviation), nil
+			case "submodule":
+				return moduleHndListNode(&hnd.Submodule), nil
+			}
+			return p.Child(r)
+		},
+		OnField: func(p node.Node, r node.FieldRequest, v *node.ValueHandle) error {
+			switch r.Meta.Ident() {
+			case "conformance-type":
+				hnd.ConformanceType = v.Val.String()
+				return nil
+			}
+			return p.Field(r, v)
+		},
 		OnEndEdit: func(p node.Node, r node.NodeRequest) error {
 			if err := p.EndEdit(r); err != nil {
 				return err
@@ -51,8 +90,14 @@ func loadModuleNode(mods map[string]*meta.Module, resolver ResolveModule, hnd *M
 }
 
 type ModuleHnd struct {
-	Name      string
-	Schema    string
-	Revision  string
-	Namespace string
+	Name            string
+	Schema          string
+	Revision        string
+	Namespace       string
+	Feature         []string
+	ConformanceType string
+
+	// only name and revision are set on deviations
+	Deviation []*ModuleHnd
+	Submodule []*ModuleHnd
 }
@@ -2,13 +2,18 @@ package device_test
 
 import (
 	"flag"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/freeconf/restconf/device"
 	"github.com/freeconf/restconf/testdata"
 	"github.com/freeconf/yang/fc"
 	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
 	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
 )
 
 var update = flag.Bool("update", false, "update golden test files")
@@ -40,3 +45,39 @@ func TestYangLibNode(t *testing.T) {
 	}
 	fc.Gold(t, *update, []byte(actual), "gold/yang_lib.json")
 }
+
+type testResolver struct{}
+
+func (testResolver) ResolveModuleHnd(hnd device.ModuleHnd) (*meta.Module, error) {
+	return parser.LoadModuleFromString(nil, fmt.Sprintf(`module %s { revision %s; }`, hnd.Name, hnd.Revision))
+}
+
+func TestLoadModuleHnds(t *testing.T) {
+	ylib := parser.RequireModule(source.Dir("../yang"), "ietf-yang-library")
+	data := `{"modules-state":{"module-set-id":"x","module":[{
+		"name":"a",
+		"revision":"2020-01-01",
+		"namespace":"urn:a",
+		"feature":["f1","f2"],
+		"conformance-type":"implement",
+		"deviation":[{"name":"a-dev","revision":"2020-01-02"}]
+	},{
+		"name":"a-dev",
+		"revision":"2020-01-02",
+		"namespace":"urn:a-dev",
+		"conformance-type":"implement"
+	}]}}`
+	b := node.NewBrowser(ylib, nodeutil.ReadJSON(data))
+	mods, hnds, err := device.LoadModuleHnds(b, testResolver{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, 2, len(mods))
+	fc.AssertEqual(t, 2, len(hnds))
+	fc.AssertEqual(t, "a", hnds[0].Name)
+	fc.AssertEqual(t, "f1,f2", strings.Join(hnds[0].Feature, ","))
+	fc.AssertEqual(t, "implement", hnds[0].ConformanceType)
+	fc.AssertEqual(t, 1, len(hnds[0].Deviation))
+	fc.AssertEqual(t, "a-dev", hnds[0].Deviation[0].Name)
+	fc.AssertEqual(t, "2020-01-02", hnds[0].Deviation[0].Revision)
+}
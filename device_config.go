@@ -0,0 +1,84 @@
+package restconf
+
+import (
+	"net/http"
+)
+
+// DeviceConfig is configuration for a single device chosen by
+// Client.DeviceConfig so one factory can manage groups of devices that need
+// different credentials or speak different dialects of RESTCONF.  Zero
+// values use factory's configuration.
+type DeviceConfig struct {
+
+	// Transport to device such as one w/a different TLS config or that adds
+	// auth.  Nil uses factory's transport.
+	Transport http.RoundTripper
+
+	// Codec for device's data.  Nil uses factory's Codec.
+	Codec Codec
+
+	// Compliance for device.  Nil uses factory's settings, see
+	// Client.Compliance.
+	Compliance *Compliance
+
+	// UserAgent sent to device.  Empty uses factory's UserAgent.
+	UserAgent string
+}
+
+// Compliance is how requests are adjusted for devices that differ from
+// RFC 8040 or from this package's server.  Each field is same as Client
+// field of same name.
+type Compliance struct {
+	ActionWrapper            bool
+	ActionUrl                string
+	TrailingSlash            bool
+	LegacyConfigParam        bool
+	PatchEdits               bool
+	KeyFormat                KeyFormat
+	JSONNamespaceChangesOnly bool
+}
+
+// Compliance is factory's compliance settings, a starting point to change
+// for a group of devices in DeviceConfig
+func (self Client) Compliance() Compliance {
+	return Compliance{
+		ActionWrapper:            self.ActionWrapper,
+		ActionUrl:                self.ActionUrl,
+		TrailingSlash:            self.TrailingSlash,
+		LegacyConfigParam:        self.LegacyConfigParam,
+		PatchEdits:               self.PatchEdits,
+		KeyFormat:                self.KeyFormat,
+		JSONNamespaceChangesOnly: self.JSONNamespaceChangesOnly,
+	}
+}
+
+// deviceConfig is factory's configuration w/device's overrides applied
+func (self Client) deviceConfig(url string) (DeviceConfig, error) {
+	var config DeviceConfig
+	if self.DeviceConfig != nil {
+		var err error
+		if config, err = self.DeviceConfig(url); err != nil {
+			return config, err
+		}
+	}
+	if config.Transport == nil {
+		config.Transport = self.sharedTransport()
+		if self.DeviceTransport != nil {
+			config.Transport = self.DeviceTransport(url, self.sharedTransport())
+		}
+	}
+	if config.Codec == nil {
+		config.Codec = codecOrDefault(self.Codec)
+	}
+	if config.Compliance == nil {
+		compliance := self.Compliance()
+		config.Compliance = &compliance
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = self.UserAgent
+		if config.UserAgent == "" {
+			config.UserAgent = defaultUserAgent
+		}
+	}
+	return config, nil
+}
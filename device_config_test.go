@@ -0,0 +1,88 @@
+package restconf
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/source"
+)
+
+type basicAuth struct {
+	user string
+	next http.RoundTripper
+}
+
+func (self basicAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(self.user, "secret")
+	return self.next.RoundTrip(req)
+}
+
+func TestClientDeviceConfig(t *testing.T) {
+	users := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users[strings.Split(r.URL.Path, "/")[1]], _, _ = r.BasicAuth()
+		w.Write([]byte(`{"module":[]}`))
+	}))
+	defer srv.Close()
+	errUnknown := errors.New("unknown group")
+	factory := &Client{
+		YangPath:      source.Dir("./yang"),
+		TrailingSlash: true,
+	}
+	var configured []string
+	factory.DeviceConfig = func(url string) (DeviceConfig, error) {
+		configured = append(configured, url)
+		switch {
+		case strings.Contains(url, "/legacy/"):
+			compliance := factory.Compliance()
+			compliance.TrailingSlash = false
+			compliance.LegacyConfigParam = true
+			return DeviceConfig{
+				Compliance: &compliance,
+				Transport:  basicAuth{user: "legacy", next: srv.Client().Transport},
+			}, nil
+		case strings.Contains(url, "/strict/"):
+			return DeviceConfig{
+				Compliance: &Compliance{ActionWrapper: true, JSONNamespaceChangesOnly: true},
+				Transport:  basicAuth{user: "strict", next: srv.Client().Transport},
+			}, nil
+		case strings.Contains(url, "/default/"):
+			return DeviceConfig{}, nil
+		}
+		return DeviceConfig{}, errUnknown
+	}
+
+	legacy, err := factory.NewDevice(srv.URL + "/legacy/restconf")
+	fc.AssertEqual(t, nil, err)
+	l := legacy.(*client)
+	fc.AssertEqual(t, false, l.trailingSlash)
+	fc.AssertEqual(t, true, l.legacyConfig)
+	fc.AssertEqual(t, false, l.actionWrapper)
+	fc.AssertEqual(t, "legacy", users["legacy"])
+
+	strict, err := factory.NewDevice(srv.URL + "/strict/restconf")
+	fc.AssertEqual(t, nil, err)
+	s := strict.(*client)
+	fc.AssertEqual(t, false, s.trailingSlash)
+	fc.AssertEqual(t, false, s.legacyConfig)
+	fc.AssertEqual(t, true, s.actionWrapper)
+	fc.AssertEqual(t, true, s.namespaceChanges)
+	fc.AssertEqual(t, "strict", users["strict"])
+
+	def, err := factory.NewDevice(srv.URL + "/default/restconf")
+	fc.AssertEqual(t, nil, err)
+	d := def.(*client)
+	fc.AssertEqual(t, true, d.trailingSlash)
+	fc.AssertEqual(t, true, d.client.Transport == factory.sharedTransport())
+	fc.AssertEqual(t, defaultUserAgent, d.userAgent)
+	fc.AssertEqual(t, "", users["default"])
+
+	_, err = factory.NewDevice(srv.URL + "/other/restconf")
+	fc.AssertEqual(t, errUnknown, err)
+	fc.AssertEqual(t, 4, len(configured))
+}
@@ -0,0 +1,36 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/source"
+)
+
+func TestDevice(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/restconf/schema/car"):
+			w.Write([]byte(`module car { namespace "c"; prefix "c"; revision 2020-01-01; leaf speed { type int32; } }`))
+		case r.URL.Path == "/restconf/data/car:speed":
+			w.Write([]byte(`{"car:speed":10}`))
+		case r.Method == "GET":
+			w.Write([]byte(`{"module":[{"name":"car","revision":"2020-01-01","namespace":"c"}]}`))
+		}
+	}))
+	defer srv.Close()
+	factory := Client{YangPath: source.Dir("./yang")}
+	dev, err := factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	rdev, valid := dev.(Device)
+	fc.AssertEqual(t, true, valid)
+	fc.AssertEqual(t, "car", rdev.ModuleInfo()[0].Name)
+	raw, err := rdev.GetRaw(context.Background(), "car:speed")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"car:speed":10}`, string(raw))
+	fc.AssertEqual(t, "HTTP/1.1", rdev.Protocol())
+}
@@ -0,0 +1,98 @@
+package restconf
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/freeconf/yang/meta"
+)
+
+// Diff is the leaf level difference between data on server and data about to
+// be sent. Paths are relative to the target of the edit and list entries
+// are addressed by key as they are in URLs (e.g. interface=eth0/mtu)
+type Diff struct {
+
+	// Path is target of edit in module:path form when diff was made by
+	// Diff on a device
+	Path string
+
+	Added   []LeafDiff
+	Changed []LeafDiff
+	Removed []LeafDiff
+}
+
+type LeafDiff struct {
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// Empty is true when there is nothing different
+func (self Diff) Empty() bool {
+	return len(self.Added) == 0 && len(self.Changed) == 0 && len(self.Removed) == 0
+}
+
+// diffData compares data as it is stored by nodeutil.ReflectChild on a map where
+// lists are maps keyed by their key
+func diffData(m meta.HasDataDefinitions, prefix string, from, to map[string]interface{}, d *Diff) {
+	for _, def := range m.DataDefinitions() {
+		diffDef(def, prefix, from, to, d)
+	}
+}
+
+func diffDef(def meta.Definition, prefix string, from, to map[string]interface{}, d *Diff) {
+	p := def.Ident()
+	if prefix != "" {
+		p = prefix + "/" + p
+	}
+	switch x := def.(type) {
+	case *meta.Choice:
+		for _, kase := range x.Cases() {
+			diffData(kase, prefix, from, to, d)
+		}
+	case *meta.List:
+		fromEntries := asDataMap(from[x.Ident()])
+		toEntries := asDataMap(to[x.Ident()])
+		for _, key := range unionKeys(fromEntries, toEntries) {
+			diffData(x, p+"="+key, asDataMap(fromEntries[key]), asDataMap(toEntries[key]), d)
+		}
+	case meta.HasDataDefinitions:
+		diffData(x, p, asDataMap(from[x.Ident()]), asDataMap(to[x.Ident()]), d)
+	case meta.Leafable:
+		oldVal, hadOld := from[x.Ident()]
+		newVal, hasNew := to[x.Ident()]
+		switch {
+		case !hadOld && hasNew:
+			d.Added = append(d.Added, LeafDiff{Path: p, New: newVal})
+		case hadOld && !hasNew:
+			d.Removed = append(d.Removed, LeafDiff{Path: p, Old: oldVal})
+		case hadOld && hasNew && !reflect.DeepEqual(oldVal, newVal):
+			d.Changed = append(d.Changed, LeafDiff{Path: p, Old: oldVal, New: newVal})
+		}
+	}
+}
+
+func asDataMap(v interface{}) map[string]interface{} {
+	if m, valid := v.(map[string]interface{}); valid {
+		return m
+	}
+	return nil
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, found := a[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	return unionKeys(m, nil)
+}
@@ -0,0 +1,42 @@
+package restconf
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestDiffData(t *testing.T) {
+	m := requestBuilder{}.m(`
+		leaf a { type string; }
+		container b {
+			leaf c { type int32; }
+		}
+		list d {
+			key "k";
+			leaf k { type string; }
+			leaf v { type int32; }
+		}`)
+	from := map[string]interface{}{
+		"a": "x",
+		"b": map[string]interface{}{"c": 1},
+		"d": map[string]interface{}{
+			"one": map[string]interface{}{"k": "one", "v": 1},
+			"two": map[string]interface{}{"k": "two", "v": 2},
+		},
+	}
+	to := map[string]interface{}{
+		"b": map[string]interface{}{"c": 2},
+		"d": map[string]interface{}{
+			"two":   map[string]interface{}{"k": "two", "v": 2},
+			"three": map[string]interface{}{"k": "three"},
+		},
+	}
+	var d Diff
+	diffData(m, "", from, to, &d)
+	fc.AssertEqual(t, "d=three/k", diffPaths(d.Added))
+	fc.AssertEqual(t, "b/c", diffPaths(d.Changed))
+	fc.AssertEqual(t, "a,d=one/k,d=one/v", diffPaths(d.Removed))
+	fc.AssertEqual(t, false, d.Empty())
+	fc.AssertEqual(t, true, Diff{}.Empty())
+}
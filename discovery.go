@@ -0,0 +1,103 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// DiscoverAddress finds RESTCONF root from server's /.well-known/host-meta
+// (RFC 8040 section 3.1) for servers that do not have it at /restconf.
+// Only scheme and host of urlAddr are used for discovery.  If server does
+// not answer w/a restconf link, urlAddr is assumed to be root as it would be
+// w/NewAddress.
+func DiscoverAddress(ctx context.Context, hc *http.Client, urlAddr string) (Address, error) {
+	root, err := discoverRoot(ctx, hc, urlAddr)
+	if err != nil {
+		fc.Debug.Printf("could not discover restconf root of %s, assuming it's as given. %s", redactUrl(urlAddr), err)
+		return NewAddress(urlAddr)
+	}
+	return NewAddress(root)
+}
+
+func discoverRoot(ctx context.Context, hc *http.Client, urlAddr string) (string, error) {
+	u, err := url.Parse(urlAddr)
+	if err != nil {
+		return "", err
+	}
+	hostMeta := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/.well-known/host-meta"}
+	req, err := http.NewRequestWithContext(ctx, "GET", hostMeta.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/xrd+xml, application/json")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("host-meta %s", resp.Status)
+	}
+	doc, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	href, err := restconfLink(doc)
+	if err != nil {
+		return "", err
+	}
+	root, err := hostMeta.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	return root.String(), nil
+}
+
+// restconfLink is href of restconf link in host-meta document which
+// is XRD XML per spec but may be JSON as this package's server sends
+func restconfLink(doc []byte) (string, error) {
+	type link struct {
+		Rel  string `xml:"rel,attr" json:"@rel"`
+		Href string `xml:"href,attr" json:"@href"`
+	}
+	var links []link
+	if trimmed := strings.TrimSpace(string(doc)); strings.HasPrefix(trimmed, "{") {
+		var jsonDoc struct {
+			Xrd struct {
+				Link json.RawMessage `json:"link"`
+			} `json:"xrd"`
+		}
+		if err := json.Unmarshal(doc, &jsonDoc); err != nil {
+			return "", err
+		}
+		// single link is an object
+		var single link
+		if err := json.Unmarshal(jsonDoc.Xrd.Link, &single); err == nil {
+			links = []link{single}
+		} else if err := json.Unmarshal(jsonDoc.Xrd.Link, &links); err != nil {
+			return "", err
+		}
+	} else {
+		var xmlDoc struct {
+			Links []link `xml:"Link"`
+		}
+		if err := xml.Unmarshal(doc, &xmlDoc); err != nil {
+			return "", err
+		}
+		links = xmlDoc.Links
+	}
+	for _, l := range links {
+		if l.Rel == "restconf" && l.Href != "" {
+			return l.Href, nil
+		}
+	}
+	return "", fmt.Errorf("%w. no restconf link in host-meta", fc.NotFoundError)
+}
@@ -0,0 +1,93 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// Download copies resource at path, in module:path form, into w exactly as
+// device sends it.  Meant for large operational data where starting over
+// after a dropped connection is expensive.  If device advertised
+// Accept-Ranges: bytes, download resumes from last byte received otherwise
+// resource is fetched again from the start and what was already copied is
+// skipped.  Download gives up when an attempt makes no progress.
+func (self *client) Download(ctx context.Context, path string, w io.Writer) (int64, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return 0, err
+	}
+	var written int64
+	var resumable bool
+	var ifRange string
+	for {
+		r := byteRange{}
+		if resumable {
+			r = byteRange{offset: written, ifRange: ifRange}
+		}
+		resp, err := self.send(withByteRange(ctx, r), "GET", "", p, nil)
+		if err != nil {
+			return written, err
+		}
+		switch {
+		case written == 0:
+			resumable = resp.Header.Get("Accept-Ranges") == "bytes"
+			if ifRange = resp.Header.Get("ETag"); ifRange == "" {
+				ifRange = resp.Header.Get("Last-Modified")
+			}
+		case resp.StatusCode == http.StatusPartialContent:
+		case resumable:
+			// If-Range did not match
+			resp.Body.Close()
+			return written, fmt.Errorf("%w. %s changed during download", ErrConflict, path)
+		default:
+			if _, err := io.CopyN(ioutil.Discard, resp.Body, written); err != nil {
+				resp.Body.Close()
+				return written, err
+			}
+		}
+		n, err := io.Copy(w, resp.Body)
+		resp.Body.Close()
+		written += n
+		if err == nil || n == 0 || ctx.Err() != nil {
+			return written, err
+		}
+		if !self.log(ctx, LogInfo, "download interrupted", LogField{"path", path},
+			LogField{"received", written}, LogField{"resumable", resumable}, LogField{"error", err.Error()}) {
+			fc.Debug.Printf("download of %s interrupted after %d bytes. %s", path, written, err)
+		}
+	}
+}
+
+type byteRange struct {
+	offset  int64
+	ifRange string
+}
+
+type byteRangeKey struct{}
+
+func withByteRange(ctx context.Context, r byteRange) context.Context {
+	return context.WithValue(ctx, byteRangeKey{}, r)
+}
+
+func setByteRange(ctx context.Context, req *http.Request) {
+	r, found := ctx.Value(byteRangeKey{}).(byteRange)
+	if !found {
+		return
+	}
+	// offsets are into bytes as device sends them so http client must not
+	// transparently decompress
+	req.Header.Set("Accept-Encoding", "identity")
+	if r.offset == 0 {
+		return
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(r.offset, 10)+"-")
+	if r.ifRange != "" {
+		req.Header.Set("If-Range", r.ifRange)
+	}
+}
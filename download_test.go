@@ -0,0 +1,84 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+// abortWriter drops connection after writing limit bytes
+type abortWriter struct {
+	http.ResponseWriter
+	limit int
+}
+
+func (self *abortWriter) Write(b []byte) (int, error) {
+	if len(b) > self.limit {
+		self.ResponseWriter.Write(b[:self.limit])
+		self.ResponseWriter.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}
+	self.limit -= len(b)
+	return self.ResponseWriter.Write(b)
+}
+
+func TestClientDownload(t *testing.T) {
+	content := `{"log":"` + strings.Repeat("x", 10000) + `"}`
+	modified := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ranges []string
+	var acceptRanges, changes bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			w = &abortWriter{ResponseWriter: w, limit: 4000}
+		} else if changes {
+			modified = modified.Add(time.Hour)
+		}
+		if acceptRanges {
+			http.ServeContent(w, r, "", modified, strings.NewReader(content))
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container diag { leaf log { type string; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+
+	// resumes where it left off
+	acceptRanges = true
+	var buf bytes.Buffer
+	n, err := c.Download(ctx, "m:diag", &buf)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, int64(len(content)), n)
+	fc.AssertEqual(t, content, buf.String())
+	fc.AssertEqual(t, `,bytes=4000-`, strings.Join(ranges, ","))
+
+	// no range support, starts over
+	acceptRanges = false
+	ranges = nil
+	buf.Reset()
+	n, err = c.Download(ctx, "m:diag", &buf)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, int64(len(content)), n)
+	fc.AssertEqual(t, content, buf.String())
+	fc.AssertEqual(t, `,`, strings.Join(ranges, ","))
+
+	// resource changed between attempts
+	acceptRanges = true
+	changes = true
+	ranges = nil
+	_, err = c.Download(ctx, "m:diag", &buf)
+	fc.AssertEqual(t, true, errors.Is(err, ErrConflict))
+}
@@ -0,0 +1,190 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/val"
+	"github.com/freeconf/yang/xpath"
+)
+
+// ErrInvalidEdit is when staged edit breaks a mandatory or when statement in
+// schema.  See Client.ValidateEdits.
+var ErrInvalidEdit = errors.New("edit is not valid")
+
+// validateEdit checks staged data in sel against schema w/o asking device.
+// Missing mandatory nodes are reported in sel only if top and in data
+// below sel only if nested, edits that merge can leave them to data already
+// on device.
+func validateEdit(sel node.Selection, top bool, nested bool) error {
+	return validateDefs(sel, sel.Meta().(meta.HasDataDefinitions).DataDefinitions(), top, nested)
+}
+
+func validateDefs(sel node.Selection, defs []meta.Definition, mandatory bool, nested bool) error {
+	for _, def := range defs {
+		if choice, isChoice := def.(*meta.Choice); isChoice {
+			if err := validateChoice(sel, choice, mandatory, nested); err != nil {
+				return err
+			}
+			continue
+		}
+		child, staged, err := stagedNode(sel, def)
+		if err != nil {
+			return err
+		}
+		if !staged {
+			if !mandatory || !isMandatory(def) {
+				continue
+			}
+			// when on a leaf is about it's parent so it can be checked w/o
+			// leaf
+			if _, isContainer := def.(*meta.Container); !isContainer {
+				if applies, err := whenTrue(sel, def); err != nil || !applies {
+					return err
+				}
+			}
+			return fmt.Errorf("%w. %s/%s is mandatory", ErrInvalidEdit, sel.Path, def.Ident())
+		}
+		whenSel := sel
+		if _, isContainer := def.(*meta.Container); isContainer {
+			whenSel = child
+		}
+		if applies, err := whenTrue(whenSel, def); err != nil {
+			return err
+		} else if !applies {
+			return fmt.Errorf("%w. %s/%s when %q is false", ErrInvalidEdit, sel.Path, def.Ident(), def.(meta.HasWhen).When().Expression())
+		}
+		switch x := def.(type) {
+		case *meta.List:
+			for item := child.First(); !item.Selection.IsNil(); item = item.Next() {
+				if item.Selection.LastErr != nil {
+					return item.Selection.LastErr
+				}
+				if err := validateDefs(item.Selection, x.DataDefinitions(), nested, nested); err != nil {
+					return err
+				}
+			}
+		case *meta.Container:
+			if err := validateDefs(child, x.DataDefinitions(), nested, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateChoice checks case that has data or that there is one if choice
+// is mandatory
+func validateChoice(sel node.Selection, choice *meta.Choice, mandatory bool, nested bool) error {
+	for _, kase := range choice.Cases() {
+		staged, err := stagedAny(sel, kase.DataDefinitions())
+		if err != nil {
+			return err
+		}
+		if staged {
+			return validateDefs(sel, kase.DataDefinitions(), mandatory, nested)
+		}
+	}
+	if mandatory && choice.Mandatory() {
+		return fmt.Errorf("%w. %s/%s is mandatory", ErrInvalidEdit, sel.Path, choice.Ident())
+	}
+	return nil
+}
+
+func stagedAny(sel node.Selection, defs []meta.Definition) (bool, error) {
+	for _, def := range defs {
+		var staged bool
+		var err error
+		if choice, isChoice := def.(*meta.Choice); isChoice {
+			for _, kase := range choice.Cases() {
+				if staged, err = stagedAny(sel, kase.DataDefinitions()); staged || err != nil {
+					break
+				}
+			}
+		} else {
+			_, staged, err = stagedNode(sel, def)
+		}
+		if staged || err != nil {
+			return staged, err
+		}
+	}
+	return false, nil
+}
+
+// stagedNode is whether edit has data for def and if def is a container or
+// list, it's selection
+func stagedNode(sel node.Selection, def meta.Definition) (node.Selection, bool, error) {
+	switch x := def.(type) {
+	case meta.Leafable:
+		r := node.FieldRequest{
+			Request: node.Request{Selection: sel},
+			Meta:    x,
+		}
+		var hnd node.ValueHandle
+		if err := sel.GetValueHnd(&r, &hnd, false); err != nil {
+			return sel, false, err
+		}
+		return sel, hnd.Val != nil, nil
+	case *meta.List:
+		child := sel.Find(x.Ident())
+		if child.LastErr != nil || child.IsNil() {
+			return child, false, child.LastErr
+		}
+		first := child.First()
+		return child, !first.Selection.IsNil(), first.Selection.LastErr
+	}
+	child := sel.Find(def.Ident())
+	return child, !child.IsNil(), child.LastErr
+}
+
+func isMandatory(def meta.Definition) bool {
+	if m, valid := def.(meta.HasMandatory); valid && m.Mandatory() {
+		return true
+	}
+	if m, valid := def.(interface{ MinElements() int }); valid {
+		return m.MinElements() > 0
+	}
+	return false
+}
+
+// whenTrue is whether def's when expression is true in sel or def has none
+func whenTrue(sel node.Selection, def meta.Definition) (bool, error) {
+	hw, valid := def.(meta.HasWhen)
+	if !valid || hw.When() == nil {
+		return true, nil
+	}
+	xp, err := xpath.Parse(hw.When().Expression())
+	if err != nil {
+		return false, err
+	}
+	return sel.XPredicate(xp)
+}
+
+// editWhen is node.CheckWhen for reads but lets data being edited thru so
+// it's checked once all of it is staged, see validateEdit
+type editWhen struct {
+	node.CheckWhen
+}
+
+func (self editWhen) CheckContainerPostConstraints(r node.ChildRequest, s node.Selection) (bool, error) {
+	if r.New {
+		return true, nil
+	}
+	return self.CheckWhen.CheckContainerPostConstraints(r, s)
+}
+
+func (self editWhen) CheckFieldPreConstraints(r *node.FieldRequest, hnd *node.ValueHandle) (bool, error) {
+	if r.Write {
+		return true, nil
+	}
+	return self.CheckWhen.CheckFieldPreConstraints(r, hnd)
+}
+
+func (self editWhen) CheckListPostConstraints(r node.ListRequest, child node.Selection, key []val.Value) (bool, error) {
+	if r.New {
+		return true, nil
+	}
+	return self.CheckWhen.CheckListPostConstraints(r, child, key)
+}
@@ -0,0 +1,85 @@
+package restconf
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientValidateEdits(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			leaf name { type string; mandatory true; }
+			leaf wheels { type int32; }
+			leaf speed { when "wheels>2"; type int32; }
+			list y {
+				key "id";
+				leaf id { type string; }
+				leaf v { type string; mandatory true; }
+			}
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var edits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{}`))
+		case "OPTIONS":
+		default:
+			edits = append(edits, r.Method)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	newClient := func(patchEdits bool) *client {
+		c := newTestClient(srv, m)
+		c.validateEdits = true
+		c.patchEdits = patchEdits
+		return c
+	}
+	upsert := func(c *client, data string) error {
+		b := node.NewBrowser(m, c.newClientNode().node())
+		return b.Root().Find("x").UpsertFrom(nodeutil.ReadJSON(data)).LastErr
+	}
+	c := newClient(false)
+
+	// missing mandatory leaf
+	err = upsert(c, `{"wheels":4}`)
+	fc.AssertEqual(t, true, errors.Is(err, ErrInvalidEdit))
+	fc.AssertEqual(t, true, strings.Contains(err.Error(), "x/name is mandatory"))
+	fc.AssertEqual(t, 0, len(edits))
+
+	// failing when
+	err = upsert(c, `{"name":"a","wheels":2,"speed":3}`)
+	fc.AssertEqual(t, true, errors.Is(err, ErrInvalidEdit))
+	fc.AssertEqual(t, true, strings.Contains(err.Error(), `x/speed when "wheels>2" is false`))
+	fc.AssertEqual(t, 0, len(edits))
+
+	// mandatory in list entry
+	err = upsert(c, `{"name":"a","y":[{"id":"k"}]}`)
+	fc.AssertEqual(t, true, errors.Is(err, ErrInvalidEdit))
+	fc.AssertEqual(t, true, strings.Contains(err.Error(), "/v is mandatory"))
+	fc.AssertEqual(t, 0, len(edits))
+
+	// valid
+	err = upsert(c, `{"name":"a","wheels":4,"speed":3,"y":[{"id":"k","v":"b"}]}`)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 1, len(edits))
+
+	// merge may leave mandatory to what is on device, when still applies
+	edits = nil
+	c = newClient(true)
+	fc.AssertEqual(t, nil, upsert(c, `{"wheels":4}`))
+	fc.AssertEqual(t, 1, len(edits))
+	err = upsert(c, `{"wheels":2,"speed":3}`)
+	fc.AssertEqual(t, true, errors.Is(err, ErrInvalidEdit))
+	fc.AssertEqual(t, 1, len(edits))
+}
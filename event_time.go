@@ -0,0 +1,86 @@
+package restconf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// DefaultEventTimeLayouts are tried in order to parse eventTime of
+// notifications.  RFC3339Nano also accepts times w/o fractional seconds or
+// w/any number of fractional digits.
+var DefaultEventTimeLayouts = []string{
+	time.RFC3339Nano,
+	// some servers leave off timezone
+	"2006-01-02T15:04:05.999999999",
+}
+
+// notificationEnvelope is how RFC 8040 section 6.4 wraps each notification
+// w/when it happened
+const notificationEnvelope = "ietf-restconf:notification"
+
+// deviceEvent is a notification w/what device said about it besides it's
+// data, when it happened if it arrived in an envelope and it's SSE id
+type deviceEvent struct {
+	node.Node
+	eventTime time.Time
+	timed     bool
+	cursor    string
+}
+
+// NewEvent is notification n as if device sent it w/eventTime and cursor
+// for replaying recorded events, see EventTime and EventCursor.  Zero
+// eventTime and empty cursor mean device did not say.
+func NewEvent(n node.Node, eventTime time.Time, cursor string) node.Node {
+	if eventTime.IsZero() && cursor == "" {
+		return n
+	}
+	return deviceEvent{Node: n, eventTime: eventTime, timed: !eventTime.IsZero(), cursor: cursor}
+}
+
+// EventTime is when notification happened according to device or false if
+// device did not say
+func EventTime(n node.Node) (time.Time, bool) {
+	if e, valid := n.(deviceEvent); valid && e.timed {
+		return e.eventTime, true
+	}
+	return time.Time{}, false
+}
+
+// unwrapNotification removes RFC 8040 envelope, if there is one, and
+// parses eventTime w/first layout that works
+func unwrapNotification(data map[string]interface{}, layouts []string) (map[string]interface{}, time.Time, bool, error) {
+	envelope, wrapped := data[notificationEnvelope].(map[string]interface{})
+	if !wrapped {
+		return data, time.Time{}, false, nil
+	}
+	var body map[string]interface{}
+	for k, v := range envelope {
+		if k != "eventTime" {
+			body, _ = v.(map[string]interface{})
+		}
+	}
+	if body == nil {
+		body = make(map[string]interface{})
+	}
+	s, _ := envelope["eventTime"].(string)
+	if s == "" {
+		return body, time.Time{}, false, nil
+	}
+	t, err := parseEventTime(s, layouts)
+	return body, t, true, err
+}
+
+func parseEventTime(s string, layouts []string) (time.Time, error) {
+	if len(layouts) == 0 {
+		layouts = DefaultEventTimeLayouts
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w. eventTime %q not in any of %v", fc.BadRequestError, s, layouts)
+}
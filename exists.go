@@ -0,0 +1,108 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/val"
+)
+
+// ExistsErrors are reasons, other than not found, that paths could not be
+// probed
+type ExistsErrors map[string]error
+
+func (self ExistsErrors) Error() string {
+	paths := make([]string, 0, len(self))
+	for path := range self {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	msgs := make([]string, len(paths))
+	for i, path := range paths {
+		msgs[i] = fmt.Sprintf("%s. %s", path, self[path])
+	}
+	return strings.Join(msgs, "\n")
+}
+
+const defaultMaxInFlight = 4
+
+// Exists probes which paths, in module:path form, are on device w/OPTIONS
+// requests instead of reading them.  Probes run concurrently up to
+// MaxInFlight.  First failure other than not found stops remaining probes
+// and error is ExistsErrors w/each path that failed.
+func (self *client) Exists(ctx context.Context, paths []string) (map[string]bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	limit := self.maxInFlight
+	if limit <= 0 {
+		limit = defaultMaxInFlight
+	}
+	slots := make(chan struct{}, limit)
+	found := make(map[string]bool, len(paths))
+	failed := make(ExistsErrors)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			exists, err := self.exists(ctx, path)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				if ctx.Err() == nil {
+					failed[path] = err
+					cancel()
+				}
+				return
+			}
+			found[path] = exists
+		}(path)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		return found, failed
+	}
+	return found, nil
+}
+
+func (self *client) exists(ctx context.Context, path string) (bool, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return false, err
+	}
+	_, err = self.support.clientDo(ctx, "OPTIONS", "", p, nil)
+	if errors.Is(err, fc.NotFoundError) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// LeafListContains checks if value is in leaf-list at path, in module:path
+// form, by addressing it as leaf-list=value (RFC 8040 section 3.5.3) w/o
+// reading entire leaf-list.  Probe is same as navigating to a resource so
+// Client.NavigationProbe applies.
+func (self *client) LeafListContains(ctx context.Context, path string, value string) (bool, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return false, err
+	}
+	if _, isLeafList := p.Meta().(*meta.LeafList); !isLeafList {
+		return false, fmt.Errorf("%w. %s is not a leaf-list", fc.BadRequestError, path)
+	}
+	return self.newClientNode().validNavigation(ctx, p.SetKey([]val.Value{val.String(value)}))
+}
@@ -0,0 +1,113 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientExists(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container a {}
+		container b {}
+		container c {}
+		container d {}
+		container e {}
+		container broken {}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var inFlight, maxInFlight int32
+	// first probes wait for each other so limit is reached
+	limitReached := make(chan struct{})
+	var reachedOnce sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		if n >= 2 {
+			reachedOnce.Do(func() { close(limitReached) })
+		}
+		select {
+		case <-limitReached:
+		case <-r.Context().Done():
+		}
+		switch {
+		case r.Method != "OPTIONS":
+			http.Error(w, "only probes expected", 400)
+		case strings.HasSuffix(r.URL.Path, "broken"):
+			http.Error(w, "oops", 500)
+		case strings.HasSuffix(r.URL.Path, "b"), strings.HasSuffix(r.URL.Path, "d"):
+			http.Error(w, "not found", 404)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.maxInFlight = 2
+	ctx := context.Background()
+	found, err := c.Exists(ctx, []string{"m:a", "m:b", "m:c", "m:d", "m:e"})
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 5, len(found))
+	fc.AssertEqual(t, true, found["m:a"])
+	fc.AssertEqual(t, false, found["m:b"])
+	fc.AssertEqual(t, true, found["m:c"])
+	fc.AssertEqual(t, false, found["m:d"])
+	fc.AssertEqual(t, true, found["m:e"])
+	fc.AssertEqual(t, int32(2), atomic.LoadInt32(&maxInFlight))
+
+	_, err = c.Exists(ctx, []string{"m:a", "m:broken"})
+	var failed ExistsErrors
+	fc.AssertEqual(t, true, errors.As(err, &failed))
+	fc.AssertEqual(t, 1, len(failed))
+	fc.AssertEqual(t, true, strings.Contains(failed["m:broken"].Error(), "oops"))
+}
+
+func TestClientLeafListContains(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			leaf-list tags { type string; }
+			leaf name { type string; }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var probes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes = append(probes, r.Method+" "+r.URL.EscapedPath())
+		if r.URL.Path != "/restconf/data/m:x/tags=a/b,c d+e" {
+			http.Error(w, "not found", 404)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+
+	found, err := c.LeafListContains(ctx, "m:x/tags", "a/b,c d+e")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, "OPTIONS /restconf/data/m:x/tags=a%2Fb%2Cc%20d%2Be", probes[0])
+
+	found, err = c.LeafListContains(ctx, "m:x/tags", "nope")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, found)
+
+	c.probe = ProbeHead
+	found, err = c.LeafListContains(ctx, "m:x/tags", "a/b,c d+e")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, "HEAD", strings.Fields(probes[2])[0])
+
+	_, err = c.LeafListContains(ctx, "m:x/name", "x")
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+}
@@ -0,0 +1,124 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/freeconf/yang/fc"
+)
+
+type fieldsKey struct{}
+
+// WithFields limits reads made with this context to fields selected by an
+// RFC 8040 fields expression such as "a;b/c" or "a(b;c)" and returns an
+// error if expression is not valid.  Expression is sent to server as is.
+//
+//	ctx, err := restconf.WithFields(ctx, "name;statistics(in-octets;out-octets)")
+//	b.RootWithContext(ctx).Find("interfaces")
+func WithFields(ctx context.Context, expr string) (context.Context, error) {
+	if err := checkFields(expr); err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, fieldsKey{}, expr), nil
+}
+
+func fieldsFrom(ctx context.Context) (string, bool) {
+	expr, found := ctx.Value(fieldsKey{}).(string)
+	return expr, found
+}
+
+func fieldsParam(expr string) string {
+	return "fields=" + url.QueryEscape(expr)
+}
+
+// checkFields is syntax check of fields expression from RFC 8040 section
+// 4.8.3.  Identifiers are not checked against schema.
+//
+//	fields-expr = path "(" fields-expr ")" / path ";" fields-expr / path
+//	path = api-identifier [ "/" path ]
+func checkFields(expr string) error {
+	p := fieldsParser{expr: expr}
+	p.fieldsExpr()
+	if p.err == nil && p.pos < len(expr) {
+		p.fail("unexpected '%c'", expr[p.pos])
+	}
+	return p.err
+}
+
+type fieldsParser struct {
+	expr string
+	pos  int
+	err  error
+}
+
+func (self *fieldsParser) fail(msg string, args ...interface{}) {
+	if self.err == nil {
+		self.err = fmt.Errorf("%w. invalid fields %q at %d, %s", fc.BadRequestError, self.expr, self.pos, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (self *fieldsParser) peek() byte {
+	if self.pos < len(self.expr) {
+		return self.expr[self.pos]
+	}
+	return 0
+}
+
+func (self *fieldsParser) fieldsExpr() {
+	for self.err == nil {
+		self.path()
+		if self.peek() == '(' {
+			self.pos++
+			self.fieldsExpr()
+			if self.peek() != ')' {
+				self.fail("missing ')'")
+				return
+			}
+			self.pos++
+		}
+		if self.peek() != ';' {
+			return
+		}
+		self.pos++
+	}
+}
+
+func (self *fieldsParser) path() {
+	for self.err == nil {
+		self.apiIdentifier()
+		if self.peek() != '/' {
+			return
+		}
+		self.pos++
+	}
+}
+
+// apiIdentifier is [module-name ":"] identifier
+func (self *fieldsParser) apiIdentifier() {
+	self.identifier()
+	if self.err == nil && self.peek() == ':' {
+		self.pos++
+		self.identifier()
+	}
+}
+
+func (self *fieldsParser) identifier() {
+	c := self.peek()
+	if !(isAlpha(c) || c == '_') {
+		self.fail("expected identifier")
+		return
+	}
+	self.pos++
+	for c = self.peek(); isAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.'; c = self.peek() {
+		self.pos++
+	}
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,71 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestCheckFields(t *testing.T) {
+	valid := []string{
+		"a",
+		"a;b/c",
+		"m:a/m:b",
+		"a(b;c/d)",
+		"a(b(c;d);e);f",
+		"if-name.x_1",
+	}
+	for _, expr := range valid {
+		fc.AssertEqual(t, nil, checkFields(expr))
+	}
+	invalid := []string{
+		"",
+		"a;",
+		"a//b",
+		"a(b",
+		"a)",
+		"a()",
+		"1a",
+		"a b",
+		"m:",
+	}
+	for _, expr := range invalid {
+		err := checkFields(expr)
+		if !errors.Is(err, fc.BadRequestError) {
+			t.Errorf("%q expected bad request, got %v", expr, err)
+		}
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		container car {
+			leaf color {
+				type string;
+			}
+			leaf speed {
+				type int32;
+			}
+		}
+}`)
+	fc.AssertEqual(t, nil, err)
+	support := &testDriverFlowSupport{
+		t:   t,
+		get: map[string]string{"car": `{"speed":10}`},
+	}
+	b := node.NewBrowser(m, (&clientNode{support: support}).node())
+	ctx, err := WithFields(context.Background(), "speed;engine(rpm)")
+	fc.AssertEqual(t, nil, err)
+	ctx = WithContent(ctx, ContentNonConfig)
+	_, err = nodeutil.WriteJSON(b.RootWithContext(ctx).Find("car"))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "content=nonconfig&fields=speed%3Bengine%28rpm%29", support.getParams[0])
+
+	_, err = WithFields(context.Background(), "speed;")
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+}
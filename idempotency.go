@@ -0,0 +1,88 @@
+package restconf
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// RandomIdempotencyKey is 128 random bits in hex, suitable for
+// Client.IdempotencyKey
+func RandomIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// doAction sends an action w/an Idempotency-Key header when a key generator
+// is configured.  Same key is sent on every attempt so a device that
+// supports idempotency keys executes action at most once.  Actions are never
+// retried w/o a key.  Cancelling request's context abandons action.
+func (self *client) doAction(req *http.Request) (*http.Response, error) {
+	resp, err := self.sendAction(req)
+	if err != nil && req.Context().Err() != nil {
+		fc.Debug.Printf("abandoned action %s. %s", redactUrl(req.URL.String()), err)
+		if self.actionCanceled != nil {
+			self.actionCanceled(redactUrl(req.URL.String()), req.Header.Get("Idempotency-Key"))
+		}
+	}
+	return resp, err
+}
+
+func (self *client) sendAction(req *http.Request) (*http.Response, error) {
+	if self.idempotencyKey == nil {
+		return self.doCsrf(req)
+	}
+	req.Header.Set("Idempotency-Key", self.idempotencyKey())
+	for attempt := 0; ; attempt++ {
+		resp, err := self.doCsrf(req)
+		if attempt >= self.actionRetries || !retryableAction(req.Context(), resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			// payload was streamed and cannot be sent again
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		fc.Debug.Printf("retrying action %s, attempt %d. %v", redactUrl(req.URL.String()), attempt+1, err)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(time.Duration(attempt+1) * actionRetryDelay):
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+var actionRetryDelay = 100 * time.Millisecond
+
+// retryableAction is when request may not have reached device or device was
+// temporarily unable to handle it.  Never when caller gave up on it.
+func retryableAction(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return isTransportErr(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientIdempotencyKey(t *testing.T) {
+	actionRetryDelay = 0
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		rpc reboot {
+			input {
+				leaf delay { type int32; }
+			}
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var keys, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		bodies = append(bodies, string(body))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	newClient := func(key func() string, retries int) *client {
+		keys, bodies = nil, nil
+		c := newTestClient(srv, m)
+		c.idempotencyKey = key
+		c.actionRetries = retries
+		return c
+	}
+	ctx := context.Background()
+	input := nodeutil.ReadJSON(`{"delay":10}`)
+
+	// same key on every attempt
+	c := newClient(RandomIdempotencyKey, 3)
+	_, _, err = c.ActionRaw(ctx, "m:reboot", input)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 3, len(keys))
+	fc.AssertEqual(t, 32, len(keys[0]))
+	fc.AssertEqual(t, keys[0], keys[2])
+	fc.AssertEqual(t, `{"delay":10}`, bodies[2])
+
+	// gives up
+	c = newClient(RandomIdempotencyKey, 1)
+	_, _, err = c.ActionRaw(ctx, "m:reboot", input)
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, 2, len(keys))
+
+	// no key, no retry
+	c = newClient(nil, 3)
+	_, _, err = c.ActionRaw(ctx, "m:reboot", input)
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, 1, len(keys))
+	fc.AssertEqual(t, "", keys[0])
+
+	fc.AssertEqual(t, false, RandomIdempotencyKey() == RandomIdempotencyKey())
+}
+
+func TestClientActionCancel(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		rpc reboot {}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	abandoned := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			return
+		}
+		// slow action
+		select {
+		case <-r.Context().Done():
+			close(abandoned)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+	var canceledUrl, canceledKey string
+	c := newTestClient(srv, m)
+	c.idempotencyKey = func() string { return "k1" }
+	c.actionRetries = 3
+	// credentials are not handed to hook
+	c.address, err = NewAddress(strings.Replace(srv.URL, "://", "://u:secret@", 1) + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	c.actionCanceled = func(url string, key string) {
+		canceledUrl, canceledKey = url, key
+	}
+	b, err := c.Browser("m")
+	fc.AssertEqual(t, nil, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	t0 := time.Now()
+	err = b.RootWithContext(ctx).Find("reboot").Action(nil).LastErr
+	fc.AssertEqual(t, true, errors.Is(err, context.DeadlineExceeded))
+	if time.Since(t0) > 2*time.Second {
+		t.Error("action was not cancelled")
+	}
+	select {
+	case <-abandoned:
+	case <-time.After(2 * time.Second):
+		t.Error("device never saw request cancelled")
+	}
+	fc.AssertEqual(t, redactUrl(c.address.Data+"m:reboot"), canceledUrl)
+	fc.AssertEqual(t, false, strings.Contains(canceledUrl, "secret"))
+	fc.AssertEqual(t, "k1", canceledKey)
+}
+
+func TestRetryableAction(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "x", Err: &net.OpError{Op: "dial", Err: errors.New("refused")}}
+	ctx := context.Background()
+	fc.AssertEqual(t, true, retryableAction(ctx, nil, refused))
+	fc.AssertEqual(t, true, retryableAction(ctx, &http.Response{StatusCode: 503}, nil))
+	fc.AssertEqual(t, false, retryableAction(ctx, &http.Response{StatusCode: 500}, nil))
+	fc.AssertEqual(t, false, retryableAction(ctx, nil, &url.Error{Op: "Post", URL: "x", Err: context.Canceled}))
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	fc.AssertEqual(t, false, retryableAction(canceled, nil, refused))
+}
@@ -0,0 +1,157 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// GetByInstanceID reads resource an instance-identifier such as
+// "/m:a/b[id='x'][n='2']/c" points to, from an error-path or a leafref for
+// example.  Prefixes may be module names (RFC 7951) or module prefixes
+// (RFC 7950).  Keys may be in any order but all keys are required. Leaf-list
+// and position predicates are not supported.
+func (self *client) GetByInstanceID(ctx context.Context, iid string) (node.Node, error) {
+	p, err := self.parseInstanceID(iid)
+	if err != nil {
+		return nil, err
+	}
+	return self.clientDo(ctx, "GET", "", p, nil)
+}
+
+// iidSegment is a node-identifier and it's key predicates
+type iidSegment struct {
+	prefix string
+	ident  string
+	keys   map[string]string
+}
+
+func (self *client) parseInstanceID(iid string) (*node.Path, error) {
+	segs, err := parseInstanceIDSegments(iid)
+	if err != nil {
+		return nil, err
+	}
+	if segs[0].prefix == "" {
+		return nil, fmt.Errorf("%w. %s first node needs a prefix", fc.BadRequestError, iid)
+	}
+	m, err := self.iidModule(segs[0].prefix)
+	if err != nil {
+		return nil, err
+	}
+	p := node.NewRootPath(m)
+	var parent meta.HasDefinitions = m
+	for _, seg := range segs {
+		def := meta.Find(parent, seg.ident)
+		if def == nil {
+			return nil, fmt.Errorf("%w. %s not found in %s", fc.NotFoundError, seg.ident, iid)
+		}
+		switch x := def.(type) {
+		case *meta.List:
+			keyMeta := x.KeyMeta()
+			if len(seg.keys) != len(keyMeta) {
+				return nil, fmt.Errorf("%w. %s needs keys %v in %s", fc.BadRequestError, x.Ident(), x.KeyMeta(), iid)
+			}
+			keyStrs := make([]string, len(keyMeta))
+			for i, k := range keyMeta {
+				v, found := seg.keys[k.Ident()]
+				if !found {
+					return nil, fmt.Errorf("%w. %s missing key %s in %s", fc.BadRequestError, x.Ident(), k.Ident(), iid)
+				}
+				keyStrs[i] = v
+			}
+			key, err := node.NewValuesByString(keyMeta, keyStrs...)
+			if err != nil {
+				return nil, fmt.Errorf("%w. %s", fc.BadRequestError, err)
+			}
+			p = node.NewListItemPath(p, x, key)
+			parent = x
+		case meta.HasDefinitions:
+			if len(seg.keys) > 0 {
+				return nil, fmt.Errorf("%w. %s has no keys in %s", fc.BadRequestError, x.Ident(), iid)
+			}
+			p = node.NewContainerPath(p, x)
+			parent = x
+		default:
+			return nil, fmt.Errorf("%w. %s in %s is not a container or list", fc.BadRequestError, def.Ident(), iid)
+		}
+	}
+	return p, nil
+}
+
+// iidModule is module by it's name or it's prefix
+func (self *client) iidModule(prefix string) (*meta.Module, error) {
+	for _, m := range self.Modules() {
+		if m.Ident() != prefix && m.Prefix() == prefix {
+			return m, nil
+		}
+	}
+	return self.module(self.moduleIdent(prefix))
+}
+
+// parseInstanceIDSegments is syntax of instance-identifier from RFC 7950
+// section 9.13 w/only key predicates
+func parseInstanceIDSegments(iid string) ([]iidSegment, error) {
+	bad := func(msg string) error {
+		return fmt.Errorf("%w. invalid instance-identifier %q, %s", fc.BadRequestError, iid, msg)
+	}
+	if !strings.HasPrefix(iid, "/") {
+		return nil, bad("expected '/'")
+	}
+	var segs []iidSegment
+	rest := iid
+	for len(rest) > 0 {
+		rest = rest[1:]
+		end := strings.IndexAny(rest, "/[")
+		if end < 0 {
+			end = len(rest)
+		}
+		var seg iidSegment
+		seg.ident = strings.TrimSpace(rest[:end])
+		if colon := strings.IndexRune(seg.ident, ':'); colon >= 0 {
+			seg.prefix, seg.ident = seg.ident[:colon], seg.ident[colon+1:]
+		}
+		if seg.ident == "" {
+			return nil, bad("expected identifier")
+		}
+		rest = rest[end:]
+		for strings.HasPrefix(rest, "[") {
+			eq := strings.IndexRune(rest, '=')
+			if eq < 0 {
+				return nil, bad("position predicates not supported")
+			}
+			name := strings.TrimSpace(rest[1:eq])
+			if name == "." {
+				return nil, bad("leaf-list predicates not supported")
+			}
+			if colon := strings.IndexRune(name, ':'); colon >= 0 {
+				name = name[colon+1:]
+			}
+			value := strings.TrimLeft(rest[eq+1:], " ")
+			if len(value) == 0 || (value[0] != '\'' && value[0] != '"') {
+				return nil, bad("expected quoted key")
+			}
+			closeQuote := strings.IndexByte(value[1:], value[0])
+			if closeQuote < 0 {
+				return nil, bad("missing closing quote")
+			}
+			after := strings.TrimLeft(value[closeQuote+2:], " ")
+			if !strings.HasPrefix(after, "]") {
+				return nil, bad("missing ']'")
+			}
+			if seg.keys == nil {
+				seg.keys = make(map[string]string)
+			}
+			seg.keys[name] = value[1 : closeQuote+1]
+			rest = after[1:]
+		}
+		if len(rest) > 0 && rest[0] != '/' {
+			return nil, bad(fmt.Sprintf("unexpected %q", rest))
+		}
+		segs = append(segs, seg)
+	}
+	return segs, nil
+}
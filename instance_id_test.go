@@ -0,0 +1,71 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientGetByInstanceID(t *testing.T) {
+	var urlPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath = r.URL.EscapedPath()
+		fmt.Fprint(w, `{"c":"hi"}`)
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "x"; revision 0;
+		container a {
+			leaf c { type string; }
+			list b {
+				key "id n";
+				leaf id { type string; }
+				leaf n { type int32; }
+				container d {
+					leaf c { type string; }
+				}
+			}
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+
+	n, err := c.GetByInstanceID(ctx, "/m:a")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "/restconf/data/m:a", urlPath)
+	actual, err := nodeutil.WriteJSON(node.Selection{
+		Node:        n,
+		Path:        node.NewRootPath(meta.Find(m, "a")),
+		Constraints: &node.Constraints{},
+		Context:     ctx,
+	})
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"c":"hi"}`, actual)
+
+	_, err = c.GetByInstanceID(ctx, `/x:a/x:b[n="2"][id='p/q']/d`)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "/restconf/data/m:a/b=p%2Fq,2/d", urlPath)
+
+	tests := []string{
+		"m:a",
+		"/a",
+		"/m:a/nope",
+		"/m:a/b[id='p']/d",
+		"/m:a/b[1]",
+		"/m:a[id='p']",
+		"/m:a/b[id='p][n='2']",
+		"/m:a/c",
+	}
+	for _, iid := range tests {
+		_, err = c.GetByInstanceID(ctx, iid)
+		fc.AssertEqual(t, true, err != nil)
+	}
+}
@@ -0,0 +1,95 @@
+package restconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// namespaceChangesOnly drops module prefix from members whose enclosing
+// member already has that prefix so prefixes are only where namespace
+// changes as RFC 7951 section 4 has it.
+//
+//	{"m:x":{"m:y":1,"other:z":{"other:a":2}}} => {"m:x":{"y":1,"other:z":{"a":2}}}
+func namespaceChangesOnly(doc []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var out bytes.Buffer
+	if err := copyNamespaced(dec, &out, ""); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func copyNamespaced(dec *json.Decoder, out *bytes.Buffer, ns string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, isDelim := tok.(json.Delim)
+	if !isDelim {
+		scalar, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		out.Write(scalar)
+		return nil
+	}
+	if delim == '[' {
+		out.WriteByte('[')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := copyNamespaced(dec, out, ns); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+		_, err = dec.Token()
+		return err
+	}
+	out.WriteByte('{')
+	for i := 0; dec.More(); i++ {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		ident := tok.(string)
+		childNs := ns
+		if colon := strings.IndexRune(ident, ':'); colon > 0 {
+			if prefix := ident[:colon]; prefix == ns {
+				ident = ident[colon+1:]
+			} else {
+				childNs = prefix
+			}
+		}
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		key, _ := json.Marshal(ident)
+		out.Write(key)
+		out.WriteByte(':')
+		if err := copyNamespaced(dec, out, childNs); err != nil {
+			return err
+		}
+	}
+	out.WriteByte('}')
+	_, err = dec.Token()
+	return err
+}
+
+// namespacePayload is payload w/prefixes only on namespace changes.  Payload
+// is read entirely so streamed edits are buffered.
+func namespacePayload(payload io.Reader) (io.Reader, error) {
+	doc, err := ioutil.ReadAll(payload)
+	if err != nil || len(bytes.TrimSpace(doc)) == 0 {
+		return bytes.NewReader(doc), err
+	}
+	if doc, err = namespaceChangesOnly(doc); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(doc), nil
+}
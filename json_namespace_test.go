@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestNamespaceChangesOnly(t *testing.T) {
+	tests := []struct {
+		qualified string
+		expected  string
+	}{
+		{
+			qualified: `{"m:x":{"m:y":1,"other:z":{"other:a":2.50,"m:b":[{"m:c":true}]}}}`,
+			expected:  `{"m:x":{"y":1,"other:z":{"a":2.50,"m:b":[{"c":true}]}}}`,
+		},
+		{
+			qualified: `{"a":null,"b":["x","y"]}`,
+			expected:  `{"a":null,"b":["x","y"]}`,
+		},
+	}
+	for _, test := range tests {
+		actual, err := namespaceChangesOnly([]byte(test.qualified))
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, string(actual))
+	}
+	_, err := namespaceChangesOnly([]byte(`{"a":`))
+	fc.AssertEqual(t, true, err != nil)
+}
+
+func TestClientJSONNamespaceChangesOnly(t *testing.T) {
+	var sent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		sent = string(body)
+	}))
+	defer srv.Close()
+	m := requestBuilder{}.m(`container x { list y { key "id"; leaf id { type string; } } }`)
+	c := newTestClient(srv, m)
+	edits := []PatchEdit{
+		{Id: "1", Operation: PatchCreate, Target: "/y=a", Value: map[string]interface{}{"m:y": []interface{}{map[string]interface{}{"m:id": "a"}}}},
+	}
+	ctx := context.Background()
+	err := c.YangPatch(ctx, "m:x", "p1", edits)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"ietf-yang-patch:yang-patch":{"patch-id":"p1","edit":[{"edit-id":"1","operation":"create","target":"/y=a","value":{"m:y":[{"m:id":"a"}]}}]}}`, sent)
+
+	c.namespaceChanges = true
+	err = c.YangPatch(ctx, "m:x", "p1", edits)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"ietf-yang-patch:yang-patch":{"patch-id":"p1","edit":[{"edit-id":"1","operation":"create","target":"/y=a","value":{"m:y":[{"id":"a"}]}}]}}`, sent)
+
+	// any JSON payload such as wrapped action input
+	p, _ := c.parsePath("m:x")
+	_, err = c.clientDo(ctx, "PUT", "", p, strings.NewReader(`{"m:x":{"m:y":[{"m:id":"b"}]}}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"m:x":{"y":[{"id":"b"}]}}`, sent)
+}
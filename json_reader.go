@@ -0,0 +1,308 @@
+package restconf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// ErrDuplicateKey is when device sent a list w/more than one entry w/same key
+// and Client.DuplicateKeys is DuplicateKeysStrict
+var ErrDuplicateKey = errors.New("list has duplicate key")
+
+// ErrDepthExceeded is when device sent data nested deeper than
+// Client.MaxDepth
+var ErrDepthExceeded = errors.New("data is nested too deep")
+
+// How lists device sent w/more than one entry w/same key are read.  See
+// Client.DuplicateKeys
+const (
+	// DuplicateKeysStrict fails reading list w/ErrDuplicateKey
+	DuplicateKeysStrict = "strict"
+
+	// DuplicateKeysKeepFirst skips all but first entry w/a key
+	DuplicateKeysKeepFirst = "keep-first"
+
+	// DuplicateKeysKeepLast skips all but last entry w/a key
+	DuplicateKeysKeepLast = "keep-last"
+)
+
+// readJSONIO is like nodeutil.ReadJSONIO but binary leaves are decoded from
+// base64 into []byte and numbers are decoded as leaf's type so 64-bit
+// integers keep their precision.  Anydata is passed thru as it was decoded
+// w/o checking it against any schema.
+func readJSONIO(in io.Reader) node.Node {
+	return jsonReader{}.read(in)
+}
+
+// jsonReader holds how lists w/duplicate keys are read, see
+// Client.DuplicateKeys, and how deep data may be nested, see
+// Client.MaxDepth
+type jsonReader struct {
+	duplicateKeys string
+	maxDepth      int
+
+	// levels below document, top-level nodes are 1
+	depth int
+}
+
+func (self jsonReader) read(in io.Reader) node.Node {
+	var data map[string]interface{}
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return node.ErrorNode{Err: err}
+	}
+	return self.container(data)
+}
+
+func jsonContainerReader(data map[string]interface{}) node.Node {
+	return jsonReader{}.container(data)
+}
+
+func (self jsonReader) container(data map[string]interface{}) node.Node {
+	return &nodeutil.Extend{
+		Base: nodeutil.JsonContainerReader(data),
+		OnChild: func(p node.Node, r node.ChildRequest) (node.Node, error) {
+			if r.New {
+				return p.Child(r)
+			}
+			switch x := data[r.Meta.Ident()].(type) {
+			case []interface{}:
+				nested, err := self.nested()
+				if err != nil {
+					return nil, err
+				}
+				return nested.list(x), nil
+			case map[string]interface{}:
+				nested, err := self.nested()
+				if err != nil {
+					return nil, err
+				}
+				return nested.container(x), nil
+			}
+			return p.Child(r)
+		},
+		OnNext: func(p node.Node, r node.ListRequest) (node.Node, []val.Value, error) {
+			// response is just the list as in { "x" : [...] }
+			if list, valid := data[r.Meta.Ident()].([]interface{}); valid && len(data) == 1 {
+				nested, err := self.nested()
+				if err != nil {
+					return nil, nil, err
+				}
+				return nested.list(list).Next(r)
+			}
+			return p.Next(r)
+		},
+		OnField: func(p node.Node, r node.FieldRequest, hnd *node.ValueHandle) error {
+			if !r.Write {
+				readAnnotations(r, data)
+				if v, found := data[r.Meta.Ident()]; found {
+					data[r.Meta.Ident()] = jsonNumbers(r.Meta, v)
+				}
+			}
+			if !r.Write && isBinary(r.Meta) {
+				var err error
+				hnd.Val, err = decodeBinary(r.Meta, data[r.Meta.Ident()])
+				return err
+			}
+			return p.Field(r, hnd)
+		},
+	}
+}
+
+// nested is reader for data one level below self's
+func (self jsonReader) nested() (jsonReader, error) {
+	self.depth++
+	if self.maxDepth > 0 && self.depth > self.maxDepth {
+		return self, fmt.Errorf("%w. more than %d levels", ErrDepthExceeded, self.maxDepth)
+	}
+	return self, nil
+}
+
+func (self jsonReader) list(all []interface{}) node.Node {
+	var list []interface{}
+	var listErr error
+	deduped := false
+	return &nodeutil.Basic{
+		OnNext: func(r node.ListRequest) (node.Node, []val.Value, error) {
+			if r.New {
+				panic("Cannot write to JSON reader")
+			}
+			keyMeta := r.Meta.KeyMeta()
+			if !deduped {
+				list, listErr = self.dedupe(r.Meta, all)
+				deduped = true
+			}
+			if listErr != nil {
+				return nil, nil, listErr
+			}
+			if len(r.Key) > 0 {
+				if !r.First {
+					return nil, nil, nil
+				}
+				for _, entry := range list {
+					candidate, _ := entry.(map[string]interface{})
+					key, err := jsonKey(keyMeta, candidate)
+					if err != nil {
+						return nil, nil, err
+					}
+					if val.EqualVals(key, r.Key) {
+						return self.container(candidate), r.Key, nil
+					}
+				}
+				return nil, nil, nil
+			}
+			if r.Row >= len(list) {
+				return nil, nil, nil
+			}
+			entry, _ := list[r.Row].(map[string]interface{})
+			key, err := jsonKey(keyMeta, entry)
+			if err != nil {
+				return nil, nil, err
+			}
+			return self.container(entry), key, nil
+		},
+	}
+}
+
+// dedupe list entries w/same key according to policy
+func (self jsonReader) dedupe(m *meta.List, list []interface{}) ([]interface{}, error) {
+	keyMeta := m.KeyMeta()
+	if self.duplicateKeys == "" || len(keyMeta) == 0 {
+		return list, nil
+	}
+	keys := make([]string, len(list))
+	last := make(map[string]int, len(list))
+	for i, entry := range list {
+		candidate, _ := entry.(map[string]interface{})
+		key, err := jsonKey(keyMeta, candidate)
+		if err != nil {
+			return nil, err
+		}
+		strs := make([]string, len(key))
+		for j, k := range key {
+			strs[j] = k.String()
+		}
+		keys[i] = strings.Join(strs, ",")
+		if _, dup := last[keys[i]]; dup {
+			switch self.duplicateKeys {
+			case DuplicateKeysStrict:
+				return nil, fmt.Errorf("%w. %s=%s", ErrDuplicateKey, m.Ident(), keys[i])
+			case DuplicateKeysKeepFirst:
+				continue
+			}
+		}
+		last[keys[i]] = i
+	}
+	deduped := make([]interface{}, 0, len(last))
+	for i, entry := range list {
+		if last[keys[i]] == i {
+			deduped = append(deduped, entry)
+		}
+	}
+	return deduped, nil
+}
+
+func jsonKey(keyMeta []meta.Leafable, entry map[string]interface{}) ([]val.Value, error) {
+	if len(keyMeta) == 0 {
+		return nil, nil
+	}
+	keyData := make([]interface{}, len(keyMeta))
+	for i, k := range keyMeta {
+		keyData[i] = jsonNumbers(k, entry[k.Ident()])
+	}
+	return node.NewValues(keyMeta, keyData...)
+}
+
+func isBinary(m meta.Leafable) bool {
+	f := m.Type().Format()
+	return f == val.FmtBinary || f == val.FmtBinaryList
+}
+
+// decodeBinary from base64 string(s).  There is no binary value type so bytes
+// are held in val.Any which writers will encode back into base64.
+func decodeBinary(m meta.Leafable, data interface{}) (val.Value, error) {
+	switch x := data.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return val.Any{Thing: x}, nil
+	case string:
+		b, err := base64.StdEncoding.DecodeString(x)
+		if err != nil {
+			return nil, fmt.Errorf("%s. %w", m.Ident(), err)
+		}
+		return val.Any{Thing: b}, nil
+	case []interface{}:
+		items := make([][]byte, len(x))
+		for i, item := range x {
+			v, err := decodeBinary(m, item)
+			if err != nil {
+				return nil, err
+			}
+			items[i], _ = v.Value().([]byte)
+		}
+		return val.Any{Thing: items}, nil
+	}
+	return nil, fmt.Errorf("%s. cannot decode binary from %T", m.Ident(), data)
+}
+
+// jsonNumbers converts numbers, left as json.Number by decoder, to leaf's
+// type.  Anything else is left alone.
+func jsonNumbers(m meta.Leafable, data interface{}) interface{} {
+	switch x := data.(type) {
+	case json.Number:
+		return jsonNumber(m.Type().Format().Single(), x)
+	case []interface{}:
+		if !m.Type().Format().IsList() {
+			return data
+		}
+		items := make([]interface{}, len(x))
+		for i, item := range x {
+			if n, isNum := item.(json.Number); isNum {
+				items[i] = jsonNumber(m.Type().Format().Single(), n)
+			} else {
+				items[i] = item
+			}
+		}
+		return items
+	}
+	return data
+}
+
+func jsonNumber(f val.Format, n json.Number) interface{} {
+	switch f {
+	case val.FmtInt8, val.FmtInt16, val.FmtInt32, val.FmtInt64:
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+	case val.FmtUInt8, val.FmtUInt16, val.FmtUInt32, val.FmtUInt64:
+		if i, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return i
+		}
+	case val.FmtUnion, val.FmtLeafRef:
+		// type isn't known until union picks one, whole numbers are kept whole
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if i, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return i
+		}
+	}
+	// as nodeutil.ReadJSONIO would have it
+	f64, err := n.Float64()
+	if err != nil {
+		return n.String()
+	}
+	return f64
+}
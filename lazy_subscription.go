@@ -0,0 +1,255 @@
+package restconf
+
+import (
+	"context"
+	"sync"
+
+	"github.com/freeconf/yang/node"
+)
+
+// LazySubscription to a notification stream that only connects to device
+// while someone is reading so subscriptions nobody is watching hold no
+// connection.  Stream is opened when first attached subscriber asks for
+// Events and closed when last subscriber detaches.  Each reading subscriber
+// gets every event thru it's own buffer of Client.StreamBuffer events so
+// each should read on it's own goroutine, a subscriber w/a full buffer holds
+// up the rest.
+type LazySubscription struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	support clientSupport
+	p       *node.Path
+	buffer  int
+
+	lock        sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	stream      *Subscription
+	closed      bool
+}
+
+// Subscriber is attached to a LazySubscription
+type Subscriber struct {
+	// filled by relay, never closed
+	queue chan node.Node
+	// to reader, closed by forward only
+	events chan node.Node
+	// closed when subscriber detaches
+	detached chan struct{}
+	// closed when subscriber is dropped because stream ended
+	ended chan struct{}
+
+	readOnce  sync.Once
+	closeOnce sync.Once
+	lazy      *LazySubscription
+
+	// guarded by lazy's lock
+	reading bool
+	err     error
+}
+
+// LazySubscribe is like Subscribe but nothing is sent to device until a
+// subscriber reads
+func (self *client) LazySubscribe(ctx context.Context, path string) (*LazySubscription, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &LazySubscription{
+		ctx:         ctx,
+		cancel:      cancel,
+		support:     self.support,
+		p:           p,
+		buffer:      self.streamBuffer,
+		subscribers: make(map[*Subscriber]struct{}),
+	}, nil
+}
+
+// Attach a subscriber.  Nothing is sent to device until subscriber asks for
+// Events.
+func (self *LazySubscription) Attach() (*Subscriber, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.closed {
+		return nil, ErrStreamEnded
+	}
+	s := &Subscriber{
+		queue:    make(chan node.Node, self.buffer),
+		events:   make(chan node.Node),
+		detached: make(chan struct{}),
+		ended:    make(chan struct{}),
+		lazy:     self,
+	}
+	self.subscribers[s] = struct{}{}
+	go s.forward()
+	return s, nil
+}
+
+// Active is whether stream to device is open
+func (self *LazySubscription) Active() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.stream != nil
+}
+
+// Close stream and detach all subscribers
+func (self *LazySubscription) Close() error {
+	self.cancel()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.closed = true
+	self.stopStream(nil)
+	return nil
+}
+
+// read starts relaying events to subscriber, opening stream if nobody else
+// is reading
+func (self *LazySubscription) read(s *Subscriber) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, attached := self.subscribers[s]; !attached {
+		return
+	}
+	s.reading = true
+	if self.stream != nil {
+		return
+	}
+	stream, err := subscribe(self.ctx, self.support, self.p)
+	if err != nil {
+		s.err = err
+		self.end(s)
+		return
+	}
+	self.stream = stream
+	go self.relay(stream)
+}
+
+// relay events to every reading subscriber until stream ends.  Lock is only
+// held to learn who is reading so a slow subscriber never blocks attaching
+// or detaching.
+func (self *LazySubscription) relay(stream *Subscription) {
+	for n := range stream.Events() {
+		self.lock.Lock()
+		if self.stream != stream {
+			// stopped, draining
+			self.lock.Unlock()
+			continue
+		}
+		readers := make([]*Subscriber, 0, len(self.subscribers))
+		for s := range self.subscribers {
+			if s.reading {
+				readers = append(readers, s)
+			}
+		}
+		self.lock.Unlock()
+		for _, s := range readers {
+			select {
+			case s.queue <- n:
+			case <-s.detached:
+			case <-s.ended:
+			case <-self.ctx.Done():
+			}
+		}
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.stream == stream {
+		// device ended stream
+		self.stopStream(stream.Err())
+	}
+}
+
+// stopStream closes stream and ends all subscribers, lock must be held
+func (self *LazySubscription) stopStream(err error) {
+	if self.stream != nil {
+		self.stream.Close()
+		self.stream = nil
+	}
+	for s := range self.subscribers {
+		s.err = err
+		self.end(s)
+	}
+}
+
+// end drops subscriber, events already queued are still delivered.  Lock
+// must be held.
+func (self *LazySubscription) end(s *Subscriber) {
+	close(s.ended)
+	delete(self.subscribers, s)
+}
+
+func (self *LazySubscription) detach(s *Subscriber) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, attached := self.subscribers[s]; !attached {
+		return
+	}
+	delete(self.subscribers, s)
+	if len(self.subscribers) == 0 {
+		self.stopStream(nil)
+	}
+}
+
+// forward queued events to reader until subscriber detaches or, after
+// delivering what's queued, stream ends
+func (self *Subscriber) forward() {
+	defer close(self.events)
+	for {
+		select {
+		case n := <-self.queue:
+			if !self.send(n) {
+				return
+			}
+		case <-self.detached:
+			return
+		case <-self.ended:
+			for {
+				select {
+				case n := <-self.queue:
+					if !self.send(n) {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (self *Subscriber) send(n node.Node) bool {
+	select {
+	case self.events <- n:
+		return true
+	case <-self.detached:
+		return false
+	}
+}
+
+// Events from device, first call opens stream if it's not open already.
+// Channel is closed when subscriber detaches or stream ends.
+func (self *Subscriber) Events() <-chan node.Node {
+	self.readOnce.Do(func() {
+		self.lazy.read(self)
+	})
+	return self.events
+}
+
+// Err is why stream ended such as it could not be opened, nil if subscriber
+// or subscription was closed
+func (self *Subscriber) Err() error {
+	self.lazy.lock.Lock()
+	defer self.lazy.lock.Unlock()
+	return self.err
+}
+
+// Close detaches subscriber, closing stream if it was last one.  Signature
+// is compatible w/node.NotifyCloser.
+func (self *Subscriber) Close() error {
+	self.closeOnce.Do(func() {
+		// unblocks relay if it's waiting on this subscriber
+		close(self.detached)
+		self.lazy.detach(self)
+	})
+	return nil
+}
@@ -0,0 +1,135 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestLazySubscription(t *testing.T) {
+	var opened int32
+	hungUp := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&opened, 1)
+		fmt.Fprint(w, "id: 1\ndata: {\"n\":1}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		hungUp <- struct{}{}
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+
+	// nobody attached
+	lazy, err := c.LazySubscribe(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, nil, lazy.Close())
+	fc.AssertEqual(t, int32(0), atomic.LoadInt32(&opened))
+	_, err = lazy.Attach()
+	fc.AssertEqual(t, ErrStreamEnded, err)
+
+	// attached but never read
+	lazy, err = c.LazySubscribe(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	a, err := lazy.Attach()
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, nil, a.Close())
+	fc.AssertEqual(t, false, lazy.Active())
+	fc.AssertEqual(t, int32(0), atomic.LoadInt32(&opened))
+
+	// first reader opens stream, last subscriber closes it
+	a, err = lazy.Attach()
+	fc.AssertEqual(t, nil, err)
+	b, err := lazy.Attach()
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, lazy.Active())
+	// each subscriber reads on it's own
+	cursors := make(chan string, 2)
+	for _, s := range []*Subscriber{a, b} {
+		events := s.Events()
+		go func(events <-chan node.Node) {
+			cursor, _ := EventCursor(<-events)
+			cursors <- cursor
+		}(events)
+	}
+	fc.AssertEqual(t, true, lazy.Active())
+	fc.AssertEqual(t, "1", <-cursors)
+	fc.AssertEqual(t, "1", <-cursors)
+	fc.AssertEqual(t, int32(1), atomic.LoadInt32(&opened))
+	fc.AssertEqual(t, nil, a.Close())
+	fc.AssertEqual(t, true, lazy.Active())
+	_, open := <-a.Events()
+	fc.AssertEqual(t, false, open)
+	fc.AssertEqual(t, nil, b.Close())
+	fc.AssertEqual(t, nil, b.Close())
+	fc.AssertEqual(t, false, lazy.Active())
+	select {
+	case <-hungUp:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not closed")
+	}
+
+	// reattaching opens stream again
+	a, err = lazy.Attach()
+	fc.AssertEqual(t, nil, err)
+	<-a.Events()
+	fc.AssertEqual(t, int32(2), atomic.LoadInt32(&opened))
+	fc.AssertEqual(t, nil, lazy.Close())
+	for range a.Events() {
+	}
+	fc.AssertEqual(t, false, lazy.Active())
+	fc.AssertEqual(t, nil, a.Err())
+}
+
+func TestLazySubscriptionSlowReader(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "id: %d\ndata: {\"n\":%d}\n\n", i, i)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	lazy, err := c.LazySubscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	defer lazy.Close()
+	slow, err := lazy.Attach()
+	fc.AssertEqual(t, nil, err)
+	fast, err := lazy.Attach()
+	fc.AssertEqual(t, nil, err)
+	slow.Events()
+	<-fast.Events()
+
+	// relay waiting on slow reader does not hold up attaching or detaching
+	done := make(chan struct{})
+	go func() {
+		other, _ := lazy.Attach()
+		other.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked by slow reader")
+	}
+	fc.AssertEqual(t, nil, slow.Close())
+	cursor, _ := EventCursor(<-fast.Events())
+	fc.AssertEqual(t, "2", cursor)
+}
@@ -0,0 +1,90 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// TotalCountHeader is where servers that support it report how many entries
+// a list has in total regardless of how many entries were returned
+const TotalCountHeader = "X-Total-Count"
+
+type totalCountsKey struct{}
+
+// TotalCounts are number of entries in lists read using context from
+// WithTotalCounts as server reported them.  There is no standard for this in
+// RFC 8040 so servers that do not support it report nothing and count is
+// unknown.
+type TotalCounts struct {
+	param  string
+	lock   sync.Mutex
+	counts map[string]int
+}
+
+// WithTotalCounts collects total count of lists read w/returned context from
+// TotalCountHeader.  Param, such as "count=true", is sent when reading lists
+// for servers that only count when asked.  Empty sends nothing.
+//
+//	ctx, counts := restconf.WithTotalCounts(ctx, "")
+//	sel := b.RootWithContext(ctx).Find("interfaces/interface")
+//	sel.First()
+//	total, known := counts.Get("ietf-interfaces:interfaces/interface")
+func WithTotalCounts(ctx context.Context, param string) (context.Context, *TotalCounts) {
+	counts := &TotalCounts{param: param, counts: make(map[string]int)}
+	return context.WithValue(ctx, totalCountsKey{}, counts), counts
+}
+
+func totalCountsFrom(ctx context.Context) (*TotalCounts, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	counts, found := ctx.Value(totalCountsKey{}).(*TotalCounts)
+	return counts, found
+}
+
+// Get is total count of list in module:path form.  False if list was not
+// read or server did not say.
+func (self *TotalCounts) Get(path string) (int, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	count, found := self.counts[path]
+	return count, found
+}
+
+// TotalCount of list sel is iterating if it was read w/context from
+// WithTotalCounts.  False if unknown.
+func TotalCount(sel node.Selection) (int, bool) {
+	counts, found := totalCountsFrom(sel.Context)
+	if !found {
+		return 0, false
+	}
+	p := sel.Path
+	if p.Key() != nil {
+		// list entry
+		p = p.SetKey(nil)
+	}
+	return counts.Get(listCountPath(p))
+}
+
+// record count from response to reading entire list at p
+func (self *TotalCounts) record(p *node.Path, header http.Header) {
+	if _, isList := p.Meta().(*meta.List); !isList || p.Key() != nil {
+		return
+	}
+	count, err := strconv.Atoi(header.Get(TotalCountHeader))
+	if err != nil || count < 0 {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.counts[listCountPath(p)] = count
+}
+
+func listCountPath(p *node.Path) string {
+	return meta.RootModule(p.Meta()).Ident() + ":" + urlPath(p)
+}
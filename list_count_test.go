@@ -0,0 +1,72 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestTotalCounts(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			list y { key "id"; leaf id { type int32; } }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var count string
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		if count != "" {
+			w.Header().Set(TotalCountHeader, count)
+		}
+		fmt.Fprint(w, `{"y":[{"id":1},{"id":2}]}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	first := func(ctx context.Context) node.Selection {
+		b := node.NewBrowser(m, c.newClientNode().node())
+		sel := b.RootWithContext(ctx).Find("x/y")
+		fc.AssertEqual(t, nil, sel.LastErr)
+		entry := sel.First()
+		fc.AssertEqual(t, nil, entry.Selection.LastErr)
+		return entry.Selection
+	}
+
+	// server counts
+	count = "57"
+	ctx, counts := WithTotalCounts(context.Background(), "count=true")
+	entry := first(ctx)
+	fc.AssertEqual(t, "count=true", query)
+	total, known := TotalCount(entry)
+	fc.AssertEqual(t, true, known)
+	fc.AssertEqual(t, 57, total)
+	total, known = counts.Get("m:x/y")
+	fc.AssertEqual(t, true, known)
+	fc.AssertEqual(t, 57, total)
+
+	// server does not count
+	count = ""
+	ctx, counts = WithTotalCounts(context.Background(), "")
+	entry = first(ctx)
+	fc.AssertEqual(t, "", query)
+	_, known = TotalCount(entry)
+	fc.AssertEqual(t, false, known)
+
+	// nonsense count is unknown
+	count = "lots"
+	ctx, _ = WithTotalCounts(context.Background(), "")
+	_, known = TotalCount(first(ctx))
+	fc.AssertEqual(t, false, known)
+
+	// not asked for
+	count = "57"
+	_, known = TotalCount(first(context.Background()))
+	fc.AssertEqual(t, false, known)
+}
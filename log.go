@@ -0,0 +1,84 @@
+package restconf
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Logger receives structured records of requests and notification streams.
+// See SlogLogger to log thru log/slog.
+type Logger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields ...LogField)
+}
+
+// LogLevel of a record w/same values as log/slog's levels
+type LogLevel int
+
+const (
+	LogDebug LogLevel = -4
+	LogInfo  LogLevel = 0
+	LogWarn  LogLevel = 4
+)
+
+// LogField is a named value of a record such as "status" and 200
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// deviceLogger tags every record w/device so logs from many devices can be
+// told apart
+func deviceLogger(logger Logger, address Address) Logger {
+	if logger == nil {
+		return nil
+	}
+	device := address.DeviceId
+	if device == "" {
+		device = redactUrl(address.Base)
+	}
+	return taggedLogger{logger: logger, field: LogField{"device", device}}
+}
+
+type taggedLogger struct {
+	logger Logger
+	field  LogField
+}
+
+func (self taggedLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	self.logger.Log(ctx, level, msg, append([]LogField{self.field}, fields...)...)
+}
+
+// logRequest after response or error.  W/o a logger, request was already
+// logged by fc before it was sent.
+func (self *client) logRequest(ctx context.Context, method string, url string, start time.Time, resp *http.Response, err error) {
+	if self.logger == nil {
+		return
+	}
+	fields := []LogField{
+		{"method", method},
+		{"url", redactUrl(url)},
+		{"duration", time.Since(start)},
+	}
+	level := LogDebug
+	if err != nil {
+		level = LogWarn
+		fields = append(fields, LogField{"error", err.Error()})
+	} else {
+		fields = append(fields, LogField{"status", resp.StatusCode})
+		if resp.StatusCode >= 400 {
+			level = LogWarn
+		}
+	}
+	self.log(ctx, level, "request", fields...)
+}
+
+// log is a structured record if there is a logger otherwise false so caller
+// can fall back to fc logging
+func (self *client) log(ctx context.Context, level LogLevel, msg string, fields ...LogField) bool {
+	if self.logger == nil {
+		return false
+	}
+	self.logger.Log(ctx, level, msg, fields...)
+	return true
+}
@@ -0,0 +1,29 @@
+//go:build go1.21
+// +build go1.21
+
+package restconf
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger sends records to logger.  Only available when built w/go 1.21
+// or later.
+//
+//	factory := restconf.Client{Logger: restconf.SlogLogger(slog.Default())}
+func SlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (self slogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.Key, f.Value)
+	}
+	self.logger.LogAttrs(ctx, slog.Level(level), msg, attrs...)
+}
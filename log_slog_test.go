@@ -0,0 +1,58 @@
+//go:build go1.21
+// +build go1.21
+
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestClientLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "DELETE" {
+			http.Error(w, "nope", 403)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	address, _ := NewAddress(srv.URL + "/restconf=dev1")
+	c := newTestClient(srv)
+	c.address = address
+	c.logger = deviceLogger(SlogLogger(logger), address)
+	p := requestBuilder{}.path(`container x {}`)
+	ctx := context.Background()
+	c.clientDo(ctx, "GET", "", p, nil)
+	c.clientDo(ctx, "DELETE", "", p, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	fc.AssertEqual(t, 2, len(lines))
+	var get, del map[string]interface{}
+	fc.AssertEqual(t, nil, json.Unmarshal([]byte(lines[0]), &get))
+	fc.AssertEqual(t, nil, json.Unmarshal([]byte(lines[1]), &del))
+	fc.AssertEqual(t, "request", get["msg"])
+	fc.AssertEqual(t, "DEBUG", get["level"])
+	fc.AssertEqual(t, "dev1", get["device"])
+	fc.AssertEqual(t, "GET", get["method"])
+	fc.AssertEqual(t, srv.URL+"/restconf=dev1/data/m:x", get["url"])
+	fc.AssertEqual(t, float64(200), get["status"])
+	_, hasDuration := get["duration"]
+	fc.AssertEqual(t, true, hasDuration)
+	fc.AssertEqual(t, "WARN", del["level"])
+	fc.AssertEqual(t, float64(403), del["status"])
+
+	// no logger falls back to fc
+	c.logger = nil
+	fc.AssertEqual(t, false, c.log(ctx, LogInfo, "x"))
+}
@@ -0,0 +1,80 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/parser"
+)
+
+type recordingLogger struct {
+	lock sync.Mutex
+	urls []string
+}
+
+func (self *recordingLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...LogField) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, f := range fields {
+		if f.Key == "url" || f.Key == "device" {
+			self.urls = append(self.urls, fmt.Sprint(f.Value))
+		}
+	}
+}
+
+func TestClientLogRedactsUrl(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+		notification n {}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == mimeEventStream {
+			w.Header().Set("Content-Type", mimeEventStream)
+			fmt.Fprint(w, "data: {}\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"a":1}`)
+	}))
+	defer srv.Close()
+	address, err := NewAddress(strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	recorder := &recordingLogger{}
+	newClient := func(logger Logger) *client {
+		c := newTestClient(srv, m)
+		c.address = address
+		c.logger = deviceLogger(logger, address)
+		return c
+	}
+	read := func(c *client) {
+		_, err := c.clientDo(context.Background(), "GET", "a=1&access_token=abc", node.NewContainerPath(node.NewRootPath(m), m.DataDefinitions()[0].(meta.HasDefinitions)), nil)
+		fc.AssertEqual(t, nil, err)
+		stream, err := c.clientStream("", node.NewContainerPath(node.NewRootPath(m), m.Notifications()["n"]), context.Background())
+		fc.AssertEqual(t, nil, err)
+		for range stream {
+		}
+	}
+
+	read(newClient(recorder))
+	fc.AssertEqual(t, true, len(recorder.urls) > 0)
+	for _, url := range recorder.urls {
+		fc.AssertEqual(t, false, strings.Contains(url, "secret") || strings.Contains(url, "abc"))
+	}
+
+	var buf bytes.Buffer
+	fc.Info.SetOutput(&buf)
+	defer fc.Info.SetOutput(os.Stdout)
+	read(newClient(nil))
+	fc.AssertEqual(t, true, strings.Contains(buf.String(), "user:REDACTED@"))
+	fc.AssertEqual(t, false, strings.Contains(buf.String(), "secret") || strings.Contains(buf.String(), "abc"))
+}
@@ -0,0 +1,71 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// longPollEvents is alternative to SSE where each GET waits on device until
+// there is a notification, or until device gives up waiting, and is then
+// immediately sent again.  Device sends notification as JSON body and nothing,
+// usually w/204 No Content, when it gave up waiting.  Events end when ctx is
+// done, closer is called or device fails a request.
+func (self *client) longPollEvents(ctx context.Context, fullUrl string, done <-chan struct{}) (<-chan sseFrame, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	events := make(chan sseFrame)
+	go func() {
+		defer close(events)
+		for {
+			data, err := self.poll(ctx, fullUrl)
+			if err != nil {
+				if ctx.Err() == nil {
+					if !self.log(ctx, LogWarn, "stream failed", LogField{"url", redactUrl(fullUrl)}, LogField{"error", err.Error()}) {
+						fc.Err.Printf("long poll %s failed. %s", redactUrl(fullUrl), err)
+					}
+				}
+				return
+			}
+			if len(data) == 0 {
+				continue
+			}
+			select {
+			case events <- sseFrame{data: data}:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return events, cancel
+}
+
+// poll is a single request that waits for next notification.  Empty data
+// means device timed out waiting.
+func (self *client) poll(ctx context.Context, fullUrl string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", fullUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", mimeYangDataJson)
+	self.setUserAgent(req)
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return nil, self.requestErr("GET", fullUrl, err)
+	}
+	defer resp.Body.Close()
+	self.protocol.Store(resp.Proto)
+	if err := self.responseErr(resp); err != nil {
+		return nil, self.requestErr("GET", fullUrl, err)
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(data), nil
+}
@@ -0,0 +1,154 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/parser"
+)
+
+// moduleCheck is when a module was last checked against device and
+// validators device sent w/it
+type moduleCheck struct {
+	checked      time.Time
+	etag         string
+	lastModified string
+}
+
+// moduleStale is whether module has been cached longer than
+// Client.ModuleMaxAge.  Caller must hold modulesLock.
+func (self *client) moduleStale(name string) bool {
+	if self.moduleMaxAge <= 0 {
+		return false
+	}
+	checked := self.schemaLoaded
+	if check, found := self.moduleChecks[name]; found {
+		checked = check.checked
+	}
+	return self.now().Sub(checked) > self.moduleMaxAge
+}
+
+func (self *client) now() time.Time {
+	if self.clock != nil {
+		return self.clock()
+	}
+	return time.Now()
+}
+
+// moduleFresh restarts module's max age.  Caller must hold modulesLock for
+// writing.
+func (self *client) moduleFresh(name string, resp *http.Response) {
+	if self.moduleMaxAge <= 0 {
+		return
+	}
+	if self.moduleChecks == nil {
+		self.moduleChecks = make(map[string]moduleCheck)
+	}
+	check := self.moduleChecks[name]
+	check.checked = self.now()
+	if resp != nil && resp.StatusCode == http.StatusOK {
+		check.etag = resp.Header.Get("ETag")
+		check.lastModified = resp.Header.Get("Last-Modified")
+	}
+	self.moduleChecks[name] = check
+}
+
+// moduleRevalidateTimeout limits how long asking device for a module again
+// can take before cached module is used
+var moduleRevalidateTimeout = 30 * time.Second
+
+// revalidateModule asks device for module w/a conditional GET and gives back
+// device's module if it has a different revision.  If device cannot be
+// asked, cached module is kept.  Only new browsers get new module, browsers
+// already made keep module they were made w/.  Caller must not hold
+// modulesLock, it's only taken to swap in new module.
+func (self *client) revalidateModule(name string, cached *meta.Module) *meta.Module {
+	ctx, cancel := context.WithTimeout(context.Background(), moduleRevalidateTimeout)
+	defer cancel()
+	resp, err := self.schemaRequest(ctx, name)
+	self.modulesLock.Lock()
+	self.moduleFresh(name, resp)
+	self.modulesLock.Unlock()
+	if err != nil {
+		fc.Debug.Printf("could not revalidate module %s, keeping cached copy. %s", name, err)
+		return cached
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode != http.StatusNotModified {
+			fc.Debug.Printf("could not revalidate module %s, keeping cached copy. %s", name, resp.Status)
+		}
+		return cached
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return cached
+	}
+	m, err := self.parseDeviceModule(name, body)
+	if err != nil {
+		fc.Debug.Printf("could not parse revalidated module %s, keeping cached copy. %s", name, err)
+		return cached
+	}
+	if revision(m) == revision(cached) {
+		return cached
+	}
+	self.modulesLock.Lock()
+	defer self.modulesLock.Unlock()
+	if current := self.modules[name]; current != cached {
+		// another caller already swapped it
+		return current
+	}
+	fc.Debug.Printf("module %s changed from revision %s to %s", name, revision(cached), revision(m))
+	self.modules[name] = m
+	return m
+}
+
+// parseDeviceModule is module from body device sent, imports still come
+// from where they usually do
+func (self *client) parseDeviceModule(name string, body []byte) (*meta.Module, error) {
+	ypath := func(n string, ext string) (io.Reader, error) {
+		if n == name && ext == ".yang" {
+			return bytes.NewReader(body), nil
+		}
+		return self.schemaPath(n, ext)
+	}
+	return parser.LoadModule(ypath, name)
+}
+
+func revision(m *meta.Module) string {
+	if rev := m.Revision(); rev != nil {
+		return rev.Ident()
+	}
+	return ""
+}
+
+func (self *client) schemaRequest(ctx context.Context, name string) (*http.Response, error) {
+	suffix := name + ".yang"
+	if self.schemaName != nil {
+		var err error
+		if suffix, err = self.schemaName(name, ".yang"); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", self.address.Schema+suffix, nil)
+	if err != nil {
+		return nil, err
+	}
+	self.modulesLock.RLock()
+	check := self.moduleChecks[name]
+	self.modulesLock.RUnlock()
+	if check.etag != "" {
+		req.Header.Set("If-None-Match", check.etag)
+	}
+	if check.lastModified != "" {
+		req.Header.Set("If-Modified-Since", check.lastModified)
+	}
+	self.setUserAgent(req)
+	return self.moduleReads.do(req, self.client.Do)
+}
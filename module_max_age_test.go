@@ -0,0 +1,111 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+)
+
+func TestClientModuleMaxAge(t *testing.T) {
+	schema := `module car { namespace "c"; prefix "c"; revision 2020-01-01; container x {} }`
+	etag := `"1"`
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(schema))
+	}))
+	defer srv.Close()
+	address, _ := NewAddress(srv.URL + "/restconf")
+	remote := httpStream{client: srv.Client(), url: address.Schema}
+	c := newTestClient(srv)
+	c.schemaPath = remote.OpenStream
+	c.moduleMaxAge = 20 * time.Millisecond
+	now := time.Now()
+	c.clock = func() time.Time { return now }
+	c.schemaLoaded = now
+	original, err := c.module("car")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 1, requests)
+	before, err := c.Browser("car")
+	fc.AssertEqual(t, nil, err)
+
+	// fresh
+	m, _ := c.module("car")
+	fc.AssertEqual(t, original, m)
+	fc.AssertEqual(t, 1, requests)
+
+	// stale but unchanged
+	now = now.Add(30 * time.Millisecond)
+	m, _ = c.module("car")
+	fc.AssertEqual(t, original, m)
+	fc.AssertEqual(t, 2, requests)
+	now = now.Add(30 * time.Millisecond)
+	m, _ = c.module("car")
+	fc.AssertEqual(t, original, m)
+	fc.AssertEqual(t, 1, notModified)
+
+	// device upgraded
+	schema = `module car { namespace "c"; prefix "c"; revision 2021-01-01; container x {} container y {} }`
+	etag = `"2"`
+	now = now.Add(30 * time.Millisecond)
+	m, _ = c.module("car")
+	fc.AssertEqual(t, "2021-01-01", m.Revision().Ident())
+	fc.AssertEqual(t, true, meta.Find(m, "y") != nil)
+	b, err := c.Browser("car")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, m, b.Meta)
+	// browsers already made keep module they were made w/
+	fc.AssertEqual(t, original, before.Meta)
+}
+
+func TestClientModuleRevalidateUnlocked(t *testing.T) {
+	hung := make(chan struct{})
+	release := make(chan struct{})
+	slowReads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/restconf/schema/slow.yang" {
+			if slowReads++; slowReads == 2 {
+				close(hung)
+				<-release
+			}
+		}
+		name := strings.TrimSuffix(path.Base(r.URL.Path), ".yang")
+		w.Header().Set("ETag", `"1"`)
+		fmt.Fprintf(w, `module %s { namespace "%s"; prefix "%s"; revision 2020-01-01; }`, name, name, name)
+	}))
+	defer srv.Close()
+	defer close(release)
+	address, _ := NewAddress(srv.URL + "/restconf")
+	remote := httpStream{client: srv.Client(), url: address.Schema}
+	c := newTestClient(srv)
+	c.schemaPath = remote.OpenStream
+	c.moduleMaxAge = time.Minute
+	now := time.Now()
+	c.clock = func() time.Time { return now }
+	c.schemaLoaded = now
+	_, err := c.module("slow")
+	fc.AssertEqual(t, nil, err)
+	_, err = c.module("fast")
+	fc.AssertEqual(t, nil, err)
+
+	now = now.Add(2 * time.Minute)
+	go c.module("slow")
+	<-hung
+	// other modules don't wait on slow device response
+	m, err := c.module("fast")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "fast", m.Ident())
+}
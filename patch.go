@@ -0,0 +1,91 @@
+package restconf
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+const (
+	mimeYangDataJson  = "application/yang-data+json"
+	mimeYangPatchJson = "application/yang-patch+json"
+)
+
+// AcceptedPatch is what media types server reported it accepts for PATCH on
+// resource in module:path form thru Accept-Patch header.  Nil means unknown.
+func (self *client) AcceptedPatch(path string) []string {
+	self.allowedLock.RLock()
+	defer self.allowedLock.RUnlock()
+	return self.acceptPatch[path]
+}
+
+func (self *client) setAcceptedPatch(path string, types []string) {
+	self.allowedLock.Lock()
+	defer self.allowedLock.Unlock()
+	if self.acceptPatch == nil {
+		self.acceptPatch = make(map[string][]string)
+	}
+	self.acceptPatch[path] = types
+}
+
+// patchEdit turns a PUT into a plain PATCH or a YANG Patch (RFC 8072)
+// depending on what server accepts.  If server accepts neither, request is
+// still a PUT.
+func (self *client) patchEdit(ctx context.Context, target string, p *node.Path, payload io.Reader) (string, string, io.Reader) {
+	codec := codecOrDefault(self.codec)
+	accepted := self.AcceptedPatch(target)
+	if accepted == nil {
+		// server that cannot answer is treated as accepting no patch formats
+		resp, err := self.send(ctx, "OPTIONS", "", p, nil)
+		if err != nil {
+			fc.Debug.Printf("could not learn patch formats of %s. %s", target, err)
+			self.setAcceptedPatch(target, []string{})
+		} else {
+			resp.Body.Close()
+		}
+		accepted = self.AcceptedPatch(target)
+	}
+	isJson := codec.MimeType() == JSONCodec.MimeType()
+	for _, mime := range accepted {
+		if mime == codec.MimeType() || (isJson && mime == mimeYangDataJson) {
+			return "PATCH", codec.MimeType(), payload
+		}
+	}
+	for _, mime := range accepted {
+		if isJson && mime == mimeYangPatchJson {
+			return "PATCH", mimeYangPatchJson, yangPatch(p, payload)
+		}
+	}
+	return "PUT", codec.MimeType(), payload
+}
+
+// yangPatch wraps JSON payload in a YANG Patch w/a single merge edit of
+// entire target resource
+func yangPatch(p *node.Path, payload io.Reader) io.Reader {
+	ident := meta.RootModule(p.Meta()).Ident() + ":" + p.Meta().Ident()
+	head, tail := `{`+jsonString(ident)+`:`, `}`
+	if _, isList := p.Meta().(*meta.List); isList {
+		head, tail = head+"[", "]"+tail
+	}
+	value := io.MultiReader(strings.NewReader(head), payload, strings.NewReader(tail))
+	return encodeYangPatch("freeconf", []yangPatchEdit{{id: "1", operation: PatchMerge, target: "/", value: value}})
+}
+
+// parseAcceptPatch reads media types from Accept-Patch header, ignoring any
+// media type parameters
+func parseAcceptPatch(header string) []string {
+	types := []string{}
+	for _, t := range strings.Split(header, ",") {
+		if semi := strings.IndexByte(t, ';'); semi >= 0 {
+			t = t[:semi]
+		}
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
@@ -0,0 +1,116 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestParseAcceptPatch(t *testing.T) {
+	fc.AssertEqual(t, "application/yang-patch+json,application/yang-data+json",
+		strings.Join(parseAcceptPatch("application/yang-patch+json, Application/YANG-Data+JSON; charset=utf-8"), ","))
+	fc.AssertEqual(t, 0, len(parseAcceptPatch("")))
+	fc.AssertEqual(t, true, parseAcceptPatch("") != nil)
+}
+
+func TestClientPatchEdits(t *testing.T) {
+	var acceptPatch string
+	var options int
+	var sent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			options++
+			if acceptPatch != "" {
+				w.Header().Set("Accept-Patch", acceptPatch)
+			}
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		sent = fmt.Sprint(r.Method, " ", r.Header.Get("Content-Type"), " ", string(body))
+	}))
+	defer srv.Close()
+	container := requestBuilder{}.path(`container x {}`)
+	list := requestBuilder{}.path(`list x { key id; leaf id { type string; } }`)
+	tests := []struct {
+		acceptPatch string
+		list        bool
+		expected    string
+	}{
+		{"application/yang-data+json", false, `PATCH application/json {"a":1}`},
+		{"application/yang-patch+json", false, `PATCH application/yang-patch+json {"ietf-yang-patch:yang-patch":{"patch-id":"freeconf","edit":[{"edit-id":"1","operation":"merge","target":"/","value":{"m:x":{"a":1}}}]}}`},
+		{"application/yang-patch+json", true, `PATCH application/yang-patch+json {"ietf-yang-patch:yang-patch":{"patch-id":"freeconf","edit":[{"edit-id":"1","operation":"merge","target":"/","value":{"m:x":[{"a":1}]}}]}}`},
+		{"application/yang-patch+json, application/json", false, `PATCH application/json {"a":1}`},
+		{"", false, `PUT application/json {"a":1}`},
+		{"application/yang-patch+xml", false, `PUT application/json {"a":1}`},
+	}
+	ctx := context.Background()
+	for _, test := range tests {
+		c := newTestClient(srv)
+		c.patchEdits = true
+		acceptPatch = test.acceptPatch
+		options = 0
+		p := container
+		if test.list {
+			p = list
+		}
+		_, err := c.clientDo(ctx, "PUT", "", p, strings.NewReader(`{"a":1}`))
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, test.expected, sent)
+
+		// accepted formats are remembered
+		_, err = c.clientDo(ctx, "PUT", "", p, strings.NewReader(`{"a":1}`))
+		fc.AssertEqual(t, nil, err)
+		fc.AssertEqual(t, 1, options)
+	}
+}
+
+func TestClientYangPatchIds(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { list y { key id; leaf id { type string; } } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var sent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fc.AssertEqual(t, "PATCH", r.Method)
+		fc.AssertEqual(t, mimeYangPatchJson, r.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(r.Body)
+		sent = string(body)
+		if strings.Contains(sent, `"fail"`) {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"ietf-yang-patch:yang-patch-status":{"patch-id":"p1","edit-status":{"edit":[
+				{"edit-id":"1","ok":[null]},
+				{"edit-id":"fail","errors":{"error":[{"error-type":"application","error-tag":"data-exists","error-message":"y=b exists"}]}}
+			]}}}`)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+	err = c.YangPatch(ctx, "m:x", "p1", []PatchEdit{
+		{Id: "1", Operation: PatchCreate, Target: "/y=a", Value: map[string]interface{}{"m:y": []interface{}{map[string]interface{}{"id": "a"}}}},
+		{Operation: PatchDelete, Target: "/y=z"},
+	})
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"ietf-yang-patch:yang-patch":{"patch-id":"p1","edit":[{"edit-id":"1","operation":"create","target":"/y=a","value":{"m:y":[{"id":"a"}]}},{"edit-id":"2","operation":"delete","target":"/y=z"}]}}`, sent)
+
+	err = c.YangPatch(ctx, "m:x", "p1", []PatchEdit{
+		{Operation: PatchDelete, Target: "/y=a"},
+		{Id: "fail", Operation: PatchCreate, Target: "/y=b", Value: map[string]interface{}{"m:y": []interface{}{map[string]interface{}{"id": "b"}}}},
+	})
+	var editErrs PatchErrors
+	fc.AssertEqual(t, true, errors.As(err, &editErrs))
+	fc.AssertEqual(t, 1, len(editErrs))
+	fc.AssertEqual(t, true, errors.Is(editErrs["fail"], fc.ConflictError))
+	fc.AssertEqual(t, "fail. conflict. (409) y=b exists", err.Error())
+
+	err = c.YangPatch(ctx, "m:x", "", []PatchEdit{{Id: "a"}, {Id: "a"}})
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+}
@@ -0,0 +1,45 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ModuleErrors are reasons each module could not be loaded
+type ModuleErrors map[string]error
+
+func (self ModuleErrors) Error() string {
+	names := make([]string, 0, len(self))
+	for name := range self {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = fmt.Sprintf("%s. %s", name, self[name])
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// PreloadModules loads and caches modules now instead of when they are first
+// browsed so missing or broken schema is found at startup.  Every module is
+// attempted and error is ModuleErrors w/each module that failed.  Modules
+// already loaded, including by concurrent preloads, are not loaded again.
+func (self *client) PreloadModules(ctx context.Context, names []string) error {
+	failed := make(ModuleErrors)
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			failed[name] = err
+			continue
+		}
+		if _, err := self.module(name); err != nil {
+			failed[name] = err
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
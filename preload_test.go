@@ -0,0 +1,61 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+)
+
+func TestClientPreloadModules(t *testing.T) {
+	var downloads int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/restconf/schema/car.yang":
+			atomic.AddInt32(&downloads, 1)
+			w.Write([]byte(`module car { namespace "c"; prefix "c"; revision 0; leaf speed { type int32; } }`))
+		case "/restconf/schema/broken.yang":
+			w.Write([]byte(`module broken {`))
+		default:
+			http.Error(w, "not found", 404)
+		}
+	}))
+	defer srv.Close()
+	schema := httpStream{client: srv.Client(), url: srv.URL + "/restconf/schema/"}
+	c := &client{
+		modules: make(map[string]*meta.Module),
+	}
+	c.schemaPath = schema.OpenStream
+	ctx := context.Background()
+
+	// concurrent preloads share download
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.PreloadModules(ctx, []string{"car"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	fc.AssertEqual(t, int32(1), atomic.LoadInt32(&downloads))
+	fc.AssertEqual(t, true, c.Modules()["car"] != nil)
+
+	err := c.PreloadModules(ctx, []string{"missing", "car", "broken"})
+	var failed ModuleErrors
+	fc.AssertEqual(t, true, errors.As(err, &failed))
+	fc.AssertEqual(t, 2, len(failed))
+	fc.AssertEqual(t, true, failed["missing"] != nil)
+	fc.AssertEqual(t, true, failed["broken"] != nil)
+	fc.AssertEqual(t, true, strings.HasPrefix(err.Error(), "broken. "))
+	fc.AssertEqual(t, int32(1), atomic.LoadInt32(&downloads))
+}
@@ -0,0 +1,493 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/source"
+)
+
+// BreakerState is health of connection to a device
+type BreakerState int
+
+const (
+	// BreakerClosed is healthy, requests go to device
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen is unhealthy, requests fail immediately w/ErrCircuitOpen
+	// until backoff has passed
+	BreakerOpen
+
+	// BreakerHalfOpen is reconnecting to device
+	BreakerHalfOpen
+)
+
+func (self BreakerState) String() string {
+	switch self {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(self))
+}
+
+// ErrCircuitOpen is when device is considered unhealthy and no request was
+// attempted
+var ErrCircuitOpen = errors.New("device unhealthy, circuit open")
+
+// Breaker configures when a ReconnectingDevice gives up on a device and how
+// often it tries to reconnect
+type Breaker struct {
+
+	// Consecutive transport failures before circuit opens.  Default is 3
+	FailureThreshold int
+
+	// How long circuit stays open before reconnecting.  Doubles after each
+	// failed reconnect up to MaxBackoff.  Default is 1s
+	Backoff time.Duration
+
+	// Default is 1m
+	MaxBackoff time.Duration
+}
+
+// ReconnectingDevice is a device that survives device restarts and network
+// outages.  On repeated transport errors device is marked unhealthy and, after
+// a backoff, device is bootstrapped again.  Browsers given out stay valid
+// across reconnects.
+type ReconnectingDevice struct {
+	url       string
+	breaker   Breaker
+	newDevice func(url string) (device.Device, error)
+
+	mu       sync.Mutex
+	current  *client
+	state    BreakerState
+	failures int
+	backoff  time.Duration
+	retryAt  time.Time
+}
+
+// NewReconnectingDevice connects to device now and reconnects as needed
+// according to breaker
+func (self Client) NewReconnectingDevice(url string, breaker Breaker) (*ReconnectingDevice, error) {
+	if breaker.FailureThreshold <= 0 {
+		breaker.FailureThreshold = 3
+	}
+	if breaker.Backoff <= 0 {
+		breaker.Backoff = time.Second
+	}
+	if breaker.MaxBackoff <= 0 {
+		breaker.MaxBackoff = time.Minute
+	}
+	d, err := self.NewDevice(url)
+	if err != nil {
+		return nil, err
+	}
+	return &ReconnectingDevice{
+		url:       url,
+		breaker:   breaker,
+		newDevice: self.NewDevice,
+		current:   d.(*client),
+		backoff:   breaker.Backoff,
+	}, nil
+}
+
+// State of circuit breaker
+func (self *ReconnectingDevice) State() BreakerState {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.state
+}
+
+// Healthy is when requests are being sent to device
+func (self *ReconnectingDevice) Healthy() bool {
+	return self.State() == BreakerClosed
+}
+
+func (self *ReconnectingDevice) SchemaSource() source.Opener {
+	return self.client().SchemaSource()
+}
+
+func (self *ReconnectingDevice) UiSource() source.Opener {
+	return self.client().UiSource()
+}
+
+func (self *ReconnectingDevice) Modules() map[string]*meta.Module {
+	return self.client().Modules()
+}
+
+// Browser sends all requests thru whatever connection is current at the time
+// so it can be held onto across reconnects
+func (self *ReconnectingDevice) Browser(module string) (*node.Browser, error) {
+	m, err := self.client().module(module)
+	if err != nil {
+		return nil, err
+	}
+	return node.NewBrowserSource(m, func() node.Node {
+		n := self.client().newClientNode()
+		n.support = self
+		return n.node()
+	}), nil
+}
+
+func (self *ReconnectingDevice) Close() {
+	self.client().Close()
+}
+
+func (self *ReconnectingDevice) client() *client {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.current
+}
+
+func (self *ReconnectingDevice) clientDo(ctx context.Context, method string, params string, p *node.Path, payload io.Reader) (node.Node, error) {
+	c, err := self.connect()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.support.clientDo(ctx, method, params, p, payload)
+	self.record(ctx, err)
+	return resp, err
+}
+
+func (self *ReconnectingDevice) clientStream(params string, p *node.Path, ctx context.Context) (<-chan node.Node, error) {
+	c, err := self.connect()
+	if err != nil {
+		return nil, err
+	}
+	events, err := c.support.clientStream(params, p, ctx)
+	self.record(ctx, err)
+	return events, err
+}
+
+// connect is current connection to device, reconnecting if circuit is open
+// and it's time to try again
+func (self *ReconnectingDevice) connect() (*client, error) {
+	self.mu.Lock()
+	switch {
+	case self.state == BreakerClosed:
+		defer self.mu.Unlock()
+		return self.current, nil
+	case self.state == BreakerHalfOpen, time.Now().Before(self.retryAt):
+		defer self.mu.Unlock()
+		return nil, fmt.Errorf("%w. %s", ErrCircuitOpen, self.url)
+	}
+	self.state = BreakerHalfOpen
+	self.mu.Unlock()
+
+	fc.Debug.Printf("reconnecting to %s", redactUrl(self.url))
+	d, err := self.newDevice(self.url)
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if err != nil {
+		self.backoff *= 2
+		if self.backoff > self.breaker.MaxBackoff {
+			self.backoff = self.breaker.MaxBackoff
+		}
+		self.open()
+		return nil, fmt.Errorf("%w. %s", ErrCircuitOpen, err)
+	}
+	self.current.Close()
+	self.current = d.(*client)
+	self.state = BreakerClosed
+	self.failures = 0
+	self.backoff = self.breaker.Backoff
+	return self.current, nil
+}
+
+// record outcome of a request.  Only transport errors count against device,
+// a 404 for example means device is perfectly healthy.
+func (self *ReconnectingDevice) record(ctx context.Context, err error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if !isTransportErr(err) || (ctx != nil && ctx.Err() != nil) {
+		// including requests caller gave up on
+		self.failures = 0
+		return
+	}
+	self.failures++
+	if self.state == BreakerClosed && self.failures >= self.breaker.FailureThreshold {
+		fc.Err.Printf("%s unhealthy after %d failures. %s", redactUrl(self.url), self.failures, err)
+		self.open()
+	}
+}
+
+func (self *ReconnectingDevice) open() {
+	self.state = BreakerOpen
+	self.retryAt = time.Now().Add(self.backoff)
+}
+
+// isTransportErr is when request could not reach device or connection to it
+// failed.  Requests cancelled by caller are not, even though http client
+// wraps them in errors that look like network errors.
+func isTransportErr(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) || errors.As(err, &dnsErr) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// do f w/current connection to device, counting it's error against device
+func (self *ReconnectingDevice) do(ctx context.Context, f func(c *client) error) error {
+	c, err := self.connect()
+	if err != nil {
+		return err
+	}
+	err = f(c)
+	self.record(ctx, err)
+	return err
+}
+
+// Rest of Device sends requests thru whatever connection is current.  What
+// they return such as subscriptions and transactions stay on connection
+// they were made on.
+
+func (self *ReconnectingDevice) ModuleInfo() []ModuleInfo {
+	return self.client().ModuleInfo()
+}
+
+func (self *ReconnectingDevice) PreloadModules(ctx context.Context, names []string) error {
+	return self.do(ctx, func(c *client) error {
+		return c.PreloadModules(ctx, names)
+	})
+}
+
+func (self *ReconnectingDevice) MountBrowser(path string, module string) (*node.Browser, error) {
+	var b *node.Browser
+	err := self.do(context.Background(), func(c *client) (err error) {
+		b, err = c.MountBrowser(path, module)
+		return
+	})
+	return b, err
+}
+
+func (self *ReconnectingDevice) DatastoreModules(datastore string) map[string]*meta.Module {
+	return self.client().DatastoreModules(datastore)
+}
+
+func (self *ReconnectingDevice) DatastoreBrowser(datastore string, module string) (*node.Browser, error) {
+	return self.client().DatastoreBrowser(datastore, module)
+}
+
+func (self *ReconnectingDevice) AllowedMethods(path string) []string {
+	return self.client().AllowedMethods(path)
+}
+
+func (self *ReconnectingDevice) AcceptedPatch(path string) []string {
+	return self.client().AcceptedPatch(path)
+}
+
+func (self *ReconnectingDevice) Protocol() string {
+	return self.client().Protocol()
+}
+
+func (self *ReconnectingDevice) ServerTime(ctx context.Context) (time.Time, time.Duration, error) {
+	var t time.Time
+	var skew time.Duration
+	err := self.do(ctx, func(c *client) (err error) {
+		t, skew, err = c.ServerTime(ctx)
+		return
+	})
+	return t, skew, err
+}
+
+func (self *ReconnectingDevice) GetRaw(ctx context.Context, path string) ([]byte, error) {
+	var data []byte
+	err := self.do(ctx, func(c *client) (err error) {
+		data, err = c.GetRaw(ctx, path)
+		return
+	})
+	return data, err
+}
+
+func (self *ReconnectingDevice) GetRawStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	var rdr io.ReadCloser
+	err := self.do(ctx, func(c *client) (err error) {
+		rdr, err = c.GetRawStream(ctx, path)
+		return
+	})
+	return rdr, err
+}
+
+func (self *ReconnectingDevice) GetWithTrace(ctx context.Context, path string) (node.Node, *Trace, error) {
+	var n node.Node
+	var trace *Trace
+	err := self.do(ctx, func(c *client) (err error) {
+		n, trace, err = c.GetWithTrace(ctx, path)
+		return
+	})
+	return n, trace, err
+}
+
+func (self *ReconnectingDevice) GetByInstanceID(ctx context.Context, iid string) (node.Node, error) {
+	var n node.Node
+	err := self.do(ctx, func(c *client) (err error) {
+		n, err = c.GetByInstanceID(ctx, iid)
+		return
+	})
+	return n, err
+}
+
+func (self *ReconnectingDevice) Exists(ctx context.Context, paths []string) (map[string]bool, error) {
+	var exists map[string]bool
+	err := self.do(ctx, func(c *client) (err error) {
+		exists, err = c.Exists(ctx, paths)
+		return
+	})
+	return exists, err
+}
+
+func (self *ReconnectingDevice) LeafListContains(ctx context.Context, path string, value string) (bool, error) {
+	var found bool
+	err := self.do(ctx, func(c *client) (err error) {
+		found, err = c.LeafListContains(ctx, path, value)
+		return
+	})
+	return found, err
+}
+
+func (self *ReconnectingDevice) Download(ctx context.Context, path string, w io.Writer) (int64, error) {
+	var n int64
+	err := self.do(ctx, func(c *client) (err error) {
+		n, err = c.Download(ctx, path, w)
+		return
+	})
+	return n, err
+}
+
+func (self *ReconnectingDevice) Snapshot(ctx context.Context) (*Snapshot, error) {
+	var snap *Snapshot
+	err := self.do(ctx, func(c *client) (err error) {
+		snap, err = c.Snapshot(ctx)
+		return
+	})
+	return snap, err
+}
+
+func (self *ReconnectingDevice) Diff(ctx context.Context, path string, desired node.Selection) (Diff, error) {
+	var d Diff
+	err := self.do(ctx, func(c *client) (err error) {
+		d, err = c.Diff(ctx, path, desired)
+		return
+	})
+	return d, err
+}
+
+func (self *ReconnectingDevice) Apply(ctx context.Context, d Diff) error {
+	return self.do(ctx, func(c *client) error {
+		return c.Apply(ctx, d)
+	})
+}
+
+func (self *ReconnectingDevice) YangPatch(ctx context.Context, path string, patchId string, edits []PatchEdit) error {
+	return self.do(ctx, func(c *client) error {
+		return c.YangPatch(ctx, path, patchId, edits)
+	})
+}
+
+func (self *ReconnectingDevice) Transaction(ctx context.Context) (*Transaction, error) {
+	var tx *Transaction
+	err := self.do(ctx, func(c *client) (err error) {
+		tx, err = c.Transaction(ctx)
+		return
+	})
+	return tx, err
+}
+
+func (self *ReconnectingDevice) ExportConfig(ctx context.Context, w io.Writer) error {
+	return self.do(ctx, func(c *client) error {
+		return c.ExportConfig(ctx, w)
+	})
+}
+
+func (self *ReconnectingDevice) ExportConfigBytes(ctx context.Context) ([]byte, error) {
+	var doc []byte
+	err := self.do(ctx, func(c *client) (err error) {
+		doc, err = c.ExportConfigBytes(ctx)
+		return
+	})
+	return doc, err
+}
+
+func (self *ReconnectingDevice) ImportConfig(ctx context.Context, doc io.Reader) error {
+	return self.do(ctx, func(c *client) error {
+		return c.ImportConfig(ctx, doc)
+	})
+}
+
+func (self *ReconnectingDevice) ActionRaw(ctx context.Context, path string, input node.Node) (*http.Response, node.Node, error) {
+	var resp *http.Response
+	var out node.Node
+	err := self.do(ctx, func(c *client) (err error) {
+		resp, out, err = c.ActionRaw(ctx, path, input)
+		return
+	})
+	return resp, out, err
+}
+
+func (self *ReconnectingDevice) ActionStream(ctx context.Context, path string, input node.Node) (<-chan node.Node, error) {
+	var events <-chan node.Node
+	err := self.do(ctx, func(c *client) (err error) {
+		events, err = c.ActionStream(ctx, path, input)
+		return
+	})
+	return events, err
+}
+
+func (self *ReconnectingDevice) ActionWithTrace(ctx context.Context, path string, input node.Node) (node.Node, *Trace, error) {
+	var out node.Node
+	var trace *Trace
+	err := self.do(ctx, func(c *client) (err error) {
+		out, trace, err = c.ActionWithTrace(ctx, path, input)
+		return
+	})
+	return out, trace, err
+}
+
+func (self *ReconnectingDevice) Subscribe(ctx context.Context, path string) (*Subscription, error) {
+	var sub *Subscription
+	err := self.do(ctx, func(c *client) (err error) {
+		sub, err = c.Subscribe(ctx, path)
+		return
+	})
+	return sub, err
+}
+
+func (self *ReconnectingDevice) LazySubscribe(ctx context.Context, path string) (*LazySubscription, error) {
+	var sub *LazySubscription
+	err := self.do(ctx, func(c *client) (err error) {
+		sub, err = c.LazySubscribe(ctx, path)
+		return
+	})
+	return sub, err
+}
+
+func (self *ReconnectingDevice) Streams(ctx context.Context) ([]StreamInfo, error) {
+	var streams []StreamInfo
+	err := self.do(ctx, func(c *client) (err error) {
+		streams, err = c.Streams(ctx)
+		return
+	})
+	return streams, err
+}
+
+func (self *ReconnectingDevice) StreamStats() []StreamStats {
+	return self.client().StreamStats()
+}
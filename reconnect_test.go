@@ -0,0 +1,138 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/source"
+)
+
+type flakyTransport struct {
+	shared http.RoundTripper
+	down   *bool
+}
+
+func (self flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if *self.down {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network down")}
+	}
+	return self.shared.RoundTrip(req)
+}
+
+func TestReconnectingDevice(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"module":[]}`))
+	}))
+	defer srv.Close()
+	down := false
+	connects := 0
+	factory := Client{
+		YangPath: source.Dir("./yang"),
+		DeviceTransport: func(url string, shared *http.Transport) http.RoundTripper {
+			connects++
+			return flakyTransport{shared: shared, down: &down}
+		},
+	}
+	d, err := factory.NewReconnectingDevice(srv.URL+"/restconf", Breaker{
+		FailureThreshold: 2,
+		Backoff:          50 * time.Millisecond,
+	})
+	fc.AssertEqual(t, nil, err)
+	b, err := d.Browser("ietf-yang-library")
+	fc.AssertEqual(t, nil, err)
+	read := func() error {
+		return b.Root().Find("modules-state").LastErr
+	}
+	fc.AssertEqual(t, nil, read())
+	fc.AssertEqual(t, BreakerClosed, d.State())
+
+	down = true
+	fc.AssertEqual(t, true, read() != nil)
+	fc.AssertEqual(t, BreakerClosed, d.State())
+	fc.AssertEqual(t, true, read() != nil)
+	fc.AssertEqual(t, BreakerOpen, d.State())
+	fc.AssertEqual(t, false, d.Healthy())
+	if err := read(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected circuit open, got %v", err)
+	}
+
+	// device recovers, same browser works once backoff has passed
+	down = false
+	err = read()
+	for i := 0; i < 100 && errors.Is(err, ErrCircuitOpen); i++ {
+		time.Sleep(10 * time.Millisecond)
+		err = read()
+	}
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, BreakerClosed, d.State())
+	fc.AssertEqual(t, 2, connects)
+}
+
+func TestIsTransportErr(t *testing.T) {
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("refused")}
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{nil, false},
+		{&url.Error{Op: "Get", URL: "x", Err: dial}, true},
+		{&url.Error{Op: "Get", URL: "x", Err: &net.DNSError{Err: "no such host"}}, true},
+		{&url.Error{Op: "Get", URL: "x", Err: syscall.ECONNRESET}, true},
+		{&url.Error{Op: "Get", URL: "x", Err: context.Canceled}, false},
+		{&url.Error{Op: "Get", URL: "x", Err: errors.New("tls: bad certificate")}, false},
+		{fc.NotFoundError, false},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, isTransportErr(test.err))
+	}
+}
+
+func TestReconnectingDeviceCallerCancel(t *testing.T) {
+	d := &ReconnectingDevice{breaker: Breaker{FailureThreshold: 1}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// caller's deadline looks like a network timeout
+	d.record(ctx, &url.Error{Op: "Get", URL: "x", Err: &net.OpError{Op: "read", Err: context.DeadlineExceeded}})
+	fc.AssertEqual(t, BreakerClosed, d.State())
+	d.record(context.Background(), &url.Error{Op: "Get", URL: "x", Err: &net.OpError{Op: "dial", Err: errors.New("refused")}})
+	fc.AssertEqual(t, BreakerOpen, d.State())
+}
+
+func TestReconnectingDeviceForwards(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"module":[]}`))
+	}))
+	defer srv.Close()
+	down := false
+	factory := Client{
+		YangPath: source.Dir("./yang"),
+		DeviceTransport: func(url string, shared *http.Transport) http.RoundTripper {
+			return flakyTransport{shared: shared, down: &down}
+		},
+	}
+	d, err := factory.NewReconnectingDevice(srv.URL+"/restconf", Breaker{FailureThreshold: 2})
+	fc.AssertEqual(t, nil, err)
+	var dev Device = d
+	ctx := context.Background()
+	raw, err := dev.GetRaw(ctx, "ietf-yang-library:modules-state")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"module":[]}`, string(raw))
+
+	// failures of forwarded requests count against device
+	down = true
+	_, err = dev.GetRaw(ctx, "ietf-yang-library:modules-state")
+	fc.AssertEqual(t, true, err != nil)
+	_, err = dev.Streams(ctx)
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, BreakerOpen, d.State())
+	_, err = dev.GetRaw(ctx, "ietf-yang-library:modules-state")
+	fc.AssertEqual(t, true, errors.Is(err, ErrCircuitOpen))
+}
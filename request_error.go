@@ -0,0 +1,84 @@
+package restconf
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RequestError is what request failed and on which device so errors from
+// many devices can be told apart in logs.  Url has credentials and tokens
+// redacted.  Use errors.Is or errors.As to check underlying error.
+type RequestError struct {
+	Method string
+	Url    string
+	Device string
+	Err    error
+}
+
+func (self *RequestError) Error() string {
+	if self.Device != "" {
+		return fmt.Sprintf("%s %s (%s). %s", self.Method, self.Url, self.Device, self.Err)
+	}
+	return fmt.Sprintf("%s %s. %s", self.Method, self.Url, self.Err)
+}
+
+func (self *RequestError) Unwrap() error {
+	return self.Err
+}
+
+func (self *client) requestErr(method string, fullUrl string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RequestError{
+		Method: method,
+		Url:    redactUrl(fullUrl),
+		Device: self.address.DeviceId,
+		Err:    err,
+	}
+}
+
+// sensitiveParams are query params whose values are redacted if their name
+// contains any of these
+var sensitiveParams = []string{"token", "key", "secret", "password", "passwd", "auth", "sig", "credential"}
+
+const redacted = "REDACTED"
+
+// redactUrl removes password and values of query params that look like
+// credentials
+func redactUrl(fullUrl string) string {
+	u, err := url.Parse(fullUrl)
+	if err != nil {
+		return redacted
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		changed := false
+		for name, vals := range q {
+			if isSensitiveParam(name) {
+				for i := range vals {
+					vals[i] = redacted
+				}
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = q.Encode()
+		}
+	}
+	return u.String()
+}
+
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveParams {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
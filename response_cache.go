@@ -0,0 +1,230 @@
+package restconf
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type bypassCacheKey struct{}
+
+// WithoutCache sends reads made w/this context to device even if there is
+// a fresh response in cache.  Response still replaces what is in cache.
+// See Client.ResponseCacheSize.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func bypassCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// responseCache holds GET responses for as long as server says they are
+// fresh w/Cache-Control or Expires, least recently used are evicted first.
+// Stale responses are kept so they can be revalidated w/a conditional GET.
+// nil cache caches nothing.
+type responseCache struct {
+	size    int
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key     string
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+func newResponseCache(size int) *responseCache {
+	if size <= 0 {
+		return nil
+	}
+	return &responseCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// conditional is whether request carries a precondition only device can
+// check so it's response can neither come from nor go in cache
+func conditional(req *http.Request) bool {
+	for _, h := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"} {
+		if req.Header.Get(h) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheKey(req *http.Request) string {
+	return req.URL.String() + " " + req.Header.Get("Accept")
+}
+
+// lookup is a fresh response from cache.  If cached response is stale, req
+// is made conditional and entry is returned to be revalidated.
+func (self *responseCache) lookup(req *http.Request) (*http.Response, *cacheEntry) {
+	if self == nil || req.Method != "GET" || req.Header.Get("Range") != "" {
+		return nil, nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	elem, found := self.entries[cacheKey(req)]
+	if !found {
+		return nil, nil
+	}
+	self.lru.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+	if bypassCache(req.Context()) {
+		return nil, nil
+	}
+	if time.Now().Before(entry.expires) {
+		return entry.response(req), entry
+	}
+	if etag := entry.header.Get("ETag"); etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	} else if modified := entry.header.Get("Last-Modified"); modified != "" {
+		req.Header.Set("If-Modified-Since", modified)
+	} else {
+		return nil, nil
+	}
+	return nil, entry
+}
+
+// revalidated is cached response after server said it has not changed
+func (self *responseCache) revalidated(entry *cacheEntry, resp *http.Response) *http.Response {
+	resp.Body.Close()
+	expires, _ := freshUntil(resp.Header, time.Now())
+	self.lock.Lock()
+	entry.expires = expires
+	self.lock.Unlock()
+	return entry.response(resp.Request)
+}
+
+// store keeps successful response if server allows it.  Body is read into
+// memory so returned response must be used in place of resp.
+func (self *responseCache) store(resp *http.Response) (*http.Response, error) {
+	if self == nil || resp.Request.Method != "GET" || resp.StatusCode != http.StatusOK || resp.Request.Header.Get("Range") != "" {
+		return resp, nil
+	}
+	key := cacheKey(resp.Request)
+	expires, cacheable := freshUntil(resp.Header, time.Now())
+	if !cacheable {
+		self.remove(key)
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	entry := &cacheEntry{key: key, header: resp.Header.Clone(), body: body, expires: expires}
+	self.lock.Lock()
+	if elem, found := self.entries[key]; found {
+		self.lru.Remove(elem)
+	}
+	self.entries[key] = self.lru.PushFront(entry)
+	for self.lru.Len() > self.size {
+		oldest := self.lru.Back()
+		self.lru.Remove(oldest)
+		delete(self.entries, oldest.Value.(*cacheEntry).key)
+	}
+	self.lock.Unlock()
+	return entry.response(resp.Request), nil
+}
+
+func (self *responseCache) remove(key string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if elem, found := self.entries[key]; found {
+		self.lru.Remove(elem)
+		delete(self.entries, key)
+	}
+}
+
+// clear is for after edits because any cached response might include what
+// was edited
+func (self *responseCache) clear() {
+	if self == nil {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.entries = make(map[string]*list.Element)
+	self.lru.Init()
+}
+
+func (self *responseCache) len() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.lru.Len()
+}
+
+func (self *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        self.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(self.body)),
+		ContentLength: int64(len(self.body)),
+		Request:       req,
+	}
+}
+
+// freshUntil is when response becomes stale according to Cache-Control or
+// Expires and false if response must not be stored or has no freshness
+// information.  no-cache responses are stored but are already stale.
+func freshUntil(header http.Header, now time.Time) (time.Time, bool) {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if _, noStore := directives["no-store"]; noStore {
+		return time.Time{}, false
+	}
+	if _, noCache := directives["no-cache"]; noCache {
+		return now, true
+	}
+	if maxAge, found := directives["max-age"]; found {
+		secs, err := strconv.Atoi(maxAge)
+		if err != nil {
+			return now, true
+		}
+		age, _ := strconv.Atoi(header.Get("Age"))
+		return now.Add(time.Duration(secs-age) * time.Second), true
+	}
+	if expires := header.Get("Expires"); expires != "" {
+		// invalid dates, such as "0", mean already expired
+		when, err := http.ParseTime(expires)
+		if err != nil {
+			return now, true
+		}
+		return when, true
+	}
+	return time.Time{}, false
+}
+
+func parseCacheControl(cc string) map[string]string {
+	directives := make(map[string]string)
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		name, value := d, ""
+		if eq := strings.IndexRune(d, '='); eq >= 0 {
+			name, value = d[:eq], strings.Trim(d[eq+1:], `"`)
+		}
+		directives[strings.ToLower(name)] = value
+	}
+	return directives
+}
@@ -0,0 +1,116 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientResponseCache(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container fresh { leaf a { type int32; } }
+		container stale { leaf a { type int32; } }
+		container nostore { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var requests []string
+	version := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, strings.TrimPrefix(r.URL.Path, "/restconf/data/m:")+r.Header.Get("If-None-Match"))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "fresh"):
+			w.Header().Set("Cache-Control", "max-age=60")
+		case strings.HasSuffix(r.URL.Path, "stale"):
+			etag := fmt.Sprintf(`"%d"`, version)
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case strings.HasSuffix(r.URL.Path, "nostore"):
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		fmt.Fprintf(w, `{"a":%d}`, version)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.cache = newResponseCache(10)
+	read := func(ctx context.Context, path string) string {
+		requests = nil
+		data, err := c.GetRaw(ctx, path)
+		fc.AssertEqual(t, nil, err)
+		return string(data) + " " + strings.Join(requests, " ")
+	}
+	ctx := context.Background()
+
+	fc.AssertEqual(t, `{"a":1} fresh`, read(ctx, "m:fresh"))
+	fc.AssertEqual(t, `{"a":1} `, read(ctx, "m:fresh"))
+	fc.AssertEqual(t, `{"a":1} fresh`, read(WithoutCache(ctx), "m:fresh"))
+
+	fc.AssertEqual(t, `{"a":1} stale`, read(ctx, "m:stale"))
+	fc.AssertEqual(t, `{"a":1} stale"1"`, read(ctx, "m:stale"))
+	version = 2
+	fc.AssertEqual(t, `{"a":2} stale"1"`, read(ctx, "m:stale"))
+	fc.AssertEqual(t, `{"a":2} stale"2"`, read(ctx, "m:stale"))
+
+	fc.AssertEqual(t, `{"a":2} nostore`, read(ctx, "m:nostore"))
+	fc.AssertEqual(t, `{"a":2} nostore`, read(ctx, "m:nostore"))
+	fc.AssertEqual(t, 2, c.cache.len())
+
+	// preconditions are for device to check so conditional reads neither
+	// come from nor replace what is cached
+	version = 3
+	conditional := context.WithValue(ctx, snapshotKey{}, `"s"`)
+	fc.AssertEqual(t, `{"a":3} fresh`, read(conditional, "m:fresh"))
+	fc.AssertEqual(t, `{"a":1} `, read(ctx, "m:fresh"))
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	cache := newResponseCache(2)
+	for _, path := range []string{"a", "b", "c"} {
+		req := httptest.NewRequest("GET", "http://x/"+path, nil)
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Cache-Control": []string{"max-age=60"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}
+		_, err := cache.store(resp)
+		fc.AssertEqual(t, nil, err)
+	}
+	fc.AssertEqual(t, 2, cache.len())
+	cached, _ := cache.lookup(httptest.NewRequest("GET", "http://x/a", nil))
+	fc.AssertEqual(t, true, cached == nil)
+	cached, _ = cache.lookup(httptest.NewRequest("GET", "http://x/c", nil))
+	fc.AssertEqual(t, true, cached != nil)
+}
+
+func TestFreshUntil(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header    http.Header
+		expires   time.Time
+		cacheable bool
+	}{
+		{http.Header{}, time.Time{}, false},
+		{http.Header{"Cache-Control": {"no-store, max-age=10"}}, time.Time{}, false},
+		{http.Header{"Cache-Control": {"no-cache"}}, now, true},
+		{http.Header{"Cache-Control": {"public, max-age=10"}}, now.Add(10 * time.Second), true},
+		{http.Header{"Cache-Control": {"max-age=10"}, "Age": {"4"}}, now.Add(6 * time.Second), true},
+		{http.Header{"Expires": {"Wed, 01 Jan 2020 00:01:00 GMT"}}, now.Add(time.Minute), true},
+		{http.Header{"Expires": {"0"}}, now, true},
+	}
+	for _, test := range tests {
+		expires, cacheable := freshUntil(test.header, now)
+		fc.AssertEqual(t, test.cacheable, cacheable)
+		fc.AssertEqual(t, true, test.expires.Equal(expires))
+	}
+}
@@ -0,0 +1,128 @@
+package restconftest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// recordedEvent is a single notification as it is stored in a recording.
+// Recordings are newline delimited JSON of these.
+type recordedEvent struct {
+	// since first event
+	Offset    time.Duration   `json:"offset"`
+	EventTime *time.Time      `json:"eventTime,omitempty"`
+	Cursor    string          `json:"cursor,omitempty"`
+	Event     json.RawMessage `json:"event,omitempty"`
+	Err       string          `json:"err,omitempty"`
+}
+
+// RecordEvents tees notifications of p from events to out so they can be
+// replayed w/ReplayEvents.  Returned channel has same events and is closed
+// when events is.  Events that cannot be written are logged and still
+// passed on.
+//
+//	sub, err := dev.Subscribe(ctx, "car:update")
+//	events := restconftest.RecordEvents(sub.Events(), sub.Path(), f)
+func RecordEvents(events <-chan node.Node, p *node.Path, out io.Writer) <-chan node.Node {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	tee := make(chan node.Node)
+	go func() {
+		defer close(tee)
+		var start time.Time
+		for n := range events {
+			if start.IsZero() {
+				start = time.Now()
+			}
+			if err := enc.Encode(recordEvent(n, p, time.Since(start))); err != nil {
+				fc.Err.Printf("could not record event of %s. %s", p, err)
+			}
+			tee <- n
+		}
+	}()
+	return tee
+}
+
+func recordEvent(n node.Node, p *node.Path, offset time.Duration) recordedEvent {
+	e := recordedEvent{Offset: offset}
+	if t, timed := restconf.EventTime(n); timed {
+		e.EventTime = &t
+	}
+	e.Cursor, _ = restconf.EventCursor(n)
+	if errNode, isErr := n.(node.ErrorNode); isErr {
+		e.Err = errNode.Err.Error()
+		return e
+	}
+	sel := node.Selection{
+		Node:        n,
+		Path:        p,
+		Constraints: &node.Constraints{},
+		Context:     context.Background(),
+	}
+	data, err := nodeutil.WriteJSON(sel)
+	if err != nil {
+		e.Err = err.Error()
+		return e
+	}
+	e.Event = json.RawMessage(data)
+	return e
+}
+
+// ReplayEvents sends events recorded w/RecordEvents keeping time between
+// them divided by speed so 1 is as they happened, 10 is ten times faster and
+// 0 sends them w/o waiting.  Channel is closed after last event or when ctx
+// is done.  Event time and cursor are as device sent them, events that
+// were errors are error nodes.
+func ReplayEvents(ctx context.Context, in io.Reader, speed float64) (<-chan node.Node, error) {
+	var recording []recordedEvent
+	dec := json.NewDecoder(in)
+	for {
+		var e recordedEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		recording = append(recording, e)
+	}
+	events := make(chan node.Node)
+	go func() {
+		defer close(events)
+		start := time.Now()
+		for _, e := range recording {
+			if speed > 0 {
+				due := start.Add(time.Duration(float64(e.Offset) / speed))
+				select {
+				case <-time.After(time.Until(due)):
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case events <- replayEvent(e):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+func replayEvent(e recordedEvent) node.Node {
+	if e.Err != "" {
+		return node.ErrorNode{Err: errors.New(e.Err)}
+	}
+	var eventTime time.Time
+	if e.EventTime != nil {
+		eventTime = *e.EventTime
+	}
+	return restconf.NewEvent(nodeutil.ReadJSON(string(e.Event)), eventTime, e.Cursor)
+}
@@ -0,0 +1,80 @@
+package restconftest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestRecordReplayEvents(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification update { leaf speed { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	p := node.NewContainerPath(node.NewRootPath(m), meta.Find(m, "update").(meta.HasDefinitions))
+	eventTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	live := make(chan node.Node)
+	go func() {
+		live <- restconf.NewEvent(nodeutil.ReadJSON(`{"speed":1}`), eventTime, "c1")
+		time.Sleep(100 * time.Millisecond)
+		live <- nodeutil.ReadJSON(`{"speed":2}`)
+		close(live)
+	}()
+	var recording bytes.Buffer
+	var recorded []string
+	for n := range RecordEvents(live, p, &recording) {
+		recorded = append(recorded, toJSON(t, p, n))
+	}
+	fc.AssertEqual(t, 2, len(recorded))
+
+	replay := func(speed float64) ([]node.Node, time.Duration) {
+		events, err := ReplayEvents(context.Background(), bytes.NewReader(recording.Bytes()), speed)
+		fc.AssertEqual(t, nil, err)
+		start := time.Now()
+		var replayed []node.Node
+		for n := range events {
+			replayed = append(replayed, n)
+		}
+		return replayed, time.Since(start)
+	}
+
+	// real time
+	replayed, took := replay(1)
+	fc.AssertEqual(t, true, took >= 100*time.Millisecond)
+	fc.AssertEqual(t, 2, len(replayed))
+	for i, n := range replayed {
+		fc.AssertEqual(t, recorded[i], toJSON(t, p, n))
+	}
+	actualTime, timed := restconf.EventTime(replayed[0])
+	fc.AssertEqual(t, true, timed)
+	fc.AssertEqual(t, true, eventTime.Equal(actualTime))
+	cursor, _ := restconf.EventCursor(replayed[0])
+	fc.AssertEqual(t, "c1", cursor)
+	_, timed = restconf.EventTime(replayed[1])
+	fc.AssertEqual(t, false, timed)
+
+	// accelerated
+	replayed, took = replay(10)
+	fc.AssertEqual(t, 2, len(replayed))
+	fc.AssertEqual(t, true, took < 100*time.Millisecond)
+	fc.AssertEqual(t, true, took >= 10*time.Millisecond)
+}
+
+func toJSON(t *testing.T, p *node.Path, n node.Node) string {
+	actual, err := nodeutil.WriteJSON(node.Selection{
+		Node:        n,
+		Path:        p,
+		Constraints: &node.Constraints{},
+		Context:     context.Background(),
+	})
+	fc.AssertEqual(t, nil, err)
+	return actual
+}
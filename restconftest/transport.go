@@ -0,0 +1,136 @@
+// Package restconftest connects a RESTCONF client to a RESTCONF server in the
+// same process w/o TCP so edits, reads, actions and notifications can be
+// tested thru entire protocol quickly and deterministically.  Notifications
+// from a live device can be recorded and replayed later w/o one.
+package restconftest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/freeconf/restconf"
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/source"
+)
+
+// Url is the address NewDevice gives client, no network is involved
+const Url = "http://restconftest/restconf"
+
+// NewDevice serves browsers from a local device and returns remote device
+// that reaches it thru Transport.  ypath must have browsers' modules as well
+// as fc-restconf and ietf-yang-library from restconf's yang directory.
+//
+//	dev, err := restconftest.NewDevice(ypath, node.NewBrowser(m, app))
+//	b, err := dev.Browser("car")
+func NewDevice(ypath source.Opener, browsers ...*node.Browser) (device.Device, error) {
+	local := device.New(ypath)
+	for _, b := range browsers {
+		local.AddBrowser(b)
+	}
+	srv := restconf.NewServer(local)
+	factory := restconf.Client{
+		YangPath: ypath,
+		DeviceTransport: func(string, *http.Transport) http.RoundTripper {
+			return Transport{Handler: srv}
+		},
+	}
+	return factory.NewDevice(Url)
+}
+
+// Transport hands requests directly to Handler.  Response is returned as
+// soon as handler sends headers, not when handler returns, so streams such
+// as notifications are delivered as they are written.
+type Transport struct {
+	Handler http.Handler
+}
+
+func (self Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// requests on server side always have a body
+	srvReq := req.Clone(req.Context())
+	if srvReq.Body == nil {
+		srvReq.Body = http.NoBody
+	}
+	srvReq.RequestURI = req.URL.RequestURI()
+	rdr, wtr := io.Pipe()
+	w := &responseWriter{
+		header: make(http.Header),
+		body:   wtr,
+		sent:   make(chan struct{}),
+		resp: &http.Response{
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Request:    req,
+			Body:       rdr,
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer wtr.Close()
+		defer w.WriteHeader(http.StatusOK)
+		self.Handler.ServeHTTP(w, srvReq)
+	}()
+	go func() {
+		select {
+		case <-done:
+		case <-req.Context().Done():
+			// handler would otherwise block forever writing a body no one
+			// will read
+			rdr.CloseWithError(req.Context().Err())
+		}
+	}()
+	select {
+	case <-w.sent:
+		return w.resp, nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+type responseWriter struct {
+	header http.Header
+	body   *io.PipeWriter
+	resp   *http.Response
+	sent   chan struct{}
+	once   sync.Once
+}
+
+func (self *responseWriter) Header() http.Header {
+	return self.header
+}
+
+func (self *responseWriter) WriteHeader(status int) {
+	self.once.Do(func() {
+		self.resp.StatusCode = status
+		self.resp.Status = statusLine(status)
+		self.resp.Header = self.header.Clone()
+		self.resp.ContentLength = -1
+		if status == http.StatusNoContent || self.resp.Request.Method == "HEAD" {
+			self.resp.Body = http.NoBody
+		}
+		close(self.sent)
+	})
+}
+
+func (self *responseWriter) Write(data []byte) (int, error) {
+	self.WriteHeader(http.StatusOK)
+	if self.resp.Body == http.NoBody {
+		return len(data), nil
+	}
+	return self.body.Write(data)
+}
+
+// Flush implements http.Flusher, writes are never buffered
+func (self *responseWriter) Flush() {
+	self.WriteHeader(http.StatusOK)
+}
+
+// statusLine is like net/http's "200 OK"
+func statusLine(status int) string {
+	return strings.TrimSpace(fmt.Sprint(status, " ", http.StatusText(status)))
+}
@@ -0,0 +1,82 @@
+package restconftest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/testdata"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestNewDevice(t *testing.T) {
+	ypath := source.Path("../testdata:../yang")
+	car := testdata.New()
+	car.Speed = 0
+	m := parser.RequireModule(ypath, "car")
+	dev, err := NewDevice(ypath, node.NewBrowser(m, testdata.Manage(car)))
+	fc.AssertEqual(t, nil, err)
+	b, err := dev.Browser("car")
+	fc.AssertEqual(t, nil, err)
+
+	// notify
+	events := make(chan string, 10)
+	closer, err := b.Root().Find("update").Notifications(func(msg node.Selection) {
+		actual, err := nodeutil.WriteJSON(msg)
+		if err != nil {
+			actual = err.Error()
+		}
+		events <- actual
+	})
+	fc.AssertEqual(t, nil, err)
+	defer closer()
+
+	// edit, car starts when edited so there is an update event
+	err = b.Root().UpsertFrom(nodeutil.ReadJSON(`{"speed":0}`)).LastErr
+	fc.AssertEqual(t, nil, err)
+	select {
+	case event := <-events:
+		fc.AssertEqual(t, true, len(event) > 0)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+
+	// read
+	speed, err := b.Root().Get("speed")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, 0, speed)
+
+	// action
+	first := car.Tire[0]
+	err = b.Root().Find("rotateTires").Action(nil).LastErr
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, first, car.Tire[3])
+}
+
+func TestTransportCancel(t *testing.T) {
+	written := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		// client never reads body
+		_, err := w.Write([]byte("unread"))
+		written <- err
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", Url, nil)
+	fc.AssertEqual(t, nil, err)
+	resp, err := Transport{Handler: handler}.RoundTrip(req)
+	fc.AssertEqual(t, nil, err)
+	defer resp.Body.Close()
+	cancel()
+	select {
+	case err := <-written:
+		fc.AssertEqual(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still blocked")
+	}
+}
@@ -0,0 +1,81 @@
+package secure
+
+import (
+	"context"
+	"sort"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+// EffectiveAccess is role's effective permission on each definition in target's
+// module for previewing what a role can do.  Browse w/fc-access module.
+// Permissions are resolved exactly as they are when role is enforced.
+func EffectiveAccess(role *Role, target *node.Browser) node.Node {
+	m := target.Meta
+	var rows []interface{}
+	ctx := role.contextFor(m, context.Background())
+	accessRows(role, m, ctx, &rows)
+	return nodeutil.ReflectChild(map[string]interface{}{
+		"access": rows,
+	})
+}
+
+func accessRows(role *Role, parent meta.HasDataDefinitions, ctx context.Context, rows *[]interface{}) {
+	for _, def := range parent.DataDefinitions() {
+		if choice, isChoice := def.(*meta.Choice); isChoice {
+			for _, ident := range choice.CaseIdents() {
+				accessRows(role, choice.Cases()[ident], ctx, rows)
+			}
+			continue
+		}
+		allowed := role.permission(def, ctx)
+		config := true
+		if x, hasConfig := def.(meta.HasConfig); hasConfig {
+			config = x.Config()
+		}
+		write := config && allowed >= Full && !role.writeExcluded(def)
+		*rows = append(*rows, accessRow(def, allowed >= Read, write, false))
+		if x, hasDefs := def.(meta.HasDataDefinitions); hasDefs && !x.IsRecursive() {
+			accessRows(role, x, role.contextFor(def, ctx), rows)
+		}
+	}
+	if x, hasActions := parent.(meta.HasActions); hasActions {
+		for _, ident := range sortedIdents(x.Actions()) {
+			a := x.Actions()[ident]
+			*rows = append(*rows, accessRow(a, false, false, role.permission(a, ctx) >= Full))
+		}
+	}
+	if x, hasNotifs := parent.(meta.HasNotifications); hasNotifs {
+		for _, ident := range sortedIdents(x.Notifications()) {
+			n := x.Notifications()[ident]
+			*rows = append(*rows, accessRow(n, role.permission(n, ctx) >= Full, false, false))
+		}
+	}
+}
+
+func accessRow(def meta.Meta, read bool, write bool, exec bool) map[string]interface{} {
+	return map[string]interface{}{
+		"path":  meta.SchemaPath(def),
+		"read":  read,
+		"write": write,
+		"exec":  exec,
+	}
+}
+
+func sortedIdents(defs interface{}) []string {
+	var idents []string
+	switch x := defs.(type) {
+	case map[string]*meta.Rpc:
+		for ident := range x {
+			idents = append(idents, ident)
+		}
+	case map[string]*meta.Notification:
+		for ident := range x {
+			idents = append(idents, ident)
+		}
+	}
+	sort.Strings(idents)
+	return idents
+}
@@ -0,0 +1,63 @@
+package secure
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestEffectiveAccess(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module birding { revision 0;
+leaf count {
+	type int32;
+}
+container owner {
+	leaf name {
+		type string;
+	}
+}
+container stats {
+	config false;
+	leaf seen {
+		type int32;
+	}
+}
+action fieldtrip {
+	input {}
+}
+notification identified {}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	role := NewRole()
+	role.Access["birding"] = &AccessControl{Path: "birding", Permissions: Full}
+	role.Access["birding/owner"] = &AccessControl{Path: "birding/owner", Permissions: Read}
+	target := node.NewBrowser(m, nodeutil.ReflectChild(map[string]interface{}{
+		"owner": map[string]interface{}{"name": "ethel"},
+	}))
+
+	ypath := source.Dir("../yang")
+	b := node.NewBrowser(parser.RequireModule(ypath, "fc-access"), EffectiveAccess(role, target))
+	actual, err := nodeutil.WriteJSON(b.Root())
+	fc.AssertEqual(t, nil, err)
+	expected := `{"access":[` +
+		`{"path":"birding/count","read":true,"write":true,"exec":false},` +
+		`{"path":"birding/owner","read":true,"write":false,"exec":false},` +
+		`{"path":"birding/owner/name","read":true,"write":false,"exec":false},` +
+		`{"path":"birding/stats","read":true,"write":false,"exec":false},` +
+		`{"path":"birding/stats/seen","read":true,"write":false,"exec":false},` +
+		`{"path":"birding/fieldtrip","read":false,"write":false,"exec":true},` +
+		`{"path":"birding/identified","read":true,"write":false,"exec":false}]}`
+	fc.AssertEqual(t, expected, actual)
+
+	// same answer as enforcement
+	s := target.Root()
+	s.Constraints.AddConstraint("auth", 0, 0, role)
+	s.Context = s.Constraints.ContextConstraint(s)
+	owner := s.Find("owner")
+	fc.AssertEqual(t, false, owner.IsNil())
+	fc.AssertEqual(t, "unauthorized", err2auth(owner.Set("name", "Harvey")))
+}
@@ -24,6 +24,10 @@ func NewRole() *Role {
 type AccessControl struct {
 	Path        string
 	Permissions Permission
+
+	// ExcludeWrite are names of leaves directly under Path that cannot be
+	// written even when Path can be
+	ExcludeWrite []string
 }
 
 type Permission int
@@ -71,19 +75,48 @@ func (self *Role) CheckActionPreConstraints(r *node.ActionRequest) (bool, error)
 }
 
 func (self *Role) ContextConstraint(s node.Selection) context.Context {
-	if acl, found := self.Access[meta.SchemaPath(s.Meta())]; found {
-		return context.WithValue(s.Context, permKey, acl.Permissions)
+	return self.contextFor(s.Meta(), s.Context)
+}
+
+// contextFor passes permission of m, if it has one, down to it's children
+func (self *Role) contextFor(m meta.Meta, c context.Context) context.Context {
+	if acl, found := self.Access[meta.SchemaPath(m)]; found {
+		return context.WithValue(c, permKey, acl.Permissions)
 	}
-	return s.Context
+	return c
 }
 
-func (self *Role) check(m meta.Meta, c context.Context, requested Permission) (bool, error) {
-	allowed := None
-	path := meta.SchemaPath(m)
-	if acl, found := self.Access[path]; found {
-		allowed = acl.Permissions
+// permission of m or, if m has none, the one it inherited from it's parents
+func (self *Role) permission(m meta.Meta, c context.Context) Permission {
+	if acl, found := self.Access[meta.SchemaPath(m)]; found {
+		return acl.Permissions
 	} else if x := c.Value(permKey); x != nil {
-		allowed = x.(Permission)
+		return x.(Permission)
+	}
+	return None
+}
+
+// writeExcluded is when m is a leaf that parent's access control does not
+// let be written
+func (self *Role) writeExcluded(m meta.Meta) bool {
+	leaf, isLeaf := m.(meta.Leafable)
+	if !isLeaf || leaf.Parent() == nil {
+		return false
+	}
+	if acl, found := self.Access[meta.SchemaPath(leaf.Parent())]; found {
+		for _, ident := range acl.ExcludeWrite {
+			if ident == leaf.Ident() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (self *Role) check(m meta.Meta, c context.Context, requested Permission) (bool, error) {
+	allowed := self.permission(m, c)
+	if requested == Full && self.writeExcluded(m) {
+		return false, fc.UnauthorizedError
 	}
 	if requested == Read {
 		return allowed >= Read, nil
@@ -178,6 +178,51 @@ notification identified {}
 	}
 }
 
+func TestAuthExcludeWrite(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module ifs { revision 0;
+container interface {
+	leaf description {
+		type string;
+	}
+	leaf admin-status {
+		type string;
+	}
+	container stats {
+		leaf admin-status {
+			type string;
+		}
+	}
+}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{
+		"interface": map[string]interface{}{
+			"description":  "uplink",
+			"admin-status": "up",
+			"stats":        map[string]interface{}{},
+		},
+	}
+	b := node.NewBrowser(m, nodeutil.ReflectChild(data))
+	role := NewRole()
+	role.Access["ifs"] = &AccessControl{Path: "ifs", Permissions: Full}
+	role.Access["ifs/interface"] = &AccessControl{
+		Path:         "ifs/interface",
+		Permissions:  Full,
+		ExcludeWrite: []string{"admin-status"},
+	}
+	s := b.Root()
+	s.Constraints.AddConstraint("auth", 0, 0, role)
+	s.Context = s.Constraints.ContextConstraint(s)
+	iface := s.Find("interface")
+	fc.AssertEqual(t, xAllowed, err2auth(iface.Set("description", "downlink")))
+	fc.AssertEqual(t, xUnauth, err2auth(iface.Set("admin-status", "down")))
+	fc.AssertEqual(t, xAllowed, val2auth(iface.GetValue("admin-status")))
+	// only leaves directly under path
+	fc.AssertEqual(t, xAllowed, err2auth(iface.Find("stats").Set("admin-status", "down")))
+}
+
 func val2auth(v val.Value, err error) string {
 	if v == nil {
 		return xHidden
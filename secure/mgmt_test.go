@@ -38,3 +38,25 @@ func TestManage(t *testing.T) {
 	fc.AssertEqual(t, 1, len(a.Roles))
 	//fc.AssertEqual(t, 3, len(a.Roles["sales"].Access))
 }
+
+func TestManageExcludeWrite(t *testing.T) {
+	a := NewRbac()
+	ypath := source.Dir("../yang")
+	b := node.NewBrowser(parser.RequireModule(ypath, "fc-secure"), Manage(a))
+	err := b.Root().UpsertFrom(nodeutil.ReadJSON(`{
+		"authorization" : {
+			"role" : [{
+				"id" : "ops",
+				"access" : [{
+					"path" : "m/x",
+					"perm" : "full",
+					"exclude-write" : ["y"]
+				}]
+			}]
+		}
+	}`)).LastErr
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, "y", a.Roles["ops"].Access["m/x"].ExcludeWrite[0])
+}
@@ -2,6 +2,7 @@ package restconf
 
 import (
 	"bytes"
+	"compress/gzip"
 	"container/list"
 	"context"
 	"fmt"
@@ -91,6 +92,14 @@ func (self *Server) ServeDevice(d device.Device) error {
 
 func (self *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			handleErr(fmt.Errorf("%w. %s", fc.BadRequestError, err), w)
+			return
+		}
+		r.Body = zr
+	}
 	if fc.DebugLogEnabled() {
 		fc.Debug.Printf("%s %s", r.Method, r.URL)
 		if r.Body != nil {
@@ -120,6 +129,9 @@ func (self *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.Set("Access-Control-Allow-Headers", "origin, content-type, accept")
 	h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS, DELETE, PATCH")
 	h.Set("Access-Control-Allow-Origin", "*")
+
+	// RFC7694 - tell clients they can compress requests
+	h.Set("Accept-Encoding", "gzip")
 	if r.URL.Path == "/" {
 		switch r.Method {
 		case "OPTIONS":
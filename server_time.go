@@ -0,0 +1,68 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServerTime is device's clock and how far ahead of local clock it is,
+// negative if behind.  Device time is from Date header of any response or,
+// if device sends no Date header, ietf-system's current-datetime.  Skew
+// assumes response was sent halfway thru round trip.  Date header only has
+// whole seconds so skew under a second or so is noise.
+func (self *client) ServerTime(ctx context.Context) (time.Time, time.Duration, error) {
+	start := time.Now()
+	// device need not support HEAD on data, Date of any response will do
+	var header http.Header
+	resp, err := self.sendRequest(ctx, request{
+		method: "HEAD",
+		url:    self.address.Data,
+		errMapper: func(resp *http.Response, _ []byte) error {
+			header = resp.Header
+			return nil
+		},
+	})
+	if err == nil {
+		header = resp.Header
+		resp.Body.Close()
+	} else if header == nil {
+		return time.Time{}, 0, err
+	}
+	serverTime, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		start = time.Now()
+		if serverTime, err = self.currentDatetime(ctx); err != nil {
+			return time.Time{}, 0, err
+		}
+	}
+	rtt := time.Since(start)
+	skew := serverTime.Sub(start.Add(rtt / 2))
+	return serverTime, skew, nil
+}
+
+// currentDatetime is from ietf-system (RFC 7317)
+func (self *client) currentDatetime(ctx context.Context) (time.Time, error) {
+	// cached clock would be stale
+	resp, err := self.sendRequest(WithoutCache(ctx), request{
+		method: "GET",
+		url:    self.address.Data + "ietf-system:system-state/clock",
+		target: "ietf-system:system-state/clock",
+		accept: mimeYangDataJson,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("device sent no Date header and could not read clock. %w", err)
+	}
+	defer resp.Body.Close()
+	var clock struct {
+		Clock struct {
+			CurrentDatetime string `json:"current-datetime"`
+		} `json:"ietf-system:clock"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&clock); err != nil {
+		return time.Time{}, err
+	}
+	return parseEventTime(clock.Clock.CurrentDatetime, nil)
+}
@@ -0,0 +1,54 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestClientServerTime(t *testing.T) {
+	skew := time.Hour
+	sendDate := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now().Add(skew)
+		if !sendDate {
+			// suppresses header http server would add
+			w.Header()["Date"] = nil
+			if r.URL.Path == "/restconf/data/ietf-system:system-state/clock" {
+				fmt.Fprintf(w, `{"ietf-system:clock":{"current-datetime":%q}}`, now.Format(time.RFC3339Nano))
+				return
+			}
+			http.Error(w, "not found", 404)
+			return
+		}
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+		// device doesn't need to support HEAD on data for Date header
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	ctx := context.Background()
+	near := func(expected time.Duration, actual time.Duration, tolerance time.Duration) {
+		t.Helper()
+		if actual < expected-tolerance || actual > expected+tolerance {
+			t.Errorf("expected skew near %s, got %s", expected, actual)
+		}
+	}
+
+	serverTime, actual, err := c.ServerTime(ctx)
+	fc.AssertEqual(t, nil, err)
+	near(time.Hour, actual, 2*time.Second)
+	near(time.Hour, time.Until(serverTime), 2*time.Second)
+
+	// clock from ietf-system has sub-second precision
+	sendDate = false
+	skew = -5 * time.Minute
+	_, actual, err = c.ServerTime(ctx)
+	fc.AssertEqual(t, nil, err)
+	near(-5*time.Minute, actual, 100*time.Millisecond)
+}
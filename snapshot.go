@@ -0,0 +1,110 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// ErrSnapshotChanged is when device's data changed since snapshot was taken
+// so it cannot be read consistently anymore
+var ErrSnapshotChanged = errors.New("device data changed since snapshot was taken")
+
+// Snapshot is a view of device's data that does not change while it is read
+// such as for config backups.  If device gives datastore an entity-tag,
+// reads go to device w/If-Match so any read after data changed fails
+// w/ErrSnapshotChanged instead of mixing old and new data.  Otherwise
+// entire datastore is read w/a single GET and reads come from that copy,
+// which is only as consistent as device makes a single response.
+//
+//	snap, err := dev.Snapshot(ctx)
+//	sel, err := snap.Root("ietf-interfaces")
+//	nodeutil.WriteJSON(sel)
+type Snapshot struct {
+	client *client
+	ctx    context.Context
+	token  string
+	data   map[string]interface{}
+}
+
+type snapshotKey struct{}
+
+func snapshotTokenFrom(ctx context.Context) (string, bool) {
+	token, found := ctx.Value(snapshotKey{}).(string)
+	return token, found && token != ""
+}
+
+// Snapshot of data in datastore from ctx, see WithDatastore, or device's
+// default datastore
+func (self *client) Snapshot(ctx context.Context) (*Snapshot, error) {
+	snap := &Snapshot{client: self, ctx: ctx}
+	resp, err := self.snapshotRequest(ctx, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if snap.token = resp.Header.Get("ETag"); snap.token != "" {
+		// cached responses may be from before token
+		snap.ctx = context.WithValue(WithoutCache(ctx), snapshotKey{}, snap.token)
+		return snap, nil
+	}
+	fc.Debug.Printf("%s gave no entity-tag for datastore, reading all of it", self.address.Base)
+	if resp, err = self.snapshotRequest(ctx, "GET"); err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&snap.data); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// snapshotRequest reads datastore from device, never cache, so snapshot
+// starts from what device has now
+func (self *client) snapshotRequest(ctx context.Context, method string) (*http.Response, error) {
+	resp, err := self.sendRequest(WithoutCache(ctx), request{
+		method: method,
+		url:    self.dataUrl(ctx),
+		accept: mimeYangDataJson,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not take snapshot. %w", err)
+	}
+	return resp, nil
+}
+
+// Consistent is true when device gave datastore an entity-tag so reads
+// are guaranteed to be from same version of data or fail
+func (self *Snapshot) Consistent() bool {
+	return self.token != ""
+}
+
+// Root of module's data in snapshot
+func (self *Snapshot) Root(module string) (node.Selection, error) {
+	if self.token != "" {
+		b, err := self.client.Browser(module)
+		if err != nil {
+			return node.Selection{}, err
+		}
+		return b.RootWithContext(self.ctx), nil
+	}
+	m, err := self.client.module(module)
+	if err != nil {
+		return node.Selection{}, err
+	}
+	data := make(map[string]interface{})
+	for ident, v := range self.data {
+		if strings.HasPrefix(ident, m.Ident()+":") {
+			data[strings.TrimPrefix(ident, m.Ident()+":")] = v
+		}
+	}
+	return node.NewBrowser(m, jsonContainerReader(data)).RootWithContext(self.ctx), nil
+}
@@ -0,0 +1,130 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestSnapshot(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			leaf y { type int32; }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	version := 1
+	tokens := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if tokens {
+			if match := r.Header.Get("If-Match"); match != "" && match != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		}
+		if r.Method == "HEAD" {
+			return
+		}
+		if r.URL.Path == "/restconf/data/" {
+			fmt.Fprintf(w, `{"m:x":{"y":%d}}`, version)
+			return
+		}
+		fmt.Fprintf(w, `{"y":%d}`, version)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	read := func(snap *Snapshot) (string, error) {
+		sel, err := snap.Root("m")
+		fc.AssertEqual(t, nil, err)
+		actual, err := sel.Find("x").Get("y")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(actual), nil
+	}
+
+	// device w/consistency token
+	snap, err := c.Snapshot(context.Background())
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, snap.Consistent())
+	actual, err := read(snap)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "1", actual)
+	actual, err = read(snap)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "1", actual)
+	version = 2
+	_, err = read(snap)
+	fc.AssertEqual(t, true, errors.Is(err, ErrSnapshotChanged))
+
+	// device w/o token, best effort single read
+	tokens = false
+	snap, err = c.Snapshot(context.Background())
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, snap.Consistent())
+	version = 3
+	actual, err = read(snap)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "2", actual)
+}
+
+func TestSnapshotSkipsCache(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x {
+			leaf y { type int32; }
+		}
+	}`)
+	fc.AssertEqual(t, nil, err)
+	version := 1
+	tokens := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		if tokens {
+			w.Header().Set("ETag", fmt.Sprintf(`"v%d"`, version))
+		}
+		if r.URL.Path == "/restconf/data/" {
+			fmt.Fprintf(w, `{"m:x":{"y":%d}}`, version)
+			return
+		}
+		fmt.Fprintf(w, `{"y":%d}`, version)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	c.cache = newResponseCache(10)
+	ctx := context.Background()
+	read := func(snap *Snapshot) string {
+		sel, err := snap.Root("m")
+		fc.AssertEqual(t, nil, err)
+		actual, err := sel.Find("x").Get("y")
+		fc.AssertEqual(t, nil, err)
+		return fmt.Sprint(actual)
+	}
+
+	// prime cache w/datastore and resource
+	_, err = c.GetRaw(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	resp, err := c.sendRequest(ctx, request{method: "GET", url: c.dataUrl(ctx), accept: mimeYangDataJson})
+	fc.AssertEqual(t, nil, err)
+	resp.Body.Close()
+	fc.AssertEqual(t, 2, c.cache.len())
+	version = 2
+
+	snap, err := c.Snapshot(ctx)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, snap.Consistent())
+	fc.AssertEqual(t, "2", read(snap))
+
+	tokens = false
+	snap, err = c.Snapshot(ctx)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, snap.Consistent())
+	fc.AssertEqual(t, "2", read(snap))
+}
@@ -0,0 +1,49 @@
+package restconf
+
+import (
+	"net/url"
+
+	"github.com/freeconf/yang/node"
+)
+
+// SortConstraint is id of SortHint in a selection's constraints
+const SortConstraint = "restconf-sort"
+
+// SortHint asks server to order entries of lists read thru a selection.
+// Sent as sort=<field> or sort=-<field> when descending.  There is no
+// standard for this in RFC 8040 so servers that do not support it will
+// ignore it and entries are iterated in whatever order server returns them.
+type SortHint struct {
+	Field      string
+	Descending bool
+}
+
+// WithSort adds sort hint to sel's constraints
+//
+//	sel := restconf.WithSort(b.Root().Find("interfaces"), "name", false)
+func WithSort(sel node.Selection, field string, descending bool) node.Selection {
+	sel.Constraints.AddConstraint(SortConstraint, 0, 0, SortHint{Field: field, Descending: descending})
+	return sel
+}
+
+// CheckListPreConstraints has nothing to check, hint is only read when
+// requesting list from server
+func (self SortHint) CheckListPreConstraints(r *node.ListRequest) (bool, error) {
+	return true, nil
+}
+
+func (self SortHint) param() string {
+	field := self.Field
+	if self.Descending {
+		field = "-" + field
+	}
+	return "sort=" + url.QueryEscape(field)
+}
+
+func sortHintFrom(constraints *node.Constraints) (SortHint, bool) {
+	if constraints == nil {
+		return SortHint{}, false
+	}
+	hint, found := constraints.Constraint(SortConstraint).(SortHint)
+	return hint, found && hint.Field != ""
+}
@@ -0,0 +1,37 @@
+package restconf
+
+import (
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestSortHint(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x {namespace ""; prefix ""; revision 0;
+		list car {
+			key name;
+			leaf name {
+				type string;
+			}
+		}
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// server ignores hint so entries are in whatever order server sends them
+	support := &testDriverFlowSupport{
+		t:   t,
+		get: map[string]string{"car": `{"car":[{"name":"b"},{"name":"c"},{"name":"a"}]}`},
+	}
+	b := node.NewBrowser(m, (&clientNode{support: support}).node())
+	sel := WithSort(b.Root().Find("car"), "name", true)
+	actual, err := nodeutil.WriteJSON(sel)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "sort=-name", support.getParams[0])
+	fc.AssertEqual(t, `{"car":[{"name":"b"},{"name":"c"},{"name":"a"}]}`, actual)
+
+	fc.AssertEqual(t, "sort=name", SortHint{Field: "name"}.param())
+}
@@ -8,37 +8,64 @@ import (
 )
 
 const (
-	sseDataPrefix = "data: "
+	sseDataPrefix  = "data: "
+	sseEventPrefix = "event: "
+	sseIdPrefix    = "id: "
 )
 
-// we only have to decode whatever server is sending.  so far it's just "data: " fields
-func decodeSse(in io.Reader) <-chan []byte {
-	events := make(chan []byte)
+// sseFrame is a single dispatched server sent event.  When server does not
+// send an "event: " line, event is empty which by SSE spec means "message"
+type sseFrame struct {
+	event string
+	id    string
+	data  []byte
+}
+
+// we only have to decode whatever server is sending.  so far it's "data: ", "event: "
+// and "id: " fields.  Closing done stops decoding once reader is no longer
+// receiving events, otherwise decoding stops at end of input.
+func decodeSse(in io.Reader, done <-chan struct{}) <-chan sseFrame {
+	events := make(chan sseFrame)
 	r := bufio.NewReader(in)
 	go func() {
 		defer close(events)
 		var buff bytes.Buffer
-		send := func() {
-			if buff.Len() > 0 {
-				orig := buff.Bytes()
-				dup := make([]byte, len(orig))
-				copy(dup, orig)
-				events <- dup
-				buff.Reset()
+		var frame sseFrame
+		send := func() bool {
+			defer func() {
+				frame = sseFrame{}
+			}()
+			if buff.Len() == 0 {
+				return true
+			}
+			orig := buff.Bytes()
+			frame.data = make([]byte, len(orig))
+			copy(frame.data, orig)
+			buff.Reset()
+			select {
+			case events <- frame:
+				return true
+			case <-done:
+				return false
 			}
 		}
 		for {
 			line, err := r.ReadBytes('\n')
 			size := len(line)
+			end := size
+			if end > 0 && line[end-1] == '\n' {
+				end--
+			}
 			if size <= 1 {
-				send()
-			} else if strings.HasPrefix(string(line), sseDataPrefix) {
-				end := size
-				if line[end-1] == '\n' {
-					end--
+				if !send() {
+					return
 				}
-				chunk := line[len(sseDataPrefix):end]
-				buff.Write(chunk)
+			} else if strings.HasPrefix(string(line), sseDataPrefix) {
+				buff.Write(line[len(sseDataPrefix):end])
+			} else if strings.HasPrefix(string(line), sseEventPrefix) {
+				frame.event = string(line[len(sseEventPrefix):end])
+			} else if strings.HasPrefix(string(line), sseIdPrefix) {
+				frame.id = string(line[len(sseIdPrefix):end])
 			}
 			if err != nil {
 				// EOF or other; stream is no longer
@@ -3,6 +3,8 @@ package restconf
 import (
 	"strings"
 	"testing"
+
+	"github.com/freeconf/yang/fc"
 )
 
 func TestSseDecode(t *testing.T) {
@@ -31,12 +33,39 @@ data: foo
 		},
 	}
 	for _, test := range tests {
-		events := decodeSse(strings.NewReader(test.payload))
+		events := decodeSse(strings.NewReader(test.payload), nil)
 		for _, expected := range test.expected {
 			actual := <-events
-			if expected != string(actual) {
-				t.Errorf("expected '%s' got '%s'", expected, actual)
+			if expected != string(actual.data) {
+				t.Errorf("expected '%s' got '%s'", expected, actual.data)
 			}
 		}
 	}
 }
+
+func TestSseDecodeEventAndId(t *testing.T) {
+	payload := `
+event: a
+id: 1
+data: x
+
+data: y
+
+id: 3
+event: b
+data: z
+`
+	events := decodeSse(strings.NewReader(payload), nil)
+	f := <-events
+	fc.AssertEqual(t, "a", f.event)
+	fc.AssertEqual(t, "1", f.id)
+	fc.AssertEqual(t, "x", string(f.data))
+	f = <-events
+	fc.AssertEqual(t, "", f.event)
+	fc.AssertEqual(t, "", f.id)
+	fc.AssertEqual(t, "y", string(f.data))
+	f = <-events
+	fc.AssertEqual(t, "b", f.event)
+	fc.AssertEqual(t, "3", f.id)
+	fc.AssertEqual(t, "z", string(f.data))
+}
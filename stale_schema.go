@@ -0,0 +1,105 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// ErrStaleSchema is when device rejected a request because an element or
+// namespace is unknown to it which usually means module on device changed.
+// See Client.RetryStaleSchema.
+var ErrStaleSchema = errors.New("schema is out of date")
+
+// staleSchemaErr is still the error for response's status
+type staleSchemaErr struct {
+	err error
+}
+
+func (self staleSchemaErr) Error() string {
+	return self.err.Error()
+}
+
+func (self staleSchemaErr) Unwrap() error {
+	return self.err
+}
+
+func (self staleSchemaErr) Is(target error) bool {
+	return target == ErrStaleSchema
+}
+
+// staleSchemaTag is whether an ietf-restconf:errors body has an error-tag
+// that means element sent is not in device's schema
+func staleSchemaTag(body []byte) bool {
+	var doc struct {
+		Errors *struct {
+			Error []struct {
+				Tag string `json:"error-tag"`
+			} `json:"error"`
+		} `json:"ietf-restconf:errors"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil || doc.Errors == nil {
+		return false
+	}
+	for _, e := range doc.Errors.Error {
+		if e.Tag == "unknown-element" || e.Tag == "unknown-namespace" {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshSchema loads module of p from device regardless of how long it has
+// been cached and resolves p in it
+func (self *client) refreshSchema(p *node.Path) (*node.Path, error) {
+	name := meta.RootModule(p.Meta()).Ident()
+	m, err := self.reloadModule(name)
+	if err != nil {
+		return nil, err
+	}
+	slice, err := node.ParsePath(p.StringNoModule(), m)
+	if err != nil {
+		return nil, err
+	}
+	return slice.Tail, nil
+}
+
+// reloadModule replaces cached module w/device's copy.  Browsers made
+// after use new module.
+func (self *client) reloadModule(name string) (*meta.Module, error) {
+	// unconditional, device may not have changed revision
+	self.modulesLock.Lock()
+	delete(self.moduleChecks, name)
+	self.modulesLock.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), moduleRevalidateTimeout)
+	defer cancel()
+	resp, err := self.schemaRequest(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	self.modulesLock.Lock()
+	self.moduleFresh(name, resp)
+	self.modulesLock.Unlock()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not load module %s. %s", name, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	m, err := self.parseDeviceModule(name, body)
+	if err != nil {
+		return nil, err
+	}
+	self.modulesLock.Lock()
+	self.modules[name] = m
+	self.modulesLock.Unlock()
+	return m, nil
+}
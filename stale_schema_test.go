@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestClientRetryStaleSchema(t *testing.T) {
+	old, err := parser.LoadModuleFromString(nil, `module m { namespace "m"; prefix "m"; revision 0;
+		container x { leaf a { type int32; } leaf gone { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var edits []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/restconf/schema/m.yang":
+			// device was upgraded w/o changing revision
+			w.Write([]byte(`module m { namespace "m"; prefix "m"; revision 0; container x { leaf a { type int32; } } }`))
+		case r.Method == "GET" || r.Method == "OPTIONS":
+			w.Write([]byte(`{}`))
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			edits = append(edits, string(body))
+			if strings.Contains(string(body), "gone") {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"ietf-restconf:errors":{"error":[{"error-type":"application","error-tag":"unknown-element","error-message":"gone"}]}}`))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+	address, _ := NewAddress(srv.URL + "/restconf")
+	remote := httpStream{client: srv.Client(), url: address.Schema}
+	newClient := func(retry bool) *client {
+		c := newTestClient(srv, old)
+		c.schemaPath = remote.OpenStream
+		c.retryStale = retry
+		return c
+	}
+	edit := func(c *client) error {
+		b, err := c.Browser("m")
+		fc.AssertEqual(t, nil, err)
+		return b.Root().Find("x").UpsertFrom(nodeutil.ReadJSON(`{"a":1,"gone":2}`)).LastErr
+	}
+
+	// off
+	err = edit(newClient(false))
+	fc.AssertEqual(t, true, errors.Is(err, ErrStaleSchema))
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+	fc.AssertEqual(t, 1, len(edits))
+
+	// refreshed and retried once
+	edits = nil
+	c := newClient(true)
+	fc.AssertEqual(t, nil, edit(c))
+	fc.AssertEqual(t, 2, len(edits))
+	fc.AssertEqual(t, `{"a":1}`, edits[1])
+	fc.AssertEqual(t, true, meta.Find(c.Modules()["m"], "x/gone") == nil)
+}
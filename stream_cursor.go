@@ -0,0 +1,46 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+
+	"github.com/freeconf/yang/node"
+)
+
+type cursorKey struct{}
+
+// WithCursor resumes notification subscriptions made w/this context after
+// event w/cursor, as given by EventCursor or Subscription.Cursor, by sending
+// it as SSE Last-Event-ID.  Device decides how far back it can resume.
+//
+//	sub, err := dev.Subscribe(restconf.WithCursor(ctx, saved), "m:x")
+func WithCursor(ctx context.Context, cursor string) context.Context {
+	return context.WithValue(ctx, cursorKey{}, cursor)
+}
+
+func cursorFrom(ctx context.Context) (string, bool) {
+	cursor, found := ctx.Value(cursorKey{}).(string)
+	return cursor, found && cursor != ""
+}
+
+// EventCursor is opaque id device gave event, including events that could
+// not be decoded, to persist once event is processed so subscription can
+// resume after it w/WithCursor.  False if device gave event no id.
+func EventCursor(n node.Node) (string, bool) {
+	if e, valid := n.(deviceEvent); valid && e.cursor != "" {
+		return e.cursor, true
+	}
+	var decodeErr *EventDecodeError
+	if errNode, isErr := n.(node.ErrorNode); isErr && errors.As(errNode.Err, &decodeErr) && decodeErr.Id != "" {
+		return decodeErr.Id, true
+	}
+	return "", false
+}
+
+// Cursor is id of latest event from device that was handed to subscriber or
+// queued for it, empty if device has not given any ids.  Queued events are
+// not yet processed so to resume exactly where processing stopped, use
+// EventCursor on each event instead.
+func (self *Subscription) Cursor() string {
+	return self.counters.lastCursor()
+}
@@ -0,0 +1,126 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// Notification encodings as ietf-restconf-monitoring lists them for each
+// stream.  See Client.StreamEncoding.
+const (
+	StreamEncodingJSON = "json"
+	StreamEncodingXML  = "xml"
+
+	// StreamEncodingAuto uses XML if no stream device lists in
+	// ietf-restconf-monitoring offers JSON, otherwise JSON
+	StreamEncodingAuto = "auto"
+)
+
+// netconfNotificationNs is namespace of XML envelope of each notification
+// (RFC 5277) that RFC 8040 section 6.4 reuses
+const netconfNotificationNs = "urn:ietf:params:xml:ns:netconf:notification:1.0"
+
+type streamEncodingKey struct{}
+
+// WithStreamEncoding decodes notifications of subscriptions made w/this
+// context as StreamEncodingJSON or StreamEncodingXML regardless of
+// Client.StreamEncoding.
+//
+//	sub, err := dev.Subscribe(restconf.WithStreamEncoding(ctx, restconf.StreamEncodingXML), "m:x")
+func WithStreamEncoding(ctx context.Context, encoding string) context.Context {
+	return context.WithValue(ctx, streamEncodingKey{}, encoding)
+}
+
+// streamEncoding is how notifications of stream opened w/ctx are decoded
+func (self *client) streamEncoding(ctx context.Context) string {
+	if encoding, found := ctx.Value(streamEncodingKey{}).(string); found && encoding != StreamEncodingAuto {
+		return encoding
+	}
+	if self.streamEnc != StreamEncodingAuto {
+		return self.streamEnc
+	}
+	self.advertisedLock.Lock()
+	defer self.advertisedLock.Unlock()
+	if self.advertisedEnc == "" {
+		self.advertisedEnc = StreamEncodingJSON
+		streams, err := self.Streams(ctx)
+		if err != nil {
+			fc.Debug.Printf("could not learn stream encoding, assuming json. %s", err)
+		} else if advertisedEncoding(streams) == StreamEncodingXML {
+			self.advertisedEnc = StreamEncodingXML
+		}
+	}
+	return self.advertisedEnc
+}
+
+// advertisedEncoding is XML only if streams offer XML but none offer JSON
+func advertisedEncoding(streams []StreamInfo) string {
+	xml := false
+	for _, s := range streams {
+		if _, found := s.Access[StreamEncodingJSON]; found {
+			return StreamEncodingJSON
+		}
+		_, found := s.Access[StreamEncodingXML]
+		xml = xml || found
+	}
+	if xml {
+		return StreamEncodingXML
+	}
+	return StreamEncodingJSON
+}
+
+// decodeXmlEvent is like decodeEvent for XML encoded notifications.
+// Notifications in an RFC 8040 envelope are unwrapped and eventTime is
+// parsed w/layouts.
+func decodeXmlEvent(m meta.Meta, frame sseFrame, layouts []string) node.Node {
+	fail := func(err error) node.Node {
+		return node.ErrorNode{Err: &EventDecodeError{
+			Event: frame.event,
+			Id:    frame.id,
+			Frame: frame.data,
+			Err:   err,
+		}}
+	}
+	elems, err := decodeXml(bytes.NewReader(frame.data))
+	if err != nil {
+		return fail(err)
+	}
+	if len(elems) != 1 {
+		return fail(fmt.Errorf("expected 1 element but got %d", len(elems)))
+	}
+	body := elems[0]
+	var eventTime time.Time
+	timed := false
+	if body.XMLName.Space == netconfNotificationNs && body.XMLName.Local == "notification" {
+		envelope := body
+		body = &xmlElem{}
+		for _, c := range envelope.Children {
+			if c.XMLName.Local == "eventTime" {
+				if eventTime, err = parseEventTime(strings.TrimSpace(c.Text), layouts); err != nil {
+					return fail(err)
+				}
+				timed = true
+			} else {
+				body = c
+			}
+		}
+	}
+	if notif, isNotif := m.(*meta.Notification); isNotif {
+		for _, c := range body.Children {
+			if meta.Find(notif, c.XMLName.Local) == nil {
+				return fail(fmt.Errorf("%w. %s has no %s", fc.NotFoundError, notif.Ident(), c.XMLName.Local))
+			}
+		}
+	}
+	if timed || frame.id != "" {
+		return deviceEvent{Node: xmlContainerReader(body), eventTime: eventTime, timed: timed, cursor: frame.id}
+	}
+	return xmlContainerReader(body)
+}
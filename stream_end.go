@@ -0,0 +1,67 @@
+package restconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// StreamError is why device ended a notification stream, either from a
+// final "error" event w/ietf-restconf:errors or from Error-Tag and
+// Error-Message HTTP trailers.  Consumers can use it to decide whether to
+// reconnect.  It is also ErrStreamEnded.
+type StreamError struct {
+	Type    string
+	Tag     string
+	Message string
+}
+
+func (self *StreamError) Error() string {
+	msg := self.Message
+	if msg == "" {
+		msg = self.Tag
+	} else if self.Tag != "" {
+		msg = self.Tag + ": " + msg
+	}
+	return fmt.Sprintf("%s. %s", ErrStreamEnded, msg)
+}
+
+func (self *StreamError) Unwrap() error {
+	return ErrStreamEnded
+}
+
+// streamFrameErr is error in frame if frame is an error and not a
+// notification
+func streamFrameErr(frame sseFrame) (*StreamError, bool) {
+	var doc struct {
+		Errors *struct {
+			Error []struct {
+				Type    string `json:"error-type"`
+				Tag     string `json:"error-tag"`
+				Message string `json:"error-message"`
+			} `json:"error"`
+		} `json:"ietf-restconf:errors"`
+	}
+	if err := json.Unmarshal(frame.data, &doc); err != nil || doc.Errors == nil {
+		if frame.event == "error" {
+			return &StreamError{Message: string(frame.data)}, true
+		}
+		return nil, false
+	}
+	streamErr := &StreamError{}
+	if len(doc.Errors.Error) > 0 {
+		first := doc.Errors.Error[0]
+		streamErr.Type, streamErr.Tag, streamErr.Message = first.Type, first.Tag, first.Message
+	}
+	return streamErr, true
+}
+
+// streamTrailerErr is error from trailers sent after stream body, if any.
+// Trailers are only there once body was read to the end.
+func streamTrailerErr(trailer http.Header) error {
+	tag, msg := trailer.Get("Error-Tag"), trailer.Get("Error-Message")
+	if tag == "" && msg == "" {
+		return nil
+	}
+	return &StreamError{Tag: tag, Message: msg}
+}
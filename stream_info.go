@@ -0,0 +1,83 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// ErrNoStreamInfo is when device does not implement ietf-restconf-monitoring
+// so it's streams cannot be listed.  This is not a failure, device may still
+// have streams.
+var ErrNoStreamInfo = errors.New("device does not list streams")
+
+// StreamInfo is an event stream from ietf-restconf-monitoring (RFC 8040
+// section 9.3)
+type StreamInfo struct {
+	Name          string
+	Description   string
+	ReplaySupport bool
+
+	// Location is url to subscribe to stream w/JSON encoding or, if device
+	// has no JSON encoding, first one device listed
+	Location string
+
+	// Access is location of stream by encoding such as "json" or "xml"
+	Access map[string]string
+}
+
+type restconfStreams struct {
+	Streams struct {
+		Stream []struct {
+			Name          string `json:"name"`
+			Description   string `json:"description"`
+			ReplaySupport bool   `json:"replay-support"`
+			Access        []struct {
+				Encoding string `json:"encoding"`
+				Location string `json:"location"`
+			} `json:"access"`
+		} `json:"stream"`
+	} `json:"ietf-restconf-monitoring:streams"`
+}
+
+// Streams lists event streams device has.  Returns an empty list and
+// ErrNoStreamInfo if device does not implement ietf-restconf-monitoring.
+func (self *client) Streams(ctx context.Context) ([]StreamInfo, error) {
+	const target = "ietf-restconf-monitoring:restconf-state/streams"
+	resp, err := self.sendRequest(ctx, request{
+		method: "GET",
+		url:    self.address.Data + target,
+		target: target,
+		accept: mimeYangDataJson,
+	})
+	if errors.Is(err, fc.NotFoundError) {
+		return []StreamInfo{}, ErrNoStreamInfo
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var doc restconfStreams
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	infos := make([]StreamInfo, 0, len(doc.Streams.Stream))
+	for _, s := range doc.Streams.Stream {
+		info := StreamInfo{
+			Name:          s.Name,
+			Description:   s.Description,
+			ReplaySupport: s.ReplaySupport,
+			Access:        make(map[string]string, len(s.Access)),
+		}
+		for _, a := range s.Access {
+			info.Access[a.Encoding] = a.Location
+			if info.Location == "" || a.Encoding == "json" {
+				info.Location = a.Location
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
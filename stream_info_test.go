@@ -0,0 +1,49 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestClientStreams(t *testing.T) {
+	var urlPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urlPath = r.URL.Path
+		fmt.Fprint(w, `{"ietf-restconf-monitoring:streams":{"stream":[
+			{"name":"NETCONF","description":"default","replay-support":true,"access":[
+				{"encoding":"xml","location":"https://x/streams/NETCONF/XML"},
+				{"encoding":"json","location":"https://x/streams/NETCONF/JSON"}
+			]},
+			{"name":"alarms","access":[{"encoding":"xml","location":"https://x/streams/alarms"}]}
+		]}}`)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	streams, err := c.Streams(context.Background())
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "/restconf/data/ietf-restconf-monitoring:restconf-state/streams", urlPath)
+	fc.AssertEqual(t, 2, len(streams))
+	fc.AssertEqual(t, "NETCONF", streams[0].Name)
+	fc.AssertEqual(t, "default", streams[0].Description)
+	fc.AssertEqual(t, true, streams[0].ReplaySupport)
+	fc.AssertEqual(t, "https://x/streams/NETCONF/JSON", streams[0].Location)
+	fc.AssertEqual(t, "https://x/streams/NETCONF/XML", streams[0].Access["xml"])
+	fc.AssertEqual(t, "https://x/streams/alarms", streams[1].Location)
+}
+
+func TestClientStreamsNotImplemented(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	streams, err := c.Streams(context.Background())
+	fc.AssertEqual(t, true, errors.Is(err, ErrNoStreamInfo))
+	fc.AssertEqual(t, 0, len(streams))
+}
@@ -0,0 +1,135 @@
+package restconf
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// StreamStats are counters for a single notification subscription to help
+// explain why a subscriber is missing notifications
+type StreamStats struct {
+
+	// Path is module:path of notification
+	Path string
+
+	// Received from device
+	Received uint64
+
+	// Delivered to subscriber
+	Delivered uint64
+
+	// Dropped because subscriber could not keep up. See
+	// Client.StreamDropWhenFull
+	Dropped uint64
+
+	// Queued waiting for subscriber to read them
+	Queued int
+}
+
+type streamCounters struct {
+	received uint64
+	accepted uint64
+	dropped  uint64
+
+	// set each time stream is opened, read by stats from any goroutine
+	lock   sync.Mutex
+	path   string
+	queued func() int
+
+	// last error, see Subscription.Err
+	err error
+
+	// see Subscription.Cursor
+	cursor atomic.Value
+
+	// id of dynamic subscription if device gave one, see
+	// Subscription.UpdateFilter
+	subscriptionId atomic.Value
+}
+
+// opened records stream counters are now counting
+func (self *streamCounters) opened(path string, queued func() int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.path = path
+	self.queued = queued
+}
+
+func (self *streamCounters) stats() StreamStats {
+	self.lock.Lock()
+	path, queuedFn := self.path, self.queued
+	self.lock.Unlock()
+	var queued int
+	if queuedFn != nil {
+		queued = queuedFn()
+	}
+	accepted := atomic.LoadUint64(&self.accepted)
+	delivered := uint64(0)
+	if accepted > uint64(queued) {
+		delivered = accepted - uint64(queued)
+	}
+	return StreamStats{
+		Path:      path,
+		Received:  atomic.LoadUint64(&self.received),
+		Delivered: delivered,
+		Dropped:   atomic.LoadUint64(&self.dropped),
+		Queued:    queued,
+	}
+}
+
+func (self *streamCounters) setErr(err error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.err = err
+}
+
+func (self *streamCounters) setCursor(cursor string) {
+	if cursor != "" {
+		self.cursor.Store(cursor)
+	}
+}
+
+func (self *streamCounters) lastCursor() string {
+	cursor, _ := self.cursor.Load().(string)
+	return cursor
+}
+
+func (self *streamCounters) lastErr() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.err
+}
+
+// StreamStats of each active notification subscription
+func (self *client) StreamStats() []StreamStats {
+	self.streamsLock.Lock()
+	defer self.streamsLock.Unlock()
+	stats := make([]StreamStats, 0, len(self.streams))
+	for s := range self.streams {
+		stats = append(stats, s.stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Path < stats[j].Path
+	})
+	return stats
+}
+
+func (self *client) addStream(s *streamCounters) {
+	self.streamsLock.Lock()
+	defer self.streamsLock.Unlock()
+	if self.streams == nil {
+		self.streams = make(map[*streamCounters]int)
+	}
+	self.streams[s]++
+}
+
+func (self *client) removeStream(s *streamCounters) {
+	self.streamsLock.Lock()
+	defer self.streamsLock.Unlock()
+	// replaced stream may be removed after it's replacement was added, see
+	// Subscription.UpdateFilter
+	if self.streams[s]--; self.streams[s] <= 0 {
+		delete(self.streams, s)
+	}
+}
@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+)
+
+func TestStreamStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, "data: {\"n\":%d}\n\n", i)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	c.streamBuffer = 2
+	c.streamDrop = true
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := c.clientStream("", requestBuilder{}.path(`container x {}`), ctx)
+	fc.AssertEqual(t, nil, err)
+
+	// stats are safe to read while stream is active
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				c.StreamStats()
+			}
+		}
+	}()
+	defer close(done)
+
+	var stats StreamStats
+	waitFor(t, "all events received", func() bool {
+		stats = c.StreamStats()[0]
+		return stats.Received == 10
+	})
+	fc.AssertEqual(t, "m:x", stats.Path)
+	fc.AssertEqual(t, uint64(10), stats.Received)
+	fc.AssertEqual(t, uint64(8), stats.Dropped)
+	fc.AssertEqual(t, 2, stats.Queued)
+	fc.AssertEqual(t, uint64(0), stats.Delivered)
+
+	<-events
+	<-events
+	stats = c.StreamStats()[0]
+	fc.AssertEqual(t, 0, stats.Queued)
+	fc.AssertEqual(t, uint64(2), stats.Delivered)
+
+	cancel()
+	waitFor(t, "stream removed", func() bool {
+		return len(c.StreamStats()) == 0
+	})
+}
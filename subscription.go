@@ -0,0 +1,176 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/freeconf/yang/fc"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+)
+
+// ErrStreamEnded is when device closed notification stream while subscriber
+// was still listening
+var ErrStreamEnded = errors.New("device ended notification stream")
+
+// Subscription to a notification stream on a device
+type Subscription struct {
+	events   chan node.Node
+	cancel   context.CancelFunc
+	counters *streamCounters
+	ctx      context.Context
+	support  clientSupport
+	p        *node.Path
+
+	// stream from device events are relayed from, replaced when filter
+	// changes, see UpdateFilter
+	openLock  sync.Mutex
+	lock      sync.Mutex
+	filter    string
+	stream    context.CancelFunc
+	relayDone chan struct{}
+	gen       int
+	ended     bool
+	// of last event subscriber got, where replacement stream resumes
+	delivered string
+}
+
+// Subscribe to notifications at path in module:path form.  Subscription ends
+// when ctx is done or subscription is closed.
+func (self *client) Subscribe(ctx context.Context, path string) (*Subscription, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return subscribe(ctx, self.support, p)
+}
+
+func subscribe(ctx context.Context, support clientSupport, p *node.Path) (*Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &Subscription{
+		events:   make(chan node.Node),
+		cancel:   cancel,
+		counters: &streamCounters{path: meta.RootModule(p.Meta()).Ident() + ":" + urlPath(p)},
+		ctx:      ctx,
+		support:  support,
+		p:        p,
+	}
+	if err := sub.open(""); err != nil {
+		cancel()
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Events from device.  Channel is closed when subscription ends.  Events that
+// could not be decoded are error nodes, see RawFrame.
+func (self *Subscription) Events() <-chan node.Node {
+	return self.events
+}
+
+// Close ends subscription.  Signature is compatible w/node.NotifyCloser.
+func (self *Subscription) Close() error {
+	self.cancel()
+	return nil
+}
+
+// Err is last error, either an event that could not be decoded or
+// ErrStreamEnded.  If device said why it ended stream, error is a
+// *StreamError.  Nil if there have been no errors.
+func (self *Subscription) Err() error {
+	return self.counters.lastErr()
+}
+
+// Path of notification subscription is to, events are data of it's meta
+func (self *Subscription) Path() *node.Path {
+	return self.p
+}
+
+// Stats for this subscription
+func (self *Subscription) Stats() StreamStats {
+	return self.counters.stats()
+}
+
+type streamCountersKey struct{}
+
+// withStreamCounters lets subscriber see counters of stream it's reading from
+func withStreamCounters(ctx context.Context, counters *streamCounters) context.Context {
+	return context.WithValue(ctx, streamCountersKey{}, counters)
+}
+
+func streamCountersFrom(ctx context.Context) *streamCounters {
+	if counters, found := ctx.Value(streamCountersKey{}).(*streamCounters); found {
+		return counters
+	}
+	return &streamCounters{}
+}
+
+// EventDecodeError is when an event from device could not be decoded into
+// loaded schema, for example device sent notification from a module client
+// did not load.  Frame is kept exactly as device sent it so it can still be
+// logged or forwarded.  Subscribers get this inside a node.ErrorNode.
+type EventDecodeError struct {
+	Event string
+	Id    string
+	Frame []byte
+	Err   error
+}
+
+func (self *EventDecodeError) Error() string {
+	return fmt.Sprintf("could not decode event %s. %s", self.Frame, self.Err)
+}
+
+func (self *EventDecodeError) Unwrap() error {
+	return self.Err
+}
+
+// RawFrame is frame of event that could not be decoded or nil if n is not a
+// decode error
+func RawFrame(n node.Node) []byte {
+	var decodeErr *EventDecodeError
+	if errNode, isErr := n.(node.ErrorNode); isErr && errors.As(errNode.Err, &decodeErr) {
+		return decodeErr.Frame
+	}
+	return nil
+}
+
+// decodeEvent reads event data as JSON and, for notifications, checks that
+// each field is in schema.  Notifications in an RFC 8040 envelope are
+// unwrapped and eventTime is parsed w/layouts.
+func decodeEvent(m meta.Meta, frame sseFrame, layouts []string) node.Node {
+	fail := func(err error) node.Node {
+		return node.ErrorNode{Err: &EventDecodeError{
+			Event: frame.event,
+			Id:    frame.id,
+			Frame: frame.data,
+			Err:   err,
+		}}
+	}
+	// numbers decoded as leaf's type, not float64, see jsonReader
+	var data map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(frame.data))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return fail(err)
+	}
+	data, eventTime, timed, err := unwrapNotification(data, layouts)
+	if err != nil {
+		return fail(err)
+	}
+	if notif, isNotif := m.(*meta.Notification); isNotif {
+		for _, ident := range sortedKeys(data) {
+			if meta.Find(notif, ident) == nil {
+				return fail(fmt.Errorf("%w. %s has no %s", fc.NotFoundError, notif.Ident(), ident))
+			}
+		}
+	}
+	if timed || frame.id != "" {
+		return deviceEvent{Node: jsonContainerReader(data), eventTime: eventTime, timed: timed, cursor: frame.id}
+	}
+	return jsonContainerReader(data)
+}
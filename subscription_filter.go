@@ -0,0 +1,165 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// UpdateFilter replaces filter, an XPath expression as in RFC 8040 filter
+// query parameter, on events of an active subscription.  Empty filter
+// removes filter.  If device sent a Subscription-Id header w/stream,
+// subscription is modified in place w/ietf-subscribed-notifications:
+// modify-subscription.  Subscription-Id is not in RFC 8040 or RFC 8639, it's
+// an extension of devices that serve each stream as an RFC 8639 dynamic
+// subscription.  Otherwise, or if device refuses, current stream is stopped
+// and a new one opened w/new filter resuming after last event subscriber got
+// if device gives events ids so no event is delivered twice or out of order.
+// If new stream cannot be opened, stream w/previous filter is reopened.
+// Events channel is the same either way.
+func (self *Subscription) UpdateFilter(filter string) error {
+	if id := self.subscriptionId(); id != "" && filter != "" {
+		if modifier, valid := self.support.(subscriptionModifier); valid {
+			err := modifier.modifySubscription(self.ctx, id, filter)
+			if err == nil {
+				self.lock.Lock()
+				self.filter = filter
+				self.lock.Unlock()
+				return nil
+			}
+			fc.Debug.Printf("could not modify subscription %s in place, reopening stream. %s", id, err)
+		}
+	}
+	return self.open(filter)
+}
+
+// Filter currently applied to events, empty if none
+func (self *Subscription) Filter() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.filter
+}
+
+func (self *Subscription) subscriptionId() string {
+	id, _ := self.counters.subscriptionId.Load().(string)
+	return id
+}
+
+// open stream w/filter and relay its events to subscriber, replacing
+// current stream if there is one.  Current stream is stopped before new one
+// is opened so only one stream ever delivers events.
+func (self *Subscription) open(filter string) error {
+	self.openLock.Lock()
+	defer self.openLock.Unlock()
+	self.lock.Lock()
+	if self.ended {
+		self.lock.Unlock()
+		return ErrStreamEnded
+	}
+	prev, prevDone, prevFilter := self.stream, self.relayDone, self.filter
+	// stopped relay will not end subscription
+	self.gen++
+	self.stream = nil
+	self.lock.Unlock()
+	if prev != nil {
+		prev()
+		<-prevDone
+	}
+	err := self.start(filter)
+	if err != nil && prev != nil {
+		if reopenErr := self.start(prevFilter); reopenErr != nil {
+			self.lock.Lock()
+			self.end()
+			self.lock.Unlock()
+			self.counters.setErr(reopenErr)
+		}
+	}
+	return err
+}
+
+// start stream w/filter resuming after last event subscriber got
+func (self *Subscription) start(filter string) error {
+	ctx, cancel := context.WithCancel(self.ctx)
+	self.lock.Lock()
+	cursor := self.delivered
+	self.lock.Unlock()
+	if cursor != "" {
+		ctx = WithCursor(ctx, cursor)
+	}
+	var params string
+	if filter != "" {
+		params = "filter=" + url.QueryEscape(filter)
+	}
+	events, err := self.support.clientStream(params, self.p, withStreamCounters(ctx, self.counters))
+	if err != nil {
+		cancel()
+		return err
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.ended {
+		cancel()
+		return ErrStreamEnded
+	}
+	self.stream = cancel
+	self.relayDone = make(chan struct{})
+	self.filter = filter
+	go self.relay(ctx, self.gen, events, self.relayDone)
+	return nil
+}
+
+// relay events from a stream until it closes.  Subscriber's channel is only
+// closed if stream was not replaced.
+func (self *Subscription) relay(ctx context.Context, gen int, events <-chan node.Node, done chan struct{}) {
+	defer close(done)
+	for n := range events {
+		if ctx.Err() != nil {
+			// replaced or closed, drain
+			continue
+		}
+		select {
+		case self.events <- n:
+			if cursor, found := EventCursor(n); found {
+				self.lock.Lock()
+				self.delivered = cursor
+				self.lock.Unlock()
+			}
+		case <-ctx.Done():
+		}
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if gen == self.gen {
+		self.end()
+	}
+}
+
+// end subscription, lock must be held
+func (self *Subscription) end() {
+	if !self.ended {
+		self.ended = true
+		close(self.events)
+	}
+}
+
+type subscriptionModifier interface {
+	modifySubscription(ctx context.Context, id string, filter string) error
+}
+
+// modifySubscription replaces filter of dynamic subscription (RFC 8639)
+func (self *client) modifySubscription(ctx context.Context, id string, filter string) error {
+	input := map[string]interface{}{
+		"ietf-subscribed-notifications:input": map[string]interface{}{
+			"id":                  json.Number(id),
+			"stream-xpath-filter": filter,
+		},
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return self.operation(ctx, "ietf-subscribed-notifications:modify-subscription", string(data))
+}
@@ -0,0 +1,466 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestSubscription(t *testing.T) {
+	hangup := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"n\":1}\n\ndata: {bad\n\n")
+		w.(http.Flusher).Flush()
+		if !hangup {
+			<-r.Context().Done()
+		}
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+
+	// device ends stream
+	sub, err := c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	var received []node.Node
+	for n := range sub.Events() {
+		received = append(received, n)
+	}
+	fc.AssertEqual(t, 2, len(received))
+	fc.AssertEqual(t, true, errors.Is(sub.Err(), ErrStreamEnded))
+	stats := sub.Stats()
+	fc.AssertEqual(t, "m:x", stats.Path)
+	fc.AssertEqual(t, uint64(2), stats.Received)
+	fc.AssertEqual(t, uint64(2), stats.Delivered)
+
+	// subscriber ends stream
+	hangup = false
+	sub, err = c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	<-sub.Events()
+	<-sub.Events()
+	fc.AssertEqual(t, true, sub.Err() != nil)
+	fc.AssertEqual(t, nil, sub.Close())
+	for range sub.Events() {
+	}
+	fc.AssertEqual(t, false, errors.Is(sub.Err(), ErrStreamEnded))
+}
+
+func TestSubscriptionEndReason(t *testing.T) {
+	var body string
+	var trailer map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name := range trailer {
+			w.Header().Add("Trailer", name)
+		}
+		fmt.Fprint(w, body)
+		for name, value := range trailer {
+			w.Header().Set(name, value)
+		}
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	endReason := func() (int, error) {
+		sub, err := c.Subscribe(context.Background(), "m:x")
+		fc.AssertEqual(t, nil, err)
+		count := 0
+		for range sub.Events() {
+			count++
+		}
+		return count, sub.Err()
+	}
+
+	// clean close
+	body = "data: {\"n\":1}\n\n"
+	count, err := endReason()
+	fc.AssertEqual(t, 1, count)
+	fc.AssertEqual(t, ErrStreamEnded, err)
+
+	// error frame
+	body = "data: {\"n\":1}\n\nevent: error\ndata: {\"ietf-restconf:errors\":{\"error\":[{\"error-type\":\"application\",\"error-tag\":\"resource-denied\",\"error-message\":\"shutting down\"}]}}\n\n"
+	count, err = endReason()
+	fc.AssertEqual(t, 1, count)
+	var streamErr *StreamError
+	fc.AssertEqual(t, true, errors.As(err, &streamErr))
+	fc.AssertEqual(t, "application", streamErr.Type)
+	fc.AssertEqual(t, "resource-denied", streamErr.Tag)
+	fc.AssertEqual(t, "shutting down", streamErr.Message)
+	fc.AssertEqual(t, true, errors.Is(err, ErrStreamEnded))
+
+	// trailer
+	body = "data: {\"n\":1}\n\n"
+	trailer = map[string]string{"Error-Tag": "operation-failed", "Error-Message": "restarting"}
+	count, err = endReason()
+	fc.AssertEqual(t, 1, count)
+	fc.AssertEqual(t, true, errors.As(err, &streamErr))
+	fc.AssertEqual(t, "operation-failed", streamErr.Tag)
+	fc.AssertEqual(t, "device ended notification stream. operation-failed: restarting", err.Error())
+}
+
+func TestSubscriptionRawFrame(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"n\":1}\n\nid: 2\ndata: {\"other:z\":2}\n\ndata: {bad\n\n")
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	sub, err := c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	var received []node.Node
+	for n := range sub.Events() {
+		received = append(received, n)
+	}
+	fc.AssertEqual(t, 3, len(received))
+	fc.AssertEqual(t, true, RawFrame(received[0]) == nil)
+	fc.AssertEqual(t, `{"other:z":2}`, string(RawFrame(received[1])))
+	var decodeErr *EventDecodeError
+	fc.AssertEqual(t, true, errors.As(received[1].(node.ErrorNode).Err, &decodeErr))
+	fc.AssertEqual(t, "2", decodeErr.Id)
+	fc.AssertEqual(t, true, errors.Is(decodeErr, fc.NotFoundError))
+	fc.AssertEqual(t, `{bad`, string(RawFrame(received[2])))
+}
+
+func TestSubscriptionEventTime(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, eventTime := range []string{"2023-04-05T06:07:08.123456789Z", "2023-04-05T06:07:08Z", "05/04/2023"} {
+			fmt.Fprintf(w, "data: {\"ietf-restconf:notification\":{\"eventTime\":%q,\"m:x\":{\"n\":1}}}\n\n", eventTime)
+		}
+		fmt.Fprint(w, "data: {\"n\":2}\n\n")
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	read := func(layouts []string) []node.Node {
+		c := newTestClient(srv, m)
+		c.eventTimeLayouts = layouts
+		sub, err := c.Subscribe(context.Background(), "m:x")
+		fc.AssertEqual(t, nil, err)
+		var received []node.Node
+		for n := range sub.Events() {
+			received = append(received, n)
+		}
+		fc.AssertEqual(t, 4, len(received))
+		return received
+	}
+
+	received := read(nil)
+	when, found := EventTime(received[0])
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, 123456789, when.Nanosecond())
+	// envelope was removed otherwise it would not have matched schema
+	fc.AssertEqual(t, true, RawFrame(received[0]) == nil)
+	when, _ = EventTime(received[1])
+	fc.AssertEqual(t, 8, when.Second())
+	var decodeErr *EventDecodeError
+	fc.AssertEqual(t, true, errors.As(received[2].(node.ErrorNode).Err, &decodeErr))
+	fc.AssertEqual(t, true, errors.Is(decodeErr, fc.BadRequestError))
+	_, found = EventTime(received[3])
+	fc.AssertEqual(t, false, found)
+
+	received = read([]string{"02/01/2006", time.RFC3339Nano})
+	when, found = EventTime(received[2])
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, time.April, when.Month())
+	when, _ = EventTime(received[0])
+	fc.AssertEqual(t, 123456789, when.Nanosecond())
+}
+
+func TestSubscriptionLongPoll(t *testing.T) {
+	var polls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		fc.AssertEqual(t, mimeYangDataJson, r.Header.Get("Accept"))
+		switch polls {
+		case 1:
+			// device gave up waiting
+			w.WriteHeader(http.StatusNoContent)
+		case 2, 3:
+			fmt.Fprintf(w, `{"n":%d}`, polls)
+		default:
+			http.Error(w, "gone", http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	c.longPoll = true
+	sub, err := c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	var received []node.Node
+	for n := range sub.Events() {
+		_, isErr := n.(node.ErrorNode)
+		fc.AssertEqual(t, false, isErr)
+		received = append(received, n)
+	}
+	fc.AssertEqual(t, 2, len(received))
+	fc.AssertEqual(t, 4, polls)
+}
+
+func TestSubscriptionCursor(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last, _ := strconv.Atoi(strings.TrimPrefix(r.Header.Get("Last-Event-ID"), "c"))
+		for id := last + 1; id <= 3; id++ {
+			fmt.Fprintf(w, "id: c%d\ndata: {\"n\":%d}\n\n", id, id)
+		}
+		fmt.Fprint(w, "data: {\"n\":0}\n\n")
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	c := newTestClient(srv, m)
+	read := func(ctx context.Context) ([]string, string) {
+		sub, err := c.Subscribe(ctx, "m:x")
+		fc.AssertEqual(t, nil, err)
+		var cursors []string
+		for n := range sub.Events() {
+			cursor, found := EventCursor(n)
+			if !found {
+				cursor = "-"
+			}
+			cursors = append(cursors, cursor)
+		}
+		return cursors, sub.Cursor()
+	}
+	ctx := context.Background()
+	cursors, latest := read(ctx)
+	fc.AssertEqual(t, "c1 c2 c3 -", strings.Join(cursors, " "))
+	fc.AssertEqual(t, "c3", latest)
+
+	cursors, latest = read(WithCursor(ctx, "c2"))
+	fc.AssertEqual(t, "c3 -", strings.Join(cursors, " "))
+	fc.AssertEqual(t, "c3", latest)
+}
+
+func TestSubscriptionUpdateFilter(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var subscriptionId string
+	var streams []string
+	var modified string
+	modify := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			body, _ := ioutil.ReadAll(r.Body)
+			modified = r.URL.Path + " " + string(body)
+			w.WriteHeader(modify)
+			return
+		}
+		streams = append(streams, r.URL.Query().Get("filter"))
+		if subscriptionId != "" {
+			w.Header().Set("Subscription-Id", subscriptionId)
+		}
+		fmt.Fprintf(w, "id: %d\ndata: {\"n\":%d}\n\n", len(streams), len(streams))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	n := func(sub *Subscription) string {
+		cursor, _ := EventCursor(<-sub.Events())
+		return cursor
+	}
+
+	// reconnect
+	sub, err := c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	events := sub.Events()
+	fc.AssertEqual(t, "1", n(sub))
+	fc.AssertEqual(t, nil, sub.UpdateFilter("/x[n>1]"))
+	fc.AssertEqual(t, "2", n(sub))
+	fc.AssertEqual(t, events, sub.Events())
+	fc.AssertEqual(t, "/x[n>1]", sub.Filter())
+	fc.AssertEqual(t, `"" "/x[n>1]"`, strings.Join(quoted(streams), " "))
+	fc.AssertEqual(t, 1, len(c.StreamStats()))
+	fc.AssertEqual(t, nil, sub.Close())
+	for range sub.Events() {
+	}
+	fc.AssertEqual(t, "", modified)
+
+	// in place
+	streams = nil
+	subscriptionId = "7"
+	sub, err = c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "1", n(sub))
+	fc.AssertEqual(t, nil, sub.UpdateFilter("/x[n>1]"))
+	fc.AssertEqual(t, `/restconf/operations/ietf-subscribed-notifications:modify-subscription {"ietf-subscribed-notifications:input":{"id":7,"stream-xpath-filter":"/x[n\u003e1]"}}`, modified)
+	fc.AssertEqual(t, 1, len(streams))
+	fc.AssertEqual(t, "/x[n>1]", sub.Filter())
+
+	// in place refused, reconnect
+	modify = http.StatusNotFound
+	fc.AssertEqual(t, nil, sub.UpdateFilter("/x[n>2]"))
+	fc.AssertEqual(t, "2", n(sub))
+	fc.AssertEqual(t, `"" "/x[n>2]"`, strings.Join(quoted(streams), " "))
+	fc.AssertEqual(t, nil, sub.Close())
+	for range sub.Events() {
+	}
+	fc.AssertEqual(t, ErrStreamEnded, sub.UpdateFilter("/x"))
+}
+
+func TestSubscriptionUpdateFilterResumes(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var resumed []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filter") == "bad" {
+			http.Error(w, "bad filter", http.StatusBadRequest)
+			return
+		}
+		last, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+		resumed = append(resumed, strconv.Quote(r.Header.Get("Last-Event-ID")))
+		for i := last + 1; i <= last+3; i++ {
+			fmt.Fprintf(w, "id: %d\ndata: {\"n\":%d}\n\n", i, i)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	n := func(sub *Subscription) string {
+		cursor, _ := EventCursor(<-sub.Events())
+		return cursor
+	}
+	sub, err := c.Subscribe(context.Background(), "m:x")
+	fc.AssertEqual(t, nil, err)
+	defer sub.Close()
+	fc.AssertEqual(t, "1", n(sub))
+
+	// events read from old stream but not delivered are not skipped
+	fc.AssertEqual(t, nil, sub.UpdateFilter("/x"))
+	fc.AssertEqual(t, "2", n(sub))
+	fc.AssertEqual(t, "3", n(sub))
+
+	// stats are safe to read while stream is reopened
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				sub.Stats()
+				c.StreamStats()
+			}
+		}
+	}()
+	fc.AssertEqual(t, nil, sub.UpdateFilter("/x[n>0]"))
+	close(done)
+	fc.AssertEqual(t, "4", n(sub))
+	fc.AssertEqual(t, "m:x", sub.Stats().Path)
+
+	// refused filter keeps previous one
+	fc.AssertEqual(t, true, sub.UpdateFilter("bad") != nil)
+	fc.AssertEqual(t, "/x[n>0]", sub.Filter())
+	fc.AssertEqual(t, "5", n(sub))
+	fc.AssertEqual(t, `"" "1" "3" "4"`, strings.Join(resumed, " "))
+}
+
+func quoted(s []string) []string {
+	q := make([]string, len(s))
+	for i, v := range s {
+		q[i] = strconv.Quote(v)
+	}
+	return q
+}
+
+func TestSubscriptionXml(t *testing.T) {
+	monitoring := `{"ietf-restconf-monitoring:streams":{"stream":[{"name":"NETCONF","access":[{"encoding":"xml","location":"x"}]}]}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/streams") {
+			fmt.Fprint(w, monitoring)
+			return
+		}
+		fmt.Fprint(w, "data: <notification xmlns=\"urn:ietf:params:xml:ns:netconf:notification:1.0\">\n")
+		fmt.Fprint(w, "data: <eventTime>2023-04-05T06:07:08Z</eventTime><x xmlns=\"urn:m\"><n>1</n></x></notification>\n\n")
+		fmt.Fprint(w, "id: 2\ndata: <x xmlns=\"urn:m\"><n>2</n></x>\n\n")
+		fmt.Fprint(w, "data: <x xmlns=\"urn:m\"><bogus>3</bogus></x>\n\n")
+	}))
+	defer srv.Close()
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace "urn:m"; prefix "m"; revision 0;
+		notification x { leaf n { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	read := func(ctx context.Context, encoding string) []node.Node {
+		c := newTestClient(srv, m)
+		c.streamEnc = encoding
+		sub, err := c.Subscribe(ctx, "m:x")
+		fc.AssertEqual(t, nil, err)
+		var received []node.Node
+		for n := range sub.Events() {
+			received = append(received, n)
+		}
+		return received
+	}
+	ctx := context.Background()
+	n := func(event node.Node) string {
+		actual, err := nodeutil.WriteJSON(node.Selection{
+			Node:        event,
+			Path:        node.NewRootPath(meta.Find(m, "x")),
+			Constraints: &node.Constraints{},
+			Context:     ctx,
+		})
+		fc.AssertEqual(t, nil, err)
+		return actual
+	}
+
+	received := read(ctx, StreamEncodingXML)
+	fc.AssertEqual(t, 3, len(received))
+	fc.AssertEqual(t, `{"n":1}`, n(received[0]))
+	when, found := EventTime(received[0])
+	fc.AssertEqual(t, true, found)
+	fc.AssertEqual(t, 8, when.Second())
+	fc.AssertEqual(t, `{"n":2}`, n(received[1]))
+	cursor, _ := EventCursor(received[1])
+	fc.AssertEqual(t, "2", cursor)
+	fc.AssertEqual(t, "<x xmlns=\"urn:m\"><bogus>3</bogus></x>", string(RawFrame(received[2])))
+
+	// per subscription
+	received = read(WithStreamEncoding(ctx, StreamEncodingXML), "")
+	fc.AssertEqual(t, `{"n":2}`, n(received[1]))
+
+	// from monitoring
+	received = read(ctx, StreamEncodingAuto)
+	fc.AssertEqual(t, `{"n":2}`, n(received[1]))
+
+	// json is default
+	received = read(ctx, "")
+	fc.AssertEqual(t, true, RawFrame(received[1]) != nil)
+	monitoring = `{"ietf-restconf-monitoring:streams":{"stream":[{"name":"NETCONF","access":[{"encoding":"xml","location":"x"},{"encoding":"json","location":"j"}]}]}}`
+	received = read(ctx, StreamEncodingAuto)
+	fc.AssertEqual(t, true, RawFrame(received[1]) != nil)
+}
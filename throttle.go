@@ -0,0 +1,79 @@
+package restconf
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrThrottled is when device said it has too many requests (429) or is
+// unavailable for a while (503 w/Retry-After).  Use errors.As w/
+// *ThrottledError to learn how long device asked to wait.
+var ErrThrottled = errors.New("device is throttling requests")
+
+// ThrottledError is how long device asked to wait before sending more
+// requests so a scheduler can back off all requests to device, not just the
+// one that failed.  RetryAfter is zero if device did not say.
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (self *ThrottledError) Error() string {
+	if self.RetryAfter > 0 {
+		return fmt.Sprintf("%s, retry after %s. %s", ErrThrottled, self.RetryAfter, self.Err)
+	}
+	return fmt.Sprintf("%s. %s", ErrThrottled, self.Err)
+}
+
+func (self *ThrottledError) Unwrap() error {
+	return self.Err
+}
+
+func (self *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+// throttledErr wraps err if resp says device is throttling
+func throttledErr(resp *http.Response, err error) error {
+	header := resp.Header.Get("Retry-After")
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+	case resp.StatusCode == http.StatusServiceUnavailable && header != "":
+	default:
+		return err
+	}
+	return &ThrottledError{RetryAfter: parseRetryAfter(header, resp.Header.Get("Date")), Err: err}
+}
+
+// parseRetryAfter is delay in seconds or until an HTTP-date (RFC 9110
+// section 10.2.3).  HTTP-date is compared to response's Date so clocks do
+// not have to agree, local clock is used if there is no Date.  Dates in the
+// past and values that cannot be parsed are zero.
+func parseRetryAfter(header string, date string) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	until, err := http.ParseTime(header)
+	if err != nil {
+		return 0
+	}
+	now, err := http.ParseTime(date)
+	if err != nil {
+		now = time.Now()
+	}
+	if wait := until.Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package restconf
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	date := "Wed, 21 Oct 2015 07:28:00 GMT"
+	tests := []struct {
+		header   string
+		date     string
+		expected time.Duration
+	}{
+		{"120", "", 2 * time.Minute},
+		{" 0 ", "", 0},
+		{"-5", "", 0},
+		{"Wed, 21 Oct 2015 07:29:30 GMT", date, 90 * time.Second},
+		// already passed
+		{"Wed, 21 Oct 2015 07:27:00 GMT", date, 0},
+		{"soon", date, 0},
+		{"", date, 0},
+	}
+	for _, test := range tests {
+		fc.AssertEqual(t, test.expected, parseRetryAfter(test.header, test.date))
+	}
+	// w/o Date header, local clock
+	until := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	wait := parseRetryAfter(until, "")
+	fc.AssertEqual(t, true, wait > 59*time.Minute && wait <= time.Hour)
+}
+
+func TestClientThrottled(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var status int
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	read := func() error {
+		b := node.NewBrowser(m, c.newClientNode().node())
+		_, err := nodeutil.WriteJSON(b.Root().Find("x"))
+		return err
+	}
+
+	// seconds
+	status = http.StatusTooManyRequests
+	header = http.Header{"Retry-After": {"30"}}
+	err = read()
+	fc.AssertEqual(t, true, errors.Is(err, ErrThrottled))
+	var throttled *ThrottledError
+	fc.AssertEqual(t, true, errors.As(err, &throttled))
+	fc.AssertEqual(t, 30*time.Second, throttled.RetryAfter)
+
+	// HTTP-date
+	status = http.StatusServiceUnavailable
+	header = http.Header{
+		"Date":        {"Wed, 21 Oct 2015 07:28:00 GMT"},
+		"Retry-After": {"Wed, 21 Oct 2015 07:38:00 GMT"},
+	}
+	err = read()
+	fc.AssertEqual(t, true, errors.As(err, &throttled))
+	fc.AssertEqual(t, 10*time.Minute, throttled.RetryAfter)
+
+	// too many requests w/o saying how long to wait
+	status = http.StatusTooManyRequests
+	header = nil
+	err = read()
+	fc.AssertEqual(t, true, errors.As(err, &throttled))
+	fc.AssertEqual(t, time.Duration(0), throttled.RetryAfter)
+
+	// unavailable but not throttling
+	status = http.StatusServiceUnavailable
+	err = read()
+	fc.AssertEqual(t, false, errors.Is(err, ErrThrottled))
+}
@@ -0,0 +1,117 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+)
+
+// Trace is exact bytes and headers of a single exchange w/device for bug
+// reports about a specific request.  If more than one request is made w/same
+// context, trace is of the last one.  Response is complete once response
+// has been decoded.
+type Trace struct {
+	Method         string
+	Url            string
+	RequestHeader  http.Header
+	Request        []byte
+	Status         int
+	ResponseHeader http.Header
+	Response       []byte
+}
+
+type traceKey struct{}
+
+// WithTrace captures next request made w/returned context such as an edit.
+//
+//	ctx, trace := restconf.WithTrace(ctx)
+//	b.RootWithContext(ctx).Find("x").UpsertFrom(n)
+//	fmt.Printf("%s", trace.Request)
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+func traceFrom(ctx context.Context) (*Trace, bool) {
+	trace, found := ctx.Value(traceKey{}).(*Trace)
+	return trace, found
+}
+
+// GetWithTrace reads path in module:path form bypassing response cache so
+// trace is always of what device sent
+func (self *client) GetWithTrace(ctx context.Context, path string) (node.Node, *Trace, error) {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, trace := WithTrace(WithoutCache(ctx))
+	n, err := self.clientDo(ctx, "GET", "", p, nil)
+	return n, trace, err
+}
+
+// ActionWithTrace invokes action at path in module:path form
+func (self *client) ActionWithTrace(ctx context.Context, path string, input node.Node) (node.Node, *Trace, error) {
+	ctx, trace := WithTrace(ctx)
+	_, output, err := self.ActionRaw(ctx, path, input)
+	return output, trace, err
+}
+
+// request records request except for body, see tracePayload
+func (self *Trace) request(req *http.Request) {
+	self.Method = req.Method
+	self.Url = redactUrl(req.URL.String())
+	self.RequestHeader = req.Header.Clone()
+	self.Request = nil
+	self.Status = 0
+	self.ResponseHeader = nil
+	self.Response = nil
+}
+
+// tracePayload is payload to send in place of given payload and it's bytes
+// once sent.  Payloads in memory stay in memory so they can still be resent,
+// streamed payloads are copied as they are sent.
+func tracePayload(payload io.Reader) (io.Reader, func() []byte) {
+	switch payload.(type) {
+	case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+		data, err := ioutil.ReadAll(payload)
+		if err != nil {
+			fc.Debug.Printf("could not trace request. %s", err)
+		}
+		return bytes.NewReader(data), func() []byte { return data }
+	case nil:
+		return nil, func() []byte { return nil }
+	}
+	var sent bytes.Buffer
+	return io.TeeReader(payload, &sent), sent.Bytes
+}
+
+// response records response and tees body so it's captured as caller
+// reads it
+func (self *Trace) response(resp *http.Response) {
+	self.Status = resp.StatusCode
+	self.ResponseHeader = resp.Header.Clone()
+	resp.Body = traceBody{
+		Reader: io.TeeReader(resp.Body, traceWriter{self}),
+		Closer: resp.Body,
+	}
+}
+
+type traceBody struct {
+	io.Reader
+	io.Closer
+}
+
+type traceWriter struct {
+	trace *Trace
+}
+
+func (self traceWriter) Write(p []byte) (int, error) {
+	self.trace.Response = append(self.trace.Response, p...)
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package restconf
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+)
+
+func TestClientTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Echo", r.Method)
+		switch r.URL.Path {
+		case "/restconf/data/m:nope":
+			http.Error(w, "no such thing", http.StatusNotFound)
+		case "/restconf/data/m:y":
+			fmt.Fprintf(w, `{"out":%q}`, string(body))
+		default:
+			fmt.Fprint(w, `{"a":"hi"}`)
+		}
+	}))
+	defer srv.Close()
+	m := requestBuilder{}.m(`container x { leaf a { type string; } } container nope {} rpc y { input { leaf in { type string; } } output { leaf out { type string; } } }`)
+	c := newTestClient(srv, m)
+	ctx := context.Background()
+
+	// read
+	n, trace, err := c.GetWithTrace(ctx, "m:x")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, n != nil)
+	fc.AssertEqual(t, "GET", trace.Method)
+	fc.AssertEqual(t, srv.URL+"/restconf/data/m:x", trace.Url)
+	fc.AssertEqual(t, "application/json", trace.RequestHeader.Get("Accept"))
+	fc.AssertEqual(t, 200, trace.Status)
+	fc.AssertEqual(t, "GET", trace.ResponseHeader.Get("X-Echo"))
+	fc.AssertEqual(t, `{"a":"hi"}`, string(trace.Response))
+
+	// error
+	_, trace, err = c.GetWithTrace(ctx, "m:nope")
+	fc.AssertEqual(t, true, err != nil)
+	fc.AssertEqual(t, 404, trace.Status)
+	fc.AssertEqual(t, "no such thing\n", string(trace.Response))
+
+	// edit
+	editCtx, trace := WithTrace(ctx)
+	b := node.NewBrowser(m, c.newClientNode().node())
+	err = b.RootWithContext(editCtx).Find("x").UpsertFrom(nodeutil.ReadJSON(`{"a":"bye"}`)).LastErr
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "PUT", trace.Method)
+	fc.AssertEqual(t, `{"a":"bye"}`, string(trace.Request))
+
+	// action
+	_, trace, err = c.ActionWithTrace(ctx, "m:y", nodeutil.ReadJSON(`{"in":"x"}`))
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, `{"in":"x"}`, string(trace.Request))
+	fc.AssertEqual(t, `{"out":"{\"in\":\"x\"}"}`, string(trace.Response))
+}
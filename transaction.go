@@ -0,0 +1,149 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// CandidateDatastore is where transactions stage edits (RFC 8527)
+const CandidateDatastore = "ietf-datastores:candidate"
+
+// ErrNotAtomic is when rolling back a transaction on a device that has no
+// candidate datastore so edits were already applied
+var ErrNotAtomic = errors.New("device does not support transactions, edits were applied directly")
+
+// ErrTransactionDone is when transaction was already committed or rolled
+// back
+var ErrTransactionDone = errors.New("transaction already committed or rolled back")
+
+// Transaction groups edits so they are applied all at once or not at all on
+// devices w/a candidate datastore.  Candidate is locked for the life of the
+// transaction and edits are committed w/ietf-netconf:commit or thrown away
+// w/ietf-netconf:discard-changes.  On devices w/o a candidate datastore
+// edits are applied as they are made.
+//
+//	tx, err := dev.Transaction(ctx)
+//	b.RootWithContext(tx.Context()).Find("x").UpsertFrom(n)
+//	err = tx.Commit()
+type Transaction struct {
+	client *client
+	ctx    context.Context
+	atomic bool
+
+	lock sync.Mutex
+	done bool
+}
+
+// Transaction starts a transaction, locking candidate datastore if device
+// has one
+func (self *client) Transaction(ctx context.Context) (*Transaction, error) {
+	tx := &Transaction{client: self, ctx: ctx}
+	hasCandidate, err := self.hasDatastore(ctx, CandidateDatastore)
+	if err != nil {
+		return nil, err
+	}
+	if !hasCandidate {
+		fc.Debug.Printf("%s has no candidate datastore, edits will not be atomic", self.address.Base)
+		return tx, nil
+	}
+	if err := self.operation(ctx, "ietf-netconf:lock", candidateTarget); err != nil {
+		return nil, err
+	}
+	tx.atomic = true
+	tx.ctx = WithDatastore(ctx, CandidateDatastore)
+	return tx, nil
+}
+
+// Atomic is false when device has no candidate datastore and edits are
+// applied as they are made
+func (self *Transaction) Atomic() bool {
+	return self.atomic
+}
+
+// Context routes edits made w/it into transaction
+func (self *Transaction) Context() context.Context {
+	return self.ctx
+}
+
+// Commit applies all edits.  If commit fails, edits are discarded.
+func (self *Transaction) Commit() error {
+	if err := self.finish(); err != nil || !self.atomic {
+		return err
+	}
+	if err := self.client.operation(self.ctx, "ietf-netconf:commit", ""); err != nil {
+		if discardErr := self.client.operation(self.ctx, "ietf-netconf:discard-changes", ""); discardErr != nil {
+			fc.Err.Printf("could not discard changes after failed commit. %s", discardErr)
+		}
+		self.unlock()
+		return err
+	}
+	return self.unlock()
+}
+
+// Rollback throws away all edits.  Returns ErrNotAtomic if device has no
+// candidate datastore because edits were already applied.
+func (self *Transaction) Rollback() error {
+	if err := self.finish(); err != nil {
+		return err
+	}
+	if !self.atomic {
+		return ErrNotAtomic
+	}
+	if err := self.client.operation(self.ctx, "ietf-netconf:discard-changes", ""); err != nil {
+		self.unlock()
+		return err
+	}
+	return self.unlock()
+}
+
+func (self *Transaction) finish() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.done {
+		return ErrTransactionDone
+	}
+	self.done = true
+	return nil
+}
+
+func (self *Transaction) unlock() error {
+	return self.client.operation(self.ctx, "ietf-netconf:unlock", candidateTarget)
+}
+
+const candidateTarget = `{"ietf-netconf:input":{"target":{"candidate":[null]}}}`
+
+// hasDatastore is whether device has NMDA datastore
+func (self *client) hasDatastore(ctx context.Context, datastore string) (bool, error) {
+	resp, err := self.sendRequest(ctx, request{method: "OPTIONS", url: self.address.Datastore(datastore)})
+	if errors.Is(err, fc.NotFoundError) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// operation invokes a RPC at /operations w/JSON input, if any, and ignores
+// output
+func (self *client) operation(ctx context.Context, name string, input string) error {
+	resp, err := self.sendRequest(ctx, request{
+		method:      "POST",
+		url:         self.address.Operations + name,
+		target:      name,
+		contentType: mimeYangDataJson,
+		accept:      mimeYangDataJson,
+		payload:     strings.NewReader(input),
+	})
+	if err != nil {
+		return fmt.Errorf("%s. %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
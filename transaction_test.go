@@ -0,0 +1,94 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+func TestTransaction(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module m { namespace ""; prefix "m"; revision 0;
+		container x { leaf a { type int32; } }
+	}`)
+	fc.AssertEqual(t, nil, err)
+	var candidate, commitFails bool
+	var log []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/restconf/")
+		if r.Method == "OPTIONS" && strings.HasPrefix(path, "ds/") && !candidate {
+			http.Error(w, "no such datastore", 404)
+			return
+		}
+		if r.Method == "GET" || r.Method == "OPTIONS" {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		log = append(log, strings.TrimSpace(fmt.Sprint(r.Method, " ", path, " ", string(body))))
+		if path == "operations/ietf-netconf:commit" && commitFails {
+			http.Error(w, "invalid", 400)
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv, m)
+	edit := func(tx *Transaction) error {
+		b, err := c.Browser("m")
+		fc.AssertEqual(t, nil, err)
+		return b.RootWithContext(tx.Context()).Find("x").UpsertFrom(nodeutil.ReadJSON(`{"a":1}`)).LastErr
+	}
+	ctx := context.Background()
+	lock := `POST operations/ietf-netconf:lock {"ietf-netconf:input":{"target":{"candidate":[null]}}}`
+	unlock := `POST operations/ietf-netconf:unlock {"ietf-netconf:input":{"target":{"candidate":[null]}}}`
+
+	// commit
+	candidate = true
+	tx, err := c.Transaction(ctx)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, true, tx.Atomic())
+	fc.AssertEqual(t, nil, edit(tx))
+	fc.AssertEqual(t, nil, tx.Commit())
+	fc.AssertEqual(t, strings.Join([]string{
+		lock,
+		`PUT ds/ietf-datastores:candidate/m:x {"a":1}`,
+		`POST operations/ietf-netconf:commit`,
+		unlock,
+	}, "\n"), strings.Join(log, "\n"))
+	fc.AssertEqual(t, true, errors.Is(tx.Commit(), ErrTransactionDone))
+
+	// rollback
+	log = nil
+	tx, _ = c.Transaction(ctx)
+	fc.AssertEqual(t, nil, edit(tx))
+	fc.AssertEqual(t, nil, tx.Rollback())
+	fc.AssertEqual(t, "POST operations/ietf-netconf:discard-changes", log[2])
+	fc.AssertEqual(t, unlock, log[3])
+
+	// failed commit discards changes
+	log = nil
+	commitFails = true
+	tx, _ = c.Transaction(ctx)
+	fc.AssertEqual(t, nil, edit(tx))
+	err = tx.Commit()
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+	fc.AssertEqual(t, "POST operations/ietf-netconf:discard-changes", log[3])
+	fc.AssertEqual(t, unlock, log[4])
+
+	// no candidate, edits go directly to device
+	log = nil
+	candidate = false
+	tx, err = c.Transaction(ctx)
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, false, tx.Atomic())
+	fc.AssertEqual(t, nil, edit(tx))
+	fc.AssertEqual(t, `PUT data/m:x {"a":1}`, strings.Join(log, "\n"))
+	fc.AssertEqual(t, true, errors.Is(tx.Rollback(), ErrNotAtomic))
+}
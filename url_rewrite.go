@@ -0,0 +1,40 @@
+package restconf
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// rewriteTransport sends each request to url from Client.URLRewriter.  Being
+// a transport, every request to device is rewritten: data, operations,
+// schema and streams.  Authorization set from url of original request is
+// kept and errors still report original url.
+type rewriteTransport struct {
+	rewrite func(string) string
+	next    http.RoundTripper
+}
+
+func (self rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	orig := req.URL.String()
+	rewritten := self.rewrite(orig)
+	if rewritten == orig {
+		return self.next.RoundTrip(req)
+	}
+	u, err := url.Parse(rewritten)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		// parse error would repeat url w/any credentials
+		return nil, fmt.Errorf("%w. could not parse rewritten url %s", fc.BadRequestError, redactUrl(rewritten))
+	}
+	fc.Debug.Printf("rewrote %s to %s", redactUrl(orig), redactUrl(rewritten))
+	out := req.Clone(req.Context())
+	out.URL = u
+	// Host header follows url
+	out.Host = ""
+	return self.next.RoundTrip(out)
+}
@@ -0,0 +1,69 @@
+package restconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/source"
+)
+
+func TestClientURLRewriter(t *testing.T) {
+	var requested []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, _ := r.BasicAuth()
+		requested = append(requested, fmt.Sprintf("%s %s %s", user, r.Method, r.URL.Path))
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/schema/x.yang"):
+			fmt.Fprint(w, `module x { namespace ""; prefix "x"; revision 0; notification e { leaf n { type int32; } } }`)
+		case strings.HasSuffix(r.URL.Path, "/x:e"):
+			fmt.Fprint(w, "data: {\"n\":1}\n\n")
+		case strings.Contains(r.URL.Path, "/operations/"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"module":[]}`))
+		}
+	}))
+	defer srv.Close()
+	factory := Client{
+		YangPath: source.Dir("./yang"),
+		URLRewriter: func(url string) string {
+			return strings.Replace(url, "/restconf/", "/gw/d1/restconf/", 1)
+		},
+	}
+	addr := strings.Replace(srv.URL, "http://", "http://admin:secret@", 1) + "/restconf"
+	dev, err := factory.NewDevice(addr)
+	fc.AssertEqual(t, nil, err)
+	c := dev.(*client)
+	ctx := context.Background()
+	_, err = c.module("x")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, nil, c.operation(ctx, "x:y", ""))
+	sub, err := c.Subscribe(ctx, "x:e")
+	fc.AssertEqual(t, nil, err)
+	for range sub.Events() {
+	}
+	fc.AssertEqual(t, true, len(requested) > 3)
+	for _, r := range requested {
+		fc.AssertEqual(t, true, strings.HasPrefix(r, "admin "))
+		fc.AssertEqual(t, true, strings.Contains(r, " /gw/d1/restconf/"))
+	}
+	last := requested[len(requested)-3:]
+	fc.AssertEqual(t, "admin GET /gw/d1/restconf/schema/x.yang", last[0])
+	fc.AssertEqual(t, "admin POST /gw/d1/restconf/operations/x:y", last[1])
+	fc.AssertEqual(t, "admin GET /gw/d1/restconf/data/x:e", last[2])
+
+	// bad rewrite is an error w/credentials redacted
+	c.client.Transport = rewriteTransport{
+		rewrite: func(string) string { return "http://admin:secret@gw/%zz" },
+		next:    http.DefaultTransport,
+	}
+	_, err = c.GetRaw(ctx, "x:e")
+	fc.AssertEqual(t, true, errors.Is(err, fc.BadRequestError))
+	fc.AssertEqual(t, false, strings.Contains(err.Error(), "secret"))
+}
@@ -0,0 +1,43 @@
+package restconf
+
+import (
+	"context"
+	"time"
+
+	"github.com/freeconf/yang/fc"
+)
+
+// warmUp opens a connection to device and loads modules, waiting at most
+// budget for it to finish.  Failures are only logged, request that needs
+// what could not be warmed up will report it.
+func (self *client) warmUp(modules []string, budget time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := self.keepAlive(); err != nil {
+			fc.Debug.Printf("could not warm up connection to %s. %s", self.address.Base, err)
+		}
+		for _, name := range modules {
+			if _, err := self.module(name); err != nil {
+				fc.Debug.Printf("could not warm up module %s. %s", name, err)
+			}
+		}
+	}()
+	if budget <= 0 {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(budget):
+		fc.Debug.Printf("warm up of %s still going after %s", self.address.Base, budget)
+	}
+}
+
+// keepAlive leaves an idle connection in transport's pool
+func (self *client) keepAlive() error {
+	resp, err := self.sendRequest(context.Background(), request{method: "HEAD", url: self.address.Data})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
@@ -0,0 +1,40 @@
+package restconf
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/restconf/testdata"
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/parser"
+	"github.com/freeconf/yang/source"
+)
+
+func TestClientWarmUp(t *testing.T) {
+	ypath := source.Path("./testdata:./yang")
+	d := device.New(ypath)
+	m := parser.RequireModule(ypath, "bird")
+	d.AddBrowser(node.NewBrowser(m, testdata.BirdNode(nil)))
+	srv := httptest.NewServer(NewServer(d))
+	defer srv.Close()
+
+	factory := Client{YangPath: ypath}
+	dev, err := factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	_, found := dev.(*client).Modules()["car"]
+	fc.AssertEqual(t, false, found)
+
+	factory = Client{
+		YangPath:      ypath,
+		WarmUp:        true,
+		WarmUpModules: []string{"car"},
+		WarmUpBudget:  5 * time.Second,
+	}
+	dev, err = factory.NewDevice(srv.URL + "/restconf")
+	fc.AssertEqual(t, nil, err)
+	_, found = dev.(*client).Modules()["car"]
+	fc.AssertEqual(t, true, found)
+}
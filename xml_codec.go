@@ -0,0 +1,318 @@
+package restconf
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+
+	"github.com/freeconf/yang/meta"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/val"
+)
+
+// XMLCodec speaks application/yang-data+xml for servers that default to XML.
+// Unlike JSON, XML needs a single root element so the selection being written
+// is wrapped in an element w/the module's namespace.
+var XMLCodec Codec = xmlCodec{}
+
+type xmlCodec struct{}
+
+func (xmlCodec) MimeType() string {
+	return "application/yang-data+xml"
+}
+
+func (xmlCodec) Reader(in io.Reader) node.Node {
+	elems, err := decodeXml(in)
+	if err != nil {
+		return node.ErrorNode{Err: err}
+	}
+	return xmlRootReader(elems)
+}
+
+func (xmlCodec) Write(out io.Writer, sel node.Selection) error {
+	wtr := &xmlWtr{out: bufio.NewWriter(out)}
+	return sel.InsertInto(wtr.node()).LastErr
+}
+
+type xmlElem struct {
+	XMLName  xml.Name
+	Text     string     `xml:",chardata"`
+	Children []*xmlElem `xml:",any"`
+}
+
+// decodeXml reads all top level elements.  More than one is only legal when
+// reading entries of a list
+func decodeXml(in io.Reader) ([]*xmlElem, error) {
+	var elems []*xmlElem
+	d := xml.NewDecoder(in)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return elems, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if start, valid := tok.(xml.StartElement); valid {
+			var e xmlElem
+			if err := d.DecodeElement(&e, &start); err != nil {
+				return nil, err
+			}
+			elems = append(elems, &e)
+		}
+	}
+}
+
+func (self *xmlElem) find(ident string) []*xmlElem {
+	var found []*xmlElem
+	for _, c := range self.Children {
+		if c.XMLName.Local == ident {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+func xmlRootReader(elems []*xmlElem) node.Node {
+	if len(elems) == 0 {
+		return nil
+	}
+	container := xmlContainerReader(elems[0])
+	list := xmlListReader(elems)
+	return &nodeutil.Basic{
+		OnChild:  container.Child,
+		OnField:  container.Field,
+		OnChoose: container.Choose,
+		OnNext:   list.Next,
+	}
+}
+
+func xmlContainerReader(e *xmlElem) node.Node {
+	s := &nodeutil.Basic{}
+	s.OnChoose = func(sel node.Selection, choice *meta.Choice) (*meta.ChoiceCase, error) {
+		for _, kase := range choice.Cases() {
+			for _, prop := range kase.DataDefinitions() {
+				if len(e.find(prop.Ident())) > 0 {
+					return kase, nil
+				}
+			}
+		}
+		return nil, nil
+	}
+	s.OnChild = func(r node.ChildRequest) (node.Node, error) {
+		if r.New {
+			panic("Cannot write to XML reader")
+		}
+		found := e.find(r.Meta.Ident())
+		if len(found) == 0 {
+			return nil, nil
+		}
+		if meta.IsList(r.Meta) {
+			return xmlListReader(found), nil
+		}
+		return xmlContainerReader(found[0]), nil
+	}
+	s.OnField = func(r node.FieldRequest, hnd *node.ValueHandle) (err error) {
+		if r.Write {
+			panic("Cannot write to XML reader")
+		}
+		found := e.find(r.Meta.Ident())
+		if len(found) == 0 {
+			return nil
+		}
+		if isBinary(r.Meta) {
+			var data interface{} = found[0].Text
+			if r.Meta.Type().Format().IsList() {
+				items := make([]interface{}, len(found))
+				for i, item := range found {
+					items[i] = item.Text
+				}
+				data = items
+			}
+			hnd.Val, err = decodeBinary(r.Meta, data)
+			return
+		}
+		if r.Meta.Type().Format().IsList() {
+			items := make([]interface{}, len(found))
+			for i, item := range found {
+				items[i] = item.Text
+			}
+			hnd.Val, err = node.NewValue(r.Meta.Type(), items)
+		} else {
+			hnd.Val, err = node.NewValue(r.Meta.Type(), found[0].Text)
+		}
+		return
+	}
+	return s
+}
+
+func xmlListReader(entries []*xmlElem) node.Node {
+	s := &nodeutil.Basic{}
+	s.OnNext = func(r node.ListRequest) (node.Node, []val.Value, error) {
+		if r.New {
+			panic("Cannot write to XML reader")
+		}
+		keyMeta := r.Meta.KeyMeta()
+		if len(r.Key) > 0 {
+			if r.First {
+				for _, candidate := range entries {
+					if xmlKeyMatches(keyMeta, candidate, r.Key) {
+						return xmlContainerReader(candidate), r.Key, nil
+					}
+				}
+			}
+			return nil, nil, nil
+		}
+		if r.Row >= len(entries) {
+			return nil, nil, nil
+		}
+		entry := entries[r.Row]
+		var key []val.Value
+		if len(keyMeta) > 0 {
+			keyData := make([]string, len(keyMeta))
+			for i, k := range keyMeta {
+				if found := entry.find(k.Ident()); len(found) > 0 {
+					keyData[i] = found[0].Text
+				}
+			}
+			var err error
+			if key, err = node.NewValuesByString(keyMeta, keyData...); err != nil {
+				return nil, nil, err
+			}
+		}
+		return xmlContainerReader(entry), key, nil
+	}
+	return s
+}
+
+func xmlKeyMatches(keyMeta []meta.Leafable, candidate *xmlElem, key []val.Value) bool {
+	for i, k := range keyMeta {
+		found := candidate.find(k.Ident())
+		if len(found) == 0 || found[0].Text != key[i].String() {
+			return false
+		}
+	}
+	return true
+}
+
+type xmlWtr struct {
+	out *bufio.Writer
+}
+
+func (self *xmlWtr) node() node.Node {
+	return &nodeutil.Extend{
+		Base: self.container(""),
+		OnBeginEdit: func(p node.Node, r node.NodeRequest) error {
+			m := r.Selection.Meta()
+			if meta.IsList(m) && !r.Selection.InsideList {
+				// each entry will be written as it's own element
+				return nil
+			}
+			return self.beginElem(m.Ident(), meta.RootModule(m).Namespace())
+		},
+		OnEndEdit: func(p node.Node, r node.NodeRequest) error {
+			m := r.Selection.Meta()
+			if !meta.IsList(m) || r.Selection.InsideList {
+				if err := self.endElem(m.Ident()); err != nil {
+					return err
+				}
+			}
+			return self.out.Flush()
+		},
+	}
+}
+
+func (self *xmlWtr) container(ident string) node.Node {
+	s := &nodeutil.Basic{}
+	s.OnChild = func(r node.ChildRequest) (node.Node, error) {
+		if !r.New {
+			return nil, nil
+		}
+		if !meta.IsList(r.Meta) {
+			if err := self.beginElem(r.Meta.Ident(), ""); err != nil {
+				return nil, err
+			}
+		}
+		return self.container(r.Meta.Ident()), nil
+	}
+	s.OnNext = func(r node.ListRequest) (node.Node, []val.Value, error) {
+		if !r.New {
+			return nil, nil, nil
+		}
+		if err := self.beginElem(r.Meta.Ident(), ""); err != nil {
+			return nil, nil, err
+		}
+		return self.container(r.Meta.Ident()), r.Key, nil
+	}
+	s.OnEndEdit = func(r node.NodeRequest) error {
+		if meta.IsList(r.Selection.Meta()) && !r.Selection.InsideList {
+			return nil
+		}
+		return self.endElem(ident)
+	}
+	s.OnField = func(r node.FieldRequest, hnd *node.ValueHandle) error {
+		if !r.Write {
+			panic("Not a reader")
+		}
+		return self.writeValue(r.Meta.Ident(), hnd.Val)
+	}
+	return s
+}
+
+func (self *xmlWtr) beginElem(ident string, ns string) error {
+	if _, err := self.out.WriteString("<" + ident); err != nil {
+		return err
+	}
+	if ns != "" {
+		if _, err := self.out.WriteString(` xmlns="`); err != nil {
+			return err
+		}
+		if err := xml.EscapeText(self.out, []byte(ns)); err != nil {
+			return err
+		}
+		if _, err := self.out.WriteString(`"`); err != nil {
+			return err
+		}
+	}
+	_, err := self.out.WriteString(">")
+	return err
+}
+
+func (self *xmlWtr) endElem(ident string) error {
+	_, err := self.out.WriteString("</" + ident + ">")
+	return err
+}
+
+func (self *xmlWtr) writeValue(ident string, v val.Value) error {
+	if items, isBinaryList := v.Value().([][]byte); isBinaryList {
+		for _, item := range items {
+			if err := self.writeValue(ident, val.Any{Thing: item}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	lerr := val.Reduce(v, nil, func(i int, item val.Value, ierr interface{}) interface{} {
+		if ierr != nil {
+			return ierr
+		}
+		if err := self.beginElem(ident, ""); err != nil {
+			return err
+		}
+		s := item.String()
+		if e, isEnum := item.(val.Enum); isEnum {
+			s = e.Label
+		} else if b, isBinary := item.Value().([]byte); isBinary {
+			s = base64.StdEncoding.EncodeToString(b)
+		}
+		if err := xml.EscapeText(self.out, []byte(s)); err != nil {
+			return err
+		}
+		return self.endElem(ident)
+	})
+	if lerr != nil {
+		return lerr.(error)
+	}
+	return nil
+}
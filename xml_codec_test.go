@@ -0,0 +1,136 @@
+package restconf
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/node"
+	"github.com/freeconf/yang/nodeutil"
+	"github.com/freeconf/yang/parser"
+)
+
+const xmlTestModule = `module x { namespace "urn:x"; prefix "x"; revision 0;
+	container car {
+		leaf make { type string; }
+		leaf-list tags { type string; }
+		list wheel {
+			key "pos";
+			leaf pos { type int32; }
+			leaf psi { type int32; }
+		}
+		container engine {
+			leaf cyl { type int32; }
+		}
+	}
+}`
+
+func TestXmlCodecRead(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, xmlTestModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := `<car xmlns="urn:x">
+		<make>ford</make>
+		<tags>a</tags><tags>b</tags>
+		<wheel><pos>1</pos><psi>30</psi></wheel>
+		<wheel><pos>2</pos><psi>32</psi></wheel>
+		<engine><cyl>6</cyl></engine>
+	</car>`
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			return XMLCodec.Reader(strings.NewReader(in)), nil
+		},
+	})
+	actual, err := nodeutil.WriteJSON(b.Root().Find("car"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"make":"ford","tags":["a","b"],"wheel":[{"pos":1,"psi":30},{"pos":2,"psi":32}],"engine":{"cyl":6}}`
+	fc.AssertEqual(t, expected, actual)
+
+	psi, err := b.Root().Find("car/wheel=2").GetValue("psi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, 32, psi.Value())
+}
+
+func TestXmlCodecWrite(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, xmlTestModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := `{"car":{"make":"a&b","tags":["a","b"],"wheel":[{"pos":1,"psi":30}],"engine":{"cyl":6}}}`
+	b := node.NewBrowser(m, nodeutil.ReadJSON(data))
+	var buf bytes.Buffer
+	if err := XMLCodec.Write(&buf, b.Root().Find("car")); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<car xmlns="urn:x"><make>a&amp;b</make><tags>a</tags><tags>b</tags><wheel><pos>1</pos><psi>30</psi></wheel><engine><cyl>6</cyl></engine></car>`
+	fc.AssertEqual(t, expected, buf.String())
+}
+
+func TestXmlCodecBinary(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, `module x { namespace "urn:x"; prefix "x"; revision 0;
+		container car {
+			leaf photo { type binary; }
+			leaf-list keys { type binary; }
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := `<car xmlns="urn:x"><photo>aGVsbG8=</photo><keys>YQ==</keys><keys>Yg==</keys></car>`
+	b := node.NewBrowser(m, &nodeutil.Basic{
+		OnChild: func(r node.ChildRequest) (node.Node, error) {
+			return XMLCodec.Reader(strings.NewReader(in)), nil
+		},
+	})
+	photo, err := b.Root().Find("car").Get("photo")
+	fc.AssertEqual(t, nil, err)
+	fc.AssertEqual(t, "hello", string(photo.([]byte)))
+	var buf bytes.Buffer
+	if err := XMLCodec.Write(&buf, b.Root().Find("car")); err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, in, buf.String())
+}
+
+func TestXmlCodecClient(t *testing.T) {
+	m, err := parser.LoadModuleFromString(nil, xmlTestModule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var put string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fc.AssertEqual(t, "application/yang-data+xml", r.Header.Get("Accept"))
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`<car xmlns="urn:x"><make>ford</make></car>`))
+		case "PUT":
+			var buf bytes.Buffer
+			buf.ReadFrom(r.Body)
+			put = buf.String()
+		}
+	}))
+	defer srv.Close()
+	c := newTestClient(srv)
+	c.codec = XMLCodec
+	d := &clientNode{support: c, codec: XMLCodec}
+	b := node.NewBrowser(m, d.node())
+	make, err := b.Root().Find("car").GetValue("make")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, "ford", make.String())
+
+	edit := nodeutil.ReadJSON(`{"make":"chevy"}`)
+	if err := b.Root().Find("car").UpsertFrom(edit).LastErr; err != nil {
+		t.Fatal(err)
+	}
+	fc.AssertEqual(t, `<car xmlns="urn:x"><make>chevy</make></car>`, put)
+}
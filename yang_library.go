@@ -0,0 +1,159 @@
+package restconf
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/freeconf/restconf/device"
+	"github.com/freeconf/yang/meta"
+)
+
+// yangLibrary is ietf-yang-library revision 2019-01-04 (RFC 8525) which
+// replaced modules-state from revision 2016-06-21 (RFC 7895) w/module sets.
+// Only the 2016 revision is available to browse locally so 2019 revision
+// is decoded from JSON directly.
+type yangLibrary struct {
+	YangLibrary struct {
+		ModuleSet []struct {
+			Name             string              `json:"name"`
+			Module           []yangLibraryModule `json:"module"`
+			ImportOnlyModule []yangLibraryModule `json:"import-only-module"`
+		} `json:"module-set"`
+		Schema []struct {
+			Name      string   `json:"name"`
+			ModuleSet []string `json:"module-set"`
+		} `json:"schema"`
+		Datastore []struct {
+			Name   string `json:"name"`
+			Schema string `json:"schema"`
+		} `json:"datastore"`
+	} `json:"ietf-yang-library:yang-library"`
+}
+
+type yangLibraryModule struct {
+	Name      string   `json:"name"`
+	Revision  string   `json:"revision"`
+	Namespace string   `json:"namespace"`
+	Location  []string `json:"location"`
+	Feature   []string `json:"feature"`
+
+	// deviation is a leafref to name of another module in module set
+	Deviation []string `json:"deviation"`
+	Submodule []struct {
+		Name     string   `json:"name"`
+		Revision string   `json:"revision"`
+		Location []string `json:"location"`
+	} `json:"submodule"`
+}
+
+// hnds converts module sets to same module entries modules-state would have
+// listed
+func (self yangLibrary) hnds() []*device.ModuleHnd {
+	var hnds []*device.ModuleHnd
+	revisions := make(map[string]string)
+	for _, set := range self.YangLibrary.ModuleSet {
+		for _, mods := range [][]yangLibraryModule{set.Module, set.ImportOnlyModule} {
+			for _, m := range mods {
+				revisions[m.Name] = m.Revision
+			}
+		}
+	}
+	for _, set := range self.YangLibrary.ModuleSet {
+		for i, mods := range [][]yangLibraryModule{set.Module, set.ImportOnlyModule} {
+			conformance := "implement"
+			if i == 1 {
+				conformance = "import"
+			}
+			for _, m := range mods {
+				hnd := &device.ModuleHnd{
+					Name:            m.Name,
+					Revision:        m.Revision,
+					Namespace:       m.Namespace,
+					Feature:         m.Feature,
+					ConformanceType: conformance,
+				}
+				if len(m.Location) > 0 {
+					hnd.Schema = m.Location[0]
+				}
+				for _, dev := range m.Deviation {
+					hnd.Deviation = append(hnd.Deviation, &device.ModuleHnd{Name: dev, Revision: revisions[dev]})
+				}
+				for _, sub := range m.Submodule {
+					subHnd := &device.ModuleHnd{Name: sub.Name, Revision: sub.Revision}
+					if len(sub.Location) > 0 {
+						subHnd.Schema = sub.Location[0]
+					}
+					hnd.Submodule = append(hnd.Submodule, subHnd)
+				}
+				hnds = append(hnds, hnd)
+			}
+		}
+	}
+	return hnds
+}
+
+// datastoreSets are names of module sets in schema of each datastore
+func (self yangLibrary) datastoreSets() map[string][]string {
+	schemas := make(map[string][]string)
+	for _, schema := range self.YangLibrary.Schema {
+		schemas[schema.Name] = schema.ModuleSet
+	}
+	sets := make(map[string][]string)
+	for _, ds := range self.YangLibrary.Datastore {
+		sets[ds.Name] = schemas[ds.Schema]
+	}
+	return sets
+}
+
+// loadYangLibrary2019 is for devices that only implement 2019 revision of
+// ietf-yang-library.  Modules of every module set are merged, modules of
+// each datastore are kept separately in datastoreModules.
+func (self *client) loadYangLibrary2019(ctx context.Context, resolver device.ResolveModule) (map[string]*meta.Module, []*device.ModuleHnd, error) {
+	resp, err := self.sendRequest(ctx, request{
+		method: "GET",
+		url:    self.address.Data + "ietf-yang-library:yang-library",
+		target: "ietf-yang-library:yang-library",
+		accept: mimeYangDataJson,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	var ylib yangLibrary
+	if err := json.NewDecoder(resp.Body).Decode(&ylib); err != nil {
+		return nil, nil, err
+	}
+	hnds := ylib.hnds()
+	mods := make(map[string]*meta.Module)
+	// module set of each module.  Same module may be in multiple sets
+	// w/different features or deviations so each set's modules are
+	// resolved on their own.
+	setMods := make(map[string]map[string]*meta.Module)
+	i := 0
+	for _, set := range ylib.YangLibrary.ModuleSet {
+		setMods[set.Name] = make(map[string]*meta.Module)
+		// hnds are in same order as module sets
+		n := len(set.Module) + len(set.ImportOnlyModule)
+		for _, hnd := range hnds[i : i+n] {
+			m, err := resolver.ResolveModuleHnd(*hnd)
+			if err != nil {
+				return nil, nil, err
+			}
+			mods[m.Ident()] = m
+			setMods[set.Name][m.Ident()] = m
+		}
+		i += n
+	}
+	datastoreMods := make(map[string]map[string]*meta.Module)
+	for ds, sets := range ylib.datastoreSets() {
+		dsMods := make(map[string]*meta.Module)
+		for _, set := range sets {
+			for ident, m := range setMods[set] {
+				dsMods[ident] = m
+			}
+		}
+		datastoreMods[ds] = dsMods
+	}
+	self.datastoreModules = datastoreMods
+	return mods, hnds, nil
+}
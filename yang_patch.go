@@ -0,0 +1,208 @@
+package restconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/freeconf/yang/fc"
+	"github.com/freeconf/yang/meta"
+)
+
+// YANG Patch edit operations (RFC 8072)
+const (
+	PatchCreate  = "create"
+	PatchDelete  = "delete"
+	PatchInsert  = "insert"
+	PatchMerge   = "merge"
+	PatchMove    = "move"
+	PatchReplace = "replace"
+	PatchRemove  = "remove"
+)
+
+// PatchEdit is one edit in a YANG Patch.  Target is relative to resource
+// being patched such as "/interface=eth0" or "/" for resource itself.  Value
+// is JSON data keyed by module qualified name of target such as
+// {"ietf-interfaces:interface":[{"name":"eth0"}]} and is not sent for delete,
+// remove or move.  Id correlates edit w/server's status, if empty one is
+// generated.
+type PatchEdit struct {
+	Id        string
+	Operation string
+	Target    string
+	Value     map[string]interface{}
+}
+
+// PatchErrors are server's reasons edits of a YANG Patch failed keyed by
+// edit id.  Each error can be checked w/errors.Is for fc.BadRequestError,
+// fc.ConflictError and such.
+type PatchErrors map[string]error
+
+func (self PatchErrors) Error() string {
+	ids := make([]string, 0, len(self))
+	for id := range self {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("%s. %s", id, self[id])
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// YangPatch sends edits to resource at path, in module:path form, as a
+// single YANG Patch so they are applied all at once or not at all.  If
+// server rejects edits, error is PatchErrors keyed by edit ids.
+func (self *client) YangPatch(ctx context.Context, path string, patchId string, edits []PatchEdit) error {
+	p, err := self.parsePath(path)
+	if err != nil {
+		return err
+	}
+	if patchId == "" {
+		patchId = "freeconf"
+	}
+	payload, err := yangPatchEdits(patchId, edits)
+	if err != nil {
+		return err
+	}
+	target := meta.RootModule(p.Meta()).Ident() + ":" + self.targetPath(p)
+	resp, err := self.sendRequest(ctx, request{
+		method:      "PATCH",
+		url:         self.resourceUrl(self.dataUrl(ctx), target),
+		target:      target,
+		contentType: mimeYangPatchJson,
+		accept:      mimeYangDataJson,
+		payload:     payload,
+		errMapper: func(resp *http.Response, msg []byte) error {
+			if editErrs := patchStatusErrors(resp.StatusCode, msg); len(editErrs) > 0 {
+				return editErrs
+			}
+			return nil
+		},
+	})
+	var editErrs PatchErrors
+	if errors.As(err, &editErrs) {
+		return editErrs
+	}
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// yangPatchEdits gives edits w/o an id one that no other edit has
+func yangPatchEdits(patchId string, edits []PatchEdit) (io.Reader, error) {
+	ids := make(map[string]bool)
+	for _, e := range edits {
+		if e.Id == "" {
+			continue
+		}
+		if ids[e.Id] {
+			return nil, fmt.Errorf("%w. duplicate edit id %s", fc.BadRequestError, e.Id)
+		}
+		ids[e.Id] = true
+	}
+	patch := make([]yangPatchEdit, len(edits))
+	next := 1
+	for i, e := range edits {
+		id := e.Id
+		for ; id == ""; next++ {
+			if candidate := strconv.Itoa(next); !ids[candidate] {
+				id = candidate
+				ids[id] = true
+			}
+		}
+		target := e.Target
+		if target == "" {
+			target = "/"
+		}
+		patch[i] = yangPatchEdit{id: id, operation: e.Operation, target: target}
+		if len(e.Value) > 0 {
+			value, err := json.Marshal(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			patch[i].value = bytes.NewReader(value)
+		}
+	}
+	return encodeYangPatch(patchId, patch), nil
+}
+
+// yangPatchEdit is an edit w/value already encoded as JSON, nil value is
+// not sent
+type yangPatchEdit struct {
+	id        string
+	operation string
+	target    string
+	value     io.Reader
+}
+
+// encodeYangPatch is JSON of a YANG Patch w/values streamed as they are read
+func encodeYangPatch(patchId string, edits []yangPatchEdit) io.Reader {
+	parts := []io.Reader{strings.NewReader(`{"ietf-yang-patch:yang-patch":{"patch-id":` + jsonString(patchId) + `,"edit":[`)}
+	for i, e := range edits {
+		sep := ","
+		if i == 0 {
+			sep = ""
+		}
+		head := fmt.Sprintf(`%s{"edit-id":%s,"operation":%s,"target":%s`, sep, jsonString(e.id), jsonString(e.operation), jsonString(e.target))
+		parts = append(parts, strings.NewReader(head))
+		if e.value != nil {
+			parts = append(parts, strings.NewReader(`,"value":`), e.value)
+		}
+		parts = append(parts, strings.NewReader(`}`))
+	}
+	parts = append(parts, strings.NewReader(`]}}`))
+	return io.MultiReader(parts...)
+}
+
+func jsonString(s string) string {
+	encoded, _ := json.Marshal(s)
+	return string(encoded)
+}
+
+// patchStatusErrors reads errors of each edit from yang-patch-status
+func patchStatusErrors(status int, msg []byte) PatchErrors {
+	type restconfErrors struct {
+		Error []struct {
+			Tag     string `json:"error-tag"`
+			Message string `json:"error-message"`
+		} `json:"error"`
+	}
+	var doc struct {
+		Status struct {
+			EditStatus struct {
+				Edit []struct {
+					Id     string         `json:"edit-id"`
+					Errors restconfErrors `json:"errors"`
+				} `json:"edit"`
+			} `json:"edit-status"`
+		} `json:"ietf-yang-patch:yang-patch-status"`
+	}
+	if err := json.Unmarshal(msg, &doc); err != nil {
+		return nil
+	}
+	errs := make(PatchErrors)
+	for _, e := range doc.Status.EditStatus.Edit {
+		if len(e.Errors.Error) == 0 {
+			continue
+		}
+		msgs := make([]string, len(e.Errors.Error))
+		for i, x := range e.Errors.Error {
+			msgs[i] = x.Message
+			if msgs[i] == "" {
+				msgs[i] = x.Tag
+			}
+		}
+		errs[e.Id] = statusErr(status, strings.Join(msgs, ", "))
+	}
+	return errs
+}